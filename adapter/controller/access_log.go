@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/adapter/logging"
+	"clean-serverless-book-sample-v2/registry"
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// accessLogKey アクセスログの項目をコンテキストに保持するためのキー
+type accessLogKey struct{}
+
+// accessLogEntry コントローラーの処理中に分かる、アクセスログの項目
+type accessLogEntry struct {
+	UserID string
+}
+
+// WithAccessLog リクエストごとに、リクエストID・トレースID・ルート・ユーザーID・処理時間・ステータスコードをログに出力する。
+// エラーレスポンスの場合は原因となったエラーも出力する。
+// hの中では、logging.FromContextでリクエストIDを付与したLoggerを取得できる
+func WithAccessLog(h HandlerFunc) HandlerFunc {
+	return func(request Request) Response {
+		start := time.Now()
+
+		logger := logging.FromContext(request.Context(), registry.GetFactory().BuildLogger()).
+			With("request_id", request.RequestID)
+		// トレースを記録している場合は、ログからトレースを辿れるようにする
+		if sc := trace.SpanContextFromContext(request.Context()); sc.IsValid() {
+			logger = logger.With("trace_id", sc.TraceID().String())
+		}
+		entry := &accessLogEntry{}
+		ctx := logging.NewContext(request.Context(), logger)
+		ctx = context.WithValue(ctx, accessLogKey{}, entry)
+
+		res := h(request.WithContext(ctx))
+
+		args := []interface{}{
+			"method", request.HTTPMethod,
+			"path", request.Path,
+			"route", request.Resource,
+			"status", res.StatusCode,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if entry.UserID != "" {
+			args = append(args, "user_id", entry.UserID)
+		}
+		if res.errorID != "" {
+			args = append(args, "error_id", res.errorID)
+		}
+		if res.err != nil {
+			args = append(args, "error", res.err)
+		}
+		logger.Log(accessLogLevel(res.StatusCode), "request", args...)
+
+		return res
+	}
+}
+
+// setAccessLogUserID 認証したユーザーをアクセスログに出力する
+func setAccessLogUserID(request Request, principal string) {
+	if entry, ok := request.Context().Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.UserID = principal
+	}
+}
+
+// accessLogLevel サーバー側のエラーはERROR、クライアント側のエラーはWARNとして出力する
+func accessLogLevel(status int) logging.Level {
+	switch {
+	case status >= 500:
+		return logging.LevelError
+	case status >= 400:
+		return logging.LevelWarn
+	default:
+		return logging.LevelInfo
+	}
+}
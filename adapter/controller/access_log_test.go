@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"bytes"
+	"clean-serverless-book-sample-v2/adapter/logging"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestWithAccessLog リクエストごとにアクセスログが出力されること
+func TestWithAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), logging.New(&buf, logging.LevelInfo))
+
+	handler := WithAccessLog(func(request Request) Response {
+		setAccessLogUserID(request, "1")
+		logging.FromContext(request.Context(), nil).Info("in controller")
+		return Response500(errors.New("failed"))
+	})
+
+	res := handler(Request{
+		RequestID:  "req-1",
+		HTTPMethod: "GET",
+		Path:       "/v1/users/1",
+		Resource:   "/v1/users/{user_id}",
+	}.WithContext(ctx))
+	assert.Equal(t, 500, res.StatusCode)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	// コントローラー内のログにもリクエストIDが付与される
+	var inController map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &inController))
+	assert.Equal(t, "req-1", inController["request_id"])
+
+	var accessLog struct {
+		Level     string              `json:"level"`
+		RequestID string              `json:"request_id"`
+		Method    string              `json:"method"`
+		Route     string              `json:"route"`
+		Status    int                 `json:"status"`
+		LatencyMS *float64            `json:"latency_ms"`
+		UserID    string              `json:"user_id"`
+		Error     *logging.ErrorValue `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(lines[1], &accessLog))
+	assert.Equal(t, "ERROR", accessLog.Level)
+	assert.Equal(t, "req-1", accessLog.RequestID)
+	assert.Equal(t, "GET", accessLog.Method)
+	assert.Equal(t, "/v1/users/{user_id}", accessLog.Route)
+	assert.Equal(t, 500, accessLog.Status)
+	assert.NotNil(t, accessLog.LatencyMS)
+	assert.Equal(t, "1", accessLog.UserID)
+	require.NotNil(t, accessLog.Error)
+	assert.Equal(t, "failed", accessLog.Error.Message)
+	assert.NotEmpty(t, accessLog.Error.Stack)
+}
+
+// TestWithAccessLog_Level クライアント側のエラーはWARNとして出力されること
+func TestWithAccessLog_Level(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), logging.New(&buf, logging.LevelInfo))
+
+	WithAccessLog(func(Request) Response {
+		return Response404()
+	})(Request{}.WithContext(ctx))
+
+	assert.Contains(t, buf.String(), `"level":"WARN"`)
+	assert.NotContains(t, buf.String(), `"user_id"`)
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/registry"
+	"clean-serverless-book-sample-v2/usecase"
+	"strings"
+)
+
+// Authenticate AuthorizationヘッダーのBearerトークンを検証し、リクエスト送信者を返す。
+// 認証に失敗した場合はエラーレスポンスを返す
+func Authenticate(request Request) (*domain.Principal, *Response) {
+	authenticator := registry.GetFactory().BuildAuthenticate()
+	res, err := authenticator.Execute(request.Context(), &usecase.AuthenticateRequest{
+		Token: bearerToken(request.Headers),
+	})
+	if err != nil {
+		errRes := ResponseError(err)
+		return nil, &errRes
+	}
+
+	setAccessLogUserID(request, res.Principal.Subject)
+	return res.Principal, nil
+}
+
+// bearerToken AuthorizationヘッダーからBearerトークンを取り出す
+func bearerToken(headers map[string]string) string {
+	const prefix = "bearer "
+	value := headerValue(headers, "Authorization")
+	if len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		return strings.TrimSpace(value[len(prefix):])
+	}
+	return ""
+}
+
+// headerValue リクエストヘッダーの値を取得する。ヘッダー名の大文字・小文字は区別しない
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
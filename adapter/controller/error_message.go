@@ -15,15 +15,23 @@ var validateErrorMessages = map[error]string{
 	ErrEmail:                 "%sの形式が不正です。",
 	ErrUint:                  "%sは0以上の数値を入力してください。",
 	ErrUniq:                  "すでに登録されている%sです。",
+	ErrLimit:                 "%sは1から100の範囲で指定してください。",
 }
 
 // displayNames 引数名の日本語表示
 var displayNames = map[string]string{
-	"user_id":      "ユーザーID",
-	"user_name":    "ユーザー名",
-	"micropost_id": "マイクロポストID",
-	"email":        "メールアドレス",
-	"content":      "本文",
+	"user_id":        "ユーザーID",
+	"user_name":      "ユーザー名",
+	"micropost_id":   "マイクロポストID",
+	"target_id":      "フォロー対象のユーザーID",
+	"email":          "メールアドレス",
+	"content":        "本文",
+	"limit":          "取得件数",
+	"cursor":         "カーソル",
+	"since_id":       "取得範囲の開始ID",
+	"max_id":         "取得範囲の終了ID",
+	"in_reply_to_id": "返信先のマイクロポストID",
+	"If-Match":       "If-Matchヘッダー",
 }
 
 // ConvertErrorsToMessage エラーメッセージに変換
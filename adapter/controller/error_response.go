@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/interactor"
+	"github.com/pkg/errors"
+	"gopkg.in/validator.v2"
+)
+
+// fieldErrors 入力値によるドメインエラーを、項目ごとのバリデーションエラーに変換する対応表
+var fieldErrors = map[*domain.Error]error{
+	domain.ErrInvalidCursor: validator.ErrUnsupported,
+	interactor.ErrUniqEmail: ErrUniq,
+}
+
+// ResponseError エラーの種類に応じたエラーレスポンスを返す
+func ResponseError(err error) Response {
+	switch domain.KindOf(err) {
+	case domain.KindNotFound:
+		return Response404()
+	case domain.KindValidation:
+		return Response400(validationErrors(err))
+	case domain.KindUnauthorized:
+		return Response401()
+	case domain.KindForbidden:
+		return Response403()
+	case domain.KindConflict:
+		return Response409(err)
+	case domain.KindPreconditionFailed:
+		return Response412()
+	case domain.KindUnavailable:
+		return Response503(err)
+	default:
+		return Response500(err)
+	}
+}
+
+// validationErrors 入力値によるドメインエラーを、項目名をキーにしたエラーに変換する
+func validationErrors(err error) map[string]error {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		return map[string]error{}
+	}
+
+	for target, fieldErr := range fieldErrors {
+		if errors.Is(err, target) {
+			return map[string]error{target.Field: fieldErr}
+		}
+	}
+
+	return map[string]error{domainErr.Field: errors.New(domainErr.Message)}
+}
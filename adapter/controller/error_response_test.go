@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/interactor"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestResponseError エラーの種類ごとのレスポンス
+func TestResponseError(t *testing.T) {
+	cases := []struct {
+		Msg      string
+		Err      error
+		Expected int
+	}{
+		{Msg: "NotFound", Err: domain.ErrNotFound, Expected: 404},
+		{Msg: "Validation", Err: domain.ErrInvalidCursor, Expected: 400},
+		{Msg: "Unauthorized", Err: domain.ErrUnauthorized, Expected: 401},
+		{Msg: "Forbidden", Err: domain.ErrForbidden, Expected: 403},
+		{Msg: "Conflict", Err: domain.ErrConflict.Wrap(errors.New("cause")), Expected: 409},
+		{Msg: "PreconditionFailed", Err: domain.ErrPreconditionFailed, Expected: 412},
+		{Msg: "Unavailable", Err: domain.ErrUnavailable.Wrap(errors.New("cause")), Expected: 503},
+		{Msg: "DeadlineExceeded", Err: context.DeadlineExceeded, Expected: 503},
+		{Msg: "その他のエラー", Err: errors.New("unknown"), Expected: 500},
+	}
+
+	for _, c := range cases {
+		// スタックトレースを付与してもエラーの種類が判定できることをチェック
+		res := ResponseError(errors.WithStack(errors.WithStack(c.Err)))
+		assert.Equal(t, c.Expected, res.StatusCode, c.Msg)
+	}
+}
+
+// TestResponseError_Validation 入力値によるエラーが項目ごとのメッセージに変換されること
+func TestResponseError_Validation(t *testing.T) {
+	cases := []struct {
+		Err      error
+		Expected map[string]string
+	}{
+		{
+			Err:      interactor.ErrUniqEmail,
+			Expected: map[string]string{"email": "すでに登録されているメールアドレスです。"},
+		},
+		{
+			Err:      domain.ErrInvalidCursor,
+			Expected: map[string]string{"cursor": "カーソルは不正な値です。"},
+		},
+		{
+			Err:      domain.NewValidationError("content", "本文が長すぎます。"),
+			Expected: map[string]string{"content": "本文が長すぎます。"},
+		},
+	}
+
+	for _, c := range cases {
+		res := ResponseError(errors.WithStack(c.Err))
+		assert.Equal(t, 400, res.StatusCode)
+
+		var body Response400Body
+		err := json.Unmarshal([]byte(res.Body), &body)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expected, body.Errors)
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"fmt"
+	"gopkg.in/validator.v2"
+	"strconv"
+	"strings"
+)
+
+// ETag リソースのバージョンからETagヘッダーの値を生成する
+func ETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// withETag レスポンスにETagヘッダーを付与する
+func withETag(res Response, version int) Response {
+	res.Headers["ETag"] = ETag(version)
+	exposeHeader(res.Headers, "ETag")
+	return res
+}
+
+// IfMatchVersion If-Matchヘッダーから操作対象のバージョンを取り出す。
+// ヘッダーがない場合や"*"の場合は0を返し、バージョンを確認せずに更新・削除する。
+// カンマ区切りで複数のETagが指定された場合は、currentで取得した現在のバージョンと一致するものを選ぶ。
+// 弱いETagは強い比較で一致しないものとして扱い、一致するETagがない場合は412、
+// ETagの形式として解釈できない場合は400レスポンスを返す
+func IfMatchVersion(headers map[string]string, current func() (int, error)) (int, *Response) {
+	value := strings.TrimSpace(headerValue(headers, "If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	tags, ok := parseETagList(value)
+	if !ok {
+		errRes := Response400(map[string]error{"If-Match": validator.ErrUnsupported})
+		return 0, &errRes
+	}
+
+	// このAPIが発行する強いETagのみがバージョンと一致しうる
+	var versions []int
+	for _, tag := range tags {
+		if tag.weak {
+			continue
+		}
+		version, err := strconv.Atoi(tag.opaque)
+		if err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	switch len(versions) {
+	case 0:
+		errRes := Response412()
+		return 0, &errRes
+	case 1:
+		// 現在のバージョンとの比較は更新・削除時の条件で行う
+		return versions[0], nil
+	}
+
+	currentVersion, err := current()
+	if err != nil {
+		errRes := ResponseError(err)
+		return 0, &errRes
+	}
+	for _, version := range versions {
+		if version == currentVersion {
+			return version, nil
+		}
+	}
+	errRes := Response412()
+	return 0, &errRes
+}
+
+// entityTag If-Matchヘッダーに指定されたETag
+type entityTag struct {
+	weak   bool
+	opaque string
+}
+
+// parseETagList カンマ区切りのETagの一覧を解釈する。形式が不正な場合はfalseを返す
+func parseETagList(value string) ([]entityTag, bool) {
+	var tags []entityTag
+	for _, element := range strings.Split(value, ",") {
+		element = strings.TrimSpace(element)
+		if element == "" {
+			continue
+		}
+
+		tag := entityTag{}
+		if strings.HasPrefix(element, "W/") {
+			tag.weak = true
+			element = element[len("W/"):]
+		}
+		if len(element) < 2 || element[0] != '"' || element[len(element)-1] != '"' {
+			return nil, false
+		}
+		tag.opaque = element[1 : len(element)-1]
+		for i := 0; i < len(tag.opaque); i++ {
+			if c := tag.opaque[i]; c == '"' || c <= ' ' || c == 0x7f {
+				return nil, false
+			}
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, len(tags) > 0
+}
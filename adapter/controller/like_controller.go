@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/registry"
+	"clean-serverless-book-sample-v2/usecase"
+	"clean-serverless-book-sample-v2/utils"
+)
+
+// PutLike いいね
+func PutLike(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得する
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// パスパラメータからマイクロポストIDを取得する
+	micropostID, err := utils.ParseUint(request.PathParameters["micropost_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// いいね処理
+	liker := registry.GetFactory().BuildLikeMicropost()
+	_, err = liker.Execute(request.Context(), &usecase.LikeMicropostRequest{
+		UserID:      userID,
+		MicropostID: micropostID,
+		Principal:   principal,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// 200レスポンス
+	return Response200OK()
+}
+
+// DeleteLike いいね取り消し
+func DeleteLike(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得する
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// パスパラメータからマイクロポストIDを取得する
+	micropostID, err := utils.ParseUint(request.PathParameters["micropost_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// いいね取り消し処理
+	unliker := registry.GetFactory().BuildUnlikeMicropost()
+	_, err = unliker.Execute(request.Context(), &usecase.UnlikeMicropostRequest{
+		UserID:      userID,
+		MicropostID: micropostID,
+		Principal:   principal,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// レスポンス
+	return Response200OK()
+}
+
+// GetLikes いいねしたユーザー一覧取得
+func GetLikes(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// パスパラメータからマイクロポストIDを取得
+	micropostID, err := utils.ParseUint(request.PathParameters["micropost_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
+	// いいねしたユーザー取得処理
+	getter := registry.GetFactory().BuildGetLikers()
+	res, err := getter.Execute(request.Context(), &usecase.GetLikersRequest{
+		UserID:      userID,
+		MicropostID: micropostID,
+		Limit:       paging.Limit,
+		Cursor:      paging.Cursor,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// レスポンス処理
+	return Response200(newUsersResponse(res.Users, res.NextCursor))
+}
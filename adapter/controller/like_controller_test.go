@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/mocks"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestPutLike_200 いいね 正常時
+func TestPutLike_200(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 投稿者とマイクロポスト、いいねするユーザーを作成
+	authorMock := tables.CreateUserMock(t, 1)
+	likerMock := tables.CreateUserMock(t, 2)
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", authorMock.ID))
+	assert.NoError(t, err)
+
+	// いいね処理。すでにいいねしている場合も成功する
+	for i := 0; i < 2; i++ {
+		res := PutLike(Request{
+			Headers: mocks.AuthHeaders(t, likerMock.ID),
+			PathParameters: map[string]string{
+				"user_id":      fmt.Sprintf("%d", authorMock.ID),
+				"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+			},
+		})
+		assert.Equal(t, 200, res.StatusCode)
+	}
+
+	// マイクロポストのいいねの件数をチェック
+	res := GetMicropost(Request{
+		Headers: mocks.AuthHeaders(t, likerMock.ID),
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", authorMock.ID),
+			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	assert.Equal(t, float64(1), body["like_count"])
+
+	// いいねしたユーザー一覧をチェック
+	res = GetLikes(Request{
+		Headers: mocks.AuthHeaders(t, authorMock.ID),
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", authorMock.ID),
+			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	users := body["users"].([]interface{})
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, float64(likerMock.ID), users[0].(map[string]interface{})["id"])
+		assert.Equal(t, likerMock.Name, users[0].(map[string]interface{})["user_name"])
+	}
+}
+
+// TestPutLike_404 いいね 存在しないマイクロポスト、または他のユーザーのマイクロポストとして指定した場合
+func TestPutLike_404(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	authorMock := tables.CreateUserMock(t, 1)
+	otherMock := tables.CreateUserMock(t, 2)
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", authorMock.ID))
+	assert.NoError(t, err)
+
+	cases := []map[string]string{
+		{"user_id": fmt.Sprintf("%d", authorMock.ID), "micropost_id": "999"},
+		{"user_id": fmt.Sprintf("%d", otherMock.ID), "micropost_id": fmt.Sprintf("%d", micropostMock.ID)},
+	}
+	for _, params := range cases {
+		res := PutLike(Request{
+			Headers:        mocks.AuthHeaders(t, otherMock.ID),
+			PathParameters: params,
+		})
+		assert.Equal(t, 404, res.StatusCode)
+	}
+
+	// いいねされていないことをチェック
+	micropost, err := tables.MicropostOperator.GetMicropostByID(context.Background(), micropostMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, micropost.LikeCount)
+}
+
+// TestPutLike_401 認証されていない場合
+func TestPutLike_401(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	authorMock := tables.CreateUserMock(t, 1)
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", authorMock.ID))
+	assert.NoError(t, err)
+
+	res := PutLike(Request{
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", authorMock.ID),
+			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+		},
+	})
+	assert.Equal(t, 401, res.StatusCode)
+}
+
+// TestDeleteLike いいね取り消し
+func TestDeleteLike(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	authorMock := tables.CreateUserMock(t, 1)
+	likerMock := tables.CreateUserMock(t, 2)
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", authorMock.ID))
+	assert.NoError(t, err)
+	err = tables.LikeOperator.Like(context.Background(), domain.NewLikeModel(micropostMock.ID, likerMock.ID))
+	assert.NoError(t, err)
+
+	// いいね取り消し処理。いいねしていない場合も成功する
+	for i := 0; i < 2; i++ {
+		res := DeleteLike(Request{
+			Headers: mocks.AuthHeaders(t, likerMock.ID),
+			PathParameters: map[string]string{
+				"user_id":      fmt.Sprintf("%d", authorMock.ID),
+				"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+			},
+		})
+		assert.Equal(t, 200, res.StatusCode)
+	}
+
+	// いいねが取り消されたことをチェック
+	micropost, err := tables.MicropostOperator.GetMicropostByID(context.Background(), micropostMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, micropost.LikeCount)
+
+	likes, _, err := tables.LikeOperator.GetLikes(context.Background(), micropostMock.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, likes, 0)
+}
+
+// TestGetLikes_Paging いいねしたユーザー一覧のページング
+func TestGetLikes_Paging(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 投稿者とマイクロポスト、いいねするユーザーを作成
+	authorMock := tables.CreateUserMock(t, 1)
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", authorMock.ID))
+	assert.NoError(t, err)
+	var likerMocks []*domain.UserModel
+	for i := 2; i <= 4; i++ {
+		likerMock := tables.CreateUserMock(t, i)
+		err := tables.LikeOperator.Like(context.Background(), domain.NewLikeModel(micropostMock.ID, likerMock.ID))
+		assert.NoError(t, err)
+		likerMocks = append(likerMocks, likerMock)
+	}
+
+	pathParameters := map[string]string{
+		"user_id":      fmt.Sprintf("%d", authorMock.ID),
+		"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+	}
+
+	// 1ページ目を取得
+	res := GetLikes(Request{
+		Headers:               mocks.AuthHeaders(t, authorMock.ID),
+		PathParameters:        pathParameters,
+		QueryStringParameters: map[string]string{"limit": "2"},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	actualUsers := body["users"].([]interface{})
+	assert.Len(t, actualUsers, 2)
+	assert.Equal(t, float64(likerMocks[0].ID), actualUsers[0].(map[string]interface{})["id"])
+	assert.Equal(t, float64(likerMocks[1].ID), actualUsers[1].(map[string]interface{})["id"])
+	cursor := body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// 2ページ目を取得
+	res = GetLikes(Request{
+		Headers:               mocks.AuthHeaders(t, authorMock.ID),
+		PathParameters:        pathParameters,
+		QueryStringParameters: map[string]string{"limit": "2", "cursor": cursor},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	actualUsers = body["users"].([]interface{})
+	if assert.Len(t, actualUsers, 1) {
+		assert.Equal(t, float64(likerMocks[2].ID), actualUsers[0].(map[string]interface{})["id"])
+	}
+	assert.Nil(t, body["next_cursor"])
+}
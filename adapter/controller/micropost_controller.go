@@ -6,7 +6,6 @@ import (
 	"clean-serverless-book-sample-v2/usecase"
 	"clean-serverless-book-sample-v2/utils"
 	"encoding/json"
-	"github.com/aws/aws-lambda-go/events"
 )
 
 // MicropostSettingsValidator バリデーション設定
@@ -16,6 +15,13 @@ func MicropostSettingsValidator() *Validator {
 	}
 }
 
+// PostMicropostSettingsValidator 新規作成時のバリデーション設定。返信先のマイクロポストIDを指定できる
+func PostMicropostSettingsValidator() *Validator {
+	validator := MicropostSettingsValidator()
+	validator.Settings = append(validator.Settings, &ValidatorSetting{ArgName: "in_reply_to_id", ValidateTags: "json_uint"})
+	return validator
+}
+
 // RequestMicropost HTTPリクエストで送られてくるJSON形式を表した構造体
 type RequestMicropost struct {
 	Content string `json:"content"`
@@ -24,6 +30,7 @@ type RequestMicropost struct {
 // RequestPostMicropost PostMicropostのリクエスト
 type RequestPostMicropost struct {
 	RequestMicropost
+	InReplyToID uint64 `json:"in_reply_to_id"`
 }
 
 // RequestPutMicropost PutMicropostのリクエスト
@@ -33,20 +40,39 @@ type RequestPutMicropost struct {
 
 // ResponseMicropost レスポンス用のJSON形式を表した構造体
 type ResponseMicropost struct {
-	ID      uint64 `json:"id"`
-	UserID  uint64 `json:"user_id"`
-	Content string `json:"content"`
+	ID          uint64 `json:"id"`
+	UserID      uint64 `json:"user_id"`
+	Content     string `json:"content"`
+	InReplyToID uint64 `json:"in_reply_to_id,omitempty"`
+	LikeCount   int    `json:"like_count"`
+	ReplyCount  int    `json:"reply_count"`
 }
 
 // ResponseMicroposts Micropostリストレスポンス用のJSON形式を表した構造体
 type ResponseMicroposts struct {
 	Microposts []*ResponseMicropost `json:"microposts"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// ResponseReplies 返信一覧レスポンス用のJSON形式を表した構造体。
+// 返信先が削除されている場合、InReplyToはnullでInReplyToDeletedがtrueになる
+type ResponseReplies struct {
+	InReplyTo        *ResponseMicropost   `json:"in_reply_to"`
+	InReplyToDeleted bool                 `json:"in_reply_to_deleted"`
+	Microposts       []*ResponseMicropost `json:"microposts"`
+	NextCursor       string               `json:"next_cursor,omitempty"`
 }
 
 // PostMicroposts 新規作成
-func PostMicroposts(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func PostMicroposts(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// バリデーション処理
-	validator := MicropostSettingsValidator()
+	validator := PostMicropostSettingsValidator()
 	validErr := validator.ValidateBody(request.Body)
 	if validErr != nil {
 		return Response400(validErr)
@@ -62,17 +88,19 @@ func PostMicroposts(request events.APIGatewayProxyRequest) events.APIGatewayProx
 	var req RequestPostMicropost
 	err = json.Unmarshal([]byte(request.Body), &req)
 	if err != nil {
-		return Response500(err)
+		return Response400(unmarshalBodyErrors(err))
 	}
 
 	// 新規作成処理
 	creator := registry.GetFactory().BuildCreateMicropost()
-	res, err := creator.Execute(&usecase.CreateMicropostRequest{
-		Content: req.Content,
-		UserID:  userID,
+	res, err := creator.Execute(request.Context(), &usecase.CreateMicropostRequest{
+		Content:     req.Content,
+		UserID:      userID,
+		InReplyToID: req.InReplyToID,
+		Principal:   principal,
 	})
 	if err != nil {
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// 201レスポンス
@@ -80,7 +108,13 @@ func PostMicroposts(request events.APIGatewayProxyRequest) events.APIGatewayProx
 }
 
 // PutMicropost 更新
-func PutMicropost(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func PutMicropost(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// バリデーション処理
 	validator := MicropostSettingsValidator()
 	validErr := validator.ValidateBody(request.Body)
@@ -104,18 +138,26 @@ func PutMicropost(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 	var req RequestPutMicropost
 	err = json.Unmarshal([]byte(request.Body), &req)
 	if err != nil {
-		return Response500(err)
+		return Response400(unmarshalBodyErrors(err))
+	}
+
+	// If-Matchヘッダーから操作対象のバージョンを取得する
+	version, preErr := IfMatchVersion(request.Headers, currentMicropostVersion(request, userID, micropostID))
+	if preErr != nil {
+		return *preErr
 	}
 
 	// 更新処理
 	updater := registry.GetFactory().BuildUpdateMicropost()
-	_, err = updater.Execute(&usecase.UpdateMicropostRequest{
+	_, err = updater.Execute(request.Context(), &usecase.UpdateMicropostRequest{
 		Content:     req.Content,
 		UserID:      userID,
 		MicropostID: micropostID,
+		Version:     version,
+		Principal:   principal,
 	})
 	if err != nil {
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// 200レスポンス
@@ -123,40 +165,152 @@ func PutMicropost(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 }
 
 // Execute 一覧取得
-func GetMicroposts(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func GetMicroposts(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// パスパラメータからユーザーIDを取得
 	userID, err := utils.ParseUint(request.PathParameters["user_id"])
 	if err != nil {
 		return Response500(err)
 	}
 
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
 	// マイクロポスト取得処理
 	getter := registry.GetFactory().BuildGetMicropostList()
-	res, err := getter.Execute(&usecase.GetMicropostListRequest{
+	res, err := getter.Execute(request.Context(), &usecase.GetMicropostListRequest{
 		UserID: userID,
+		Limit:  paging.Limit,
+		Cursor: paging.Cursor,
 	})
 	if err != nil {
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// ドメインモデルからレスポンス用の構造体に詰め替える
 	var resMicroposts = make([]*ResponseMicropost, len(res.Microposts))
 	for i, m := range res.Microposts {
-		resMicroposts[i] = &ResponseMicropost{
-			ID:      m.ID,
-			UserID:  m.UserID,
-			Content: m.Content,
-		}
+		resMicroposts[i] = newResponseMicropost(m)
 	}
 
 	// レスポンス処理
 	return Response200(&ResponseMicroposts{
 		Microposts: resMicroposts,
+		NextCursor: res.NextCursor,
 	})
 }
 
+// GetPublicTimeline 全てのユーザーのマイクロポストを新しい順に取得
+func GetPublicTimeline(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
+	// クエリパラメータから取得するIDの範囲を取得
+	sinceID, maxID, validErr := ParseIDRange(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
+	// 公開タイムライン取得処理
+	getter := registry.GetFactory().BuildGetPublicTimeline()
+	res, err := getter.Execute(request.Context(), &usecase.GetPublicTimelineRequest{
+		Limit:   paging.Limit,
+		Cursor:  paging.Cursor,
+		SinceID: sinceID,
+		MaxID:   maxID,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// ドメインモデルからレスポンス用の構造体に詰め替える
+	var resMicroposts = make([]*ResponseMicropost, len(res.Microposts))
+	for i, m := range res.Microposts {
+		resMicroposts[i] = newResponseMicropost(m)
+	}
+
+	// レスポンス処理
+	return Response200(&ResponseMicroposts{
+		Microposts: resMicroposts,
+		NextCursor: res.NextCursor,
+	})
+}
+
+// GetReplies マイクロポストへの返信を古い順に取得
+func GetReplies(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからマイクロポストIDを取得する
+	micropostID, err := utils.ParseUint(request.PathParameters["micropost_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
+	// 返信一覧取得処理
+	getter := registry.GetFactory().BuildGetReplies()
+	res, err := getter.Execute(request.Context(), &usecase.GetRepliesRequest{
+		MicropostID: micropostID,
+		Limit:       paging.Limit,
+		Cursor:      paging.Cursor,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// ドメインモデルからレスポンス用の構造体に詰め替える
+	var resMicroposts = make([]*ResponseMicropost, len(res.Microposts))
+	for i, m := range res.Microposts {
+		resMicroposts[i] = newResponseMicropost(m)
+	}
+
+	resReplies := &ResponseReplies{
+		InReplyToDeleted: res.InReplyTo == nil,
+		Microposts:       resMicroposts,
+		NextCursor:       res.NextCursor,
+	}
+	if res.InReplyTo != nil {
+		resReplies.InReplyTo = newResponseMicropost(res.InReplyTo)
+	}
+
+	// レスポンス処理
+	return Response200(resReplies)
+}
+
 // GetMicropost IDから取得
-func GetMicropost(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func GetMicropost(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// パスパラメータからユーザーIDを取得する
 	userID, err := utils.ParseUint(request.PathParameters["user_id"])
 	if err != nil {
@@ -171,27 +325,26 @@ func GetMicropost(request events.APIGatewayProxyRequest) events.APIGatewayProxyR
 
 	// マイクロポスト取得処理
 	getter := registry.GetFactory().BuildGetMicropostByID()
-	res, err := getter.Execute(&usecase.GetMicropostByIDRequest{
+	res, err := getter.Execute(request.Context(), &usecase.GetMicropostByIDRequest{
 		MicropostID: micropostID,
 		UserID:      userID,
 	})
 	if err != nil {
-		if err.Error() == domain.ErrNotFound.Error() {
-			return Response404()
-		}
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// ドメインモデルからレスポンス用構造体に詰め替えて、レスポンス
-	return Response200(&ResponseMicropost{
-		ID:      res.Micropost.ID,
-		Content: res.Micropost.Content,
-		UserID:  res.Micropost.UserID,
-	})
+	return withETag(Response200(newResponseMicropost(res.Micropost)), res.Micropost.Version)
 }
 
 // DeleteMicropost 削除処理
-func DeleteMicropost(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func DeleteMicropost(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// パスパラメータからユーザーIDを取得する
 	userID, err := utils.ParseUint(request.PathParameters["user_id"])
 	if err != nil {
@@ -204,16 +357,51 @@ func DeleteMicropost(request events.APIGatewayProxyRequest) events.APIGatewayPro
 		return Response500(err)
 	}
 
+	// If-Matchヘッダーから操作対象のバージョンを取得する
+	version, preErr := IfMatchVersion(request.Headers, currentMicropostVersion(request, userID, micropostID))
+	if preErr != nil {
+		return *preErr
+	}
+
 	// 削除処理
 	deleter := registry.GetFactory().BuildDeleteMicropost()
-	_, err = deleter.Execute(&usecase.DeleteMicropostRequest{
+	_, err = deleter.Execute(request.Context(), &usecase.DeleteMicropostRequest{
 		MicropostID: micropostID,
 		UserID:      userID,
+		Version:     version,
+		Principal:   principal,
 	})
 	if err != nil {
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// レスポンス
 	return Response200OK()
 }
+
+// newResponseMicropost ドメインモデルからレスポンス用の構造体に詰め替える
+func newResponseMicropost(m *domain.MicropostModel) *ResponseMicropost {
+	return &ResponseMicropost{
+		ID:          m.ID,
+		UserID:      m.UserID,
+		Content:     m.Content,
+		InReplyToID: m.InReplyToID,
+		LikeCount:   m.LikeCount,
+		ReplyCount:  m.ReplyCount,
+	}
+}
+
+// currentMicropostVersion If-Matchに複数のETagが指定された場合に、マイクロポストの現在のバージョンを取得する
+func currentMicropostVersion(request Request, userID, micropostID uint64) func() (int, error) {
+	return func() (int, error) {
+		getter := registry.GetFactory().BuildGetMicropostByID()
+		res, err := getter.Execute(request.Context(), &usecase.GetMicropostByIDRequest{
+			MicropostID: micropostID,
+			UserID:      userID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return res.Micropost.Version, nil
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"clean-serverless-book-sample-v2/mocks"
 	"clean-serverless-book-sample-v2/registry"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
@@ -26,11 +26,13 @@ func TestPostMicroposts_201(t *testing.T) {
 	bodyStr, err := json.Marshal(body)
 	assert.NoError(t, err)
 
-	userID := uint64(1)
+	// マイクロポストの投稿者となるユーザーを作成
+	userID := tables.CreateUserMock(t, 1).ID
 
 	// 新規作成処理
-	res := PostMicroposts(events.APIGatewayProxyRequest{
-		Body: string(bodyStr),
+	res := PostMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, userID),
+		Body:    string(bodyStr),
 		PathParameters: map[string]string{
 			"user_id": fmt.Sprintf("%d", userID),
 		},
@@ -50,7 +52,7 @@ func TestPostMicroposts_201(t *testing.T) {
 
 	// DynamoDBに保存されているかチェック
 	getter := registry.GetFactory().BuildGetMicropostByID()
-	micropostRes, err := getter.Execute(&usecase.GetMicropostByIDRequest{
+	micropostRes, err := getter.Execute(context.Background(), &usecase.GetMicropostByIDRequest{
 		UserID:      userID,
 		MicropostID: id,
 	})
@@ -87,6 +89,56 @@ func TestPostMicroposts_400(t *testing.T) {
 				"content": "本文の文字数が上限を超えています。",
 			},
 		},
+		// 返信先のマイクロポストIDが負の数の場合
+		{
+			Request: map[string]interface{}{
+				"content":        "a",
+				"in_reply_to_id": -1,
+			},
+			Expected: map[string]interface{}{
+				"in_reply_to_id": "返信先のマイクロポストIDは0以上の数値を入力してください。",
+			},
+		},
+		// 返信先のマイクロポストIDが数値の文字列の場合
+		{
+			Request: map[string]interface{}{
+				"content":        "a",
+				"in_reply_to_id": "5",
+			},
+			Expected: map[string]interface{}{
+				"in_reply_to_id": "返信先のマイクロポストIDは0以上の数値を入力してください。",
+			},
+		},
+		// 返信先のマイクロポストIDが小数の場合
+		{
+			Request: map[string]interface{}{
+				"content":        "a",
+				"in_reply_to_id": 1.5,
+			},
+			Expected: map[string]interface{}{
+				"in_reply_to_id": "返信先のマイクロポストIDは0以上の数値を入力してください。",
+			},
+		},
+		// 返信先のマイクロポストIDが整数に切り捨てると0になる負の数の場合
+		{
+			Request: map[string]interface{}{
+				"content":        "a",
+				"in_reply_to_id": -0.5,
+			},
+			Expected: map[string]interface{}{
+				"in_reply_to_id": "返信先のマイクロポストIDは0以上の数値を入力してください。",
+			},
+		},
+		// 返信先のマイクロポストIDがuint64の範囲を超えている場合
+		{
+			Request: map[string]interface{}{
+				"content":        "a",
+				"in_reply_to_id": 1e20,
+			},
+			Expected: map[string]interface{}{
+				"in_reply_to_id": "返信先のマイクロポストIDは不正な値です。",
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -96,8 +148,9 @@ func TestPostMicroposts_400(t *testing.T) {
 		bodyStr, err := json.Marshal(body)
 		assert.NoError(t, err)
 
-		res := PostMicroposts(events.APIGatewayProxyRequest{
-			Body: string(bodyStr),
+		res := PostMicroposts(Request{
+			Headers: mocks.AuthHeaders(t, 1),
+			Body:    string(bodyStr),
 			PathParameters: map[string]string{
 				"user_id": "1",
 			},
@@ -120,10 +173,13 @@ func TestPutMicropost_200(t *testing.T) {
 	tables := mocks.SetupDB(t)
 	defer tables.Cleanup()
 
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+
 	// 更新用モックデータを作成
-	micropostMock, err := tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_1",
-		UserID:  1,
+		UserID:  userMock.ID,
 	})
 	assert.NoError(t, err)
 
@@ -135,8 +191,9 @@ func TestPutMicropost_200(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 更新処理
-	res := PutMicropost(events.APIGatewayProxyRequest{
-		Body: string(bodyStr),
+	res := PutMicropost(Request{
+		Headers: mocks.AuthHeaders(t, micropostMock.UserID),
+		Body:    string(bodyStr),
 		PathParameters: map[string]string{
 			"user_id":      fmt.Sprintf("%d", micropostMock.UserID),
 			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
@@ -152,7 +209,7 @@ func TestPutMicropost_200(t *testing.T) {
 	assert.NoError(t, err)
 
 	// DynamoDBに更新データが反映されているかチェック
-	micropost, err := tables.MicropostOperator.GetMicropostByID(micropostMock.ID)
+	micropost, err := tables.MicropostOperator.GetMicropostByID(context.Background(), micropostMock.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, body["content"].(string), micropost.Content)
 }
@@ -163,10 +220,13 @@ func TestPutMicropost_400(t *testing.T) {
 	tables := mocks.SetupDB(t)
 	defer tables.Cleanup()
 
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+
 	// 更新用モックデータを作成
-	micropostMock, err := tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_1",
-		UserID:  1,
+		UserID:  userMock.ID,
 	})
 	assert.NoError(t, err)
 
@@ -201,8 +261,9 @@ func TestPutMicropost_400(t *testing.T) {
 		bodyStr, err := json.Marshal(body)
 		assert.NoError(t, err)
 
-		res := PutMicropost(events.APIGatewayProxyRequest{
-			Body: string(bodyStr),
+		res := PutMicropost(Request{
+			Headers: mocks.AuthHeaders(t, micropostMock.UserID),
+			Body:    string(bodyStr),
 			PathParameters: map[string]string{
 				"user_id":      fmt.Sprintf("%d", micropostMock.UserID),
 				"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
@@ -226,15 +287,19 @@ func TestGetMicropost(t *testing.T) {
 	tables := mocks.SetupDB(t)
 	defer tables.Cleanup()
 
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+
 	// 取得用のモックデータを作成
-	micropostMock, err := tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_1",
-		UserID:  1,
+		UserID:  userMock.ID,
 	})
 	assert.NoError(t, err)
 
 	// 取得処理
-	res := GetMicropost(events.APIGatewayProxyRequest{
+	res := GetMicropost(Request{
+		Headers: mocks.AuthHeaders(t, micropostMock.UserID),
 		PathParameters: map[string]string{
 			"user_id":      fmt.Sprintf("%d", micropostMock.UserID),
 			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
@@ -255,28 +320,33 @@ func TestGetMicroposts(t *testing.T) {
 	tables := mocks.SetupDB(t)
 	defer tables.Cleanup()
 
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+	otherUserMock := tables.CreateUserMock(t, 2)
+
 	// 取得用のモックデータを作成
-	micropostMock1, err := tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	micropostMock1, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_1",
-		UserID:  1,
+		UserID:  userMock.ID,
 	})
 	assert.NoError(t, err)
 
-	micropostMock2, err := tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	micropostMock2, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_2",
-		UserID:  1,
+		UserID:  userMock.ID,
 	})
 	assert.NoError(t, err)
 
 	// このデータはUserIDが異なるので取得されない想定
-	_, err = tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	_, err = tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_3",
-		UserID:  2,
+		UserID:  otherUserMock.ID,
 	})
 	assert.NoError(t, err)
 
 	// 一覧取得処理
-	res := GetMicroposts(events.APIGatewayProxyRequest{
+	res := GetMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, 1),
 		PathParameters: map[string]string{
 			"user_id": "1",
 		},
@@ -312,15 +382,19 @@ func TestDeleteMicropost(t *testing.T) {
 	tables := mocks.SetupDB(t)
 	defer tables.Cleanup()
 
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+
 	// 削除用モックデータを作成
-	micropostMock, err := tables.MicropostOperator.CreateMicropost(&domain.MicropostModel{
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
 		Content: "Content_1",
-		UserID:  1,
+		UserID:  userMock.ID,
 	})
 	assert.NoError(t, err)
 
 	// 削除処理
-	res := DeleteMicropost(events.APIGatewayProxyRequest{
+	res := DeleteMicropost(Request{
+		Headers: mocks.AuthHeaders(t, micropostMock.UserID),
 		PathParameters: map[string]string{
 			"user_id":      fmt.Sprintf("%d", micropostMock.UserID),
 			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
@@ -331,7 +405,557 @@ func TestDeleteMicropost(t *testing.T) {
 	assert.Equal(t, 200, res.StatusCode)
 
 	// DynamoDBからデータが削除されているかチェック
-	microposts, err := tables.MicropostOperator.GetMicropostsByUserID(micropostMock.UserID)
+	microposts, _, err := tables.MicropostOperator.GetMicropostsByUserID(context.Background(), micropostMock.UserID, nil)
 	assert.NoError(t, err)
 	assert.Len(t, microposts, 0)
 }
+
+// TestGetMicroposts_Paging 一覧取得処理 ページング
+func TestGetMicroposts_Paging(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+
+	// 取得用のモックデータを作成
+	var micropostMocks []*domain.MicropostModel
+	for i := 1; i <= 3; i++ {
+		m, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+			Content: fmt.Sprintf("Content_%d", i),
+			UserID:  userMock.ID,
+		})
+		assert.NoError(t, err)
+		micropostMocks = append(micropostMocks, m)
+	}
+
+	// 1ページ目を取得
+	res := GetMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+		QueryStringParameters: map[string]string{
+			"limit": "2",
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	actualMicroposts := body["microposts"].([]interface{})
+	assert.Len(t, actualMicroposts, 2)
+	assert.Equal(t, float64(micropostMocks[0].ID), actualMicroposts[0].(map[string]interface{})["id"])
+	assert.Equal(t, float64(micropostMocks[1].ID), actualMicroposts[1].(map[string]interface{})["id"])
+	cursor := body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// 別のユーザーの一覧にはカーソルを使えない
+	res = GetMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID+1),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID+1),
+		},
+		QueryStringParameters: map[string]string{
+			"cursor": cursor,
+		},
+	})
+	assert.Equal(t, 400, res.StatusCode)
+
+	// 2ページ目を取得
+	res = GetMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+		QueryStringParameters: map[string]string{
+			"limit":  "2",
+			"cursor": cursor,
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	actualMicroposts = body["microposts"].([]interface{})
+	assert.Len(t, actualMicroposts, 1)
+	assert.Equal(t, float64(micropostMocks[2].ID), actualMicroposts[0].(map[string]interface{})["id"])
+	assert.Nil(t, body["next_cursor"])
+}
+
+// TestPostMicroposts_404 新規作成処理 ユーザーが存在しない場合
+func TestPostMicroposts_404(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// リクエスト用パラメータ
+	body := map[string]interface{}{
+		"content": "Content_1",
+	}
+	bodyStr, err := json.Marshal(body)
+	assert.NoError(t, err)
+
+	// 存在しないユーザーで新規作成処理
+	res := PostMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, 999),
+		Body:    string(bodyStr),
+		PathParameters: map[string]string{
+			"user_id": "999",
+		},
+	})
+
+	// レスポンスコードチェック
+	assert.Equal(t, 404, res.StatusCode)
+
+	// DynamoDBに保存されていないことをチェック
+	microposts, _, err := tables.MicropostOperator.GetMicropostsByUserID(context.Background(), 999, nil)
+	assert.NoError(t, err)
+	assert.Len(t, microposts, 0)
+
+	// リポジトリを直接使った場合も作成できないことをチェック
+	_, err = tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+		Content: "Content_1",
+		UserID:  999,
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, domain.ErrNotFound.Error(), err.Error())
+	}
+}
+
+// TestMicropost_403 本人以外がマイクロポストを作成・更新・削除しようとした場合
+func TestMicropost_403(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// マイクロポストの投稿者と、別のユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+	otherUserMock := tables.CreateUserMock(t, 2)
+
+	// モックデータを作成
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+		Content: "Content_1",
+		UserID:  userMock.ID,
+	})
+	assert.NoError(t, err)
+
+	body := mocks.MarshalJSON(t, map[string]interface{}{
+		"content": "Content_update",
+	})
+
+	// 新規作成処理
+	res := PostMicroposts(Request{
+		Headers: mocks.AuthHeaders(t, otherUserMock.ID),
+		Body:    body,
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+	assert.Equal(t, 403, res.StatusCode)
+
+	// 更新処理
+	res = PutMicropost(Request{
+		Headers: mocks.AuthHeaders(t, otherUserMock.ID),
+		Body:    body,
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", userMock.ID),
+			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+		},
+	})
+	assert.Equal(t, 403, res.StatusCode)
+
+	// 削除処理
+	res = DeleteMicropost(Request{
+		Headers: mocks.AuthHeaders(t, otherUserMock.ID),
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", userMock.ID),
+			"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+		},
+	})
+	assert.Equal(t, 403, res.StatusCode)
+
+	// DynamoDBのデータが変更されていないことをチェック
+	microposts, _, err := tables.MicropostOperator.GetMicropostsByUserID(context.Background(), userMock.ID, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, microposts, 1) {
+		assert.Equal(t, micropostMock.Content, microposts[0].Content)
+	}
+}
+
+// TestPutMicropost_404 更新処理 存在しない、または他のユーザーのマイクロポストを指定した場合
+func TestPutMicropost_404(t *testing.T) {
+	// テスト用のDynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// マイクロポストの投稿者と、別のユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+	otherUserMock := tables.CreateUserMock(t, 2)
+
+	// 更新用モックデータを作成
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+		Content: "Content_1",
+		UserID:  userMock.ID,
+	})
+	assert.NoError(t, err)
+
+	body := mocks.MarshalJSON(t, map[string]interface{}{
+		"content": "Content_update",
+	})
+
+	cases := []struct {
+		Msg         string
+		UserID      uint64
+		MicropostID uint64
+	}{
+		{
+			Msg:         "存在しないマイクロポスト",
+			UserID:      userMock.ID,
+			MicropostID: micropostMock.ID + 1000,
+		},
+		{
+			Msg:         "他のユーザーのマイクロポスト",
+			UserID:      otherUserMock.ID,
+			MicropostID: micropostMock.ID,
+		},
+	}
+
+	for _, c := range cases {
+		// 更新処理
+		res := PutMicropost(Request{
+			Headers: mocks.AuthHeaders(t, c.UserID),
+			Body:    body,
+			PathParameters: map[string]string{
+				"user_id":      fmt.Sprintf("%d", c.UserID),
+				"micropost_id": fmt.Sprintf("%d", c.MicropostID),
+			},
+		})
+
+		// レスポンスコードをチェック
+		assert.Equal(t, 404, res.StatusCode, c.Msg)
+	}
+
+	// DynamoDBのデータが更新されていないことをチェック
+	micropost, err := tables.MicropostOperator.GetMicropostByID(context.Background(), micropostMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, micropostMock.Content, micropost.Content)
+	assert.Equal(t, userMock.ID, micropost.UserID)
+}
+
+// TestMicropost_412 If-Matchのバージョンが一致しない場合の更新・削除
+func TestMicropost_412(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// マイクロポストの投稿者となるユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+
+	// モックデータを作成
+	micropostMock, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+		Content: "Content_1",
+		UserID:  userMock.ID,
+	})
+	assert.NoError(t, err)
+
+	pathParameters := map[string]string{
+		"user_id":      fmt.Sprintf("%d", userMock.ID),
+		"micropost_id": fmt.Sprintf("%d", micropostMock.ID),
+	}
+
+	// 取得処理でETagを取得
+	res := GetMicropost(Request{
+		Headers:        mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 200, res.StatusCode)
+	etag := res.Headers["ETag"]
+	assert.Equal(t, ETag(micropostMock.Version), etag)
+
+	headers := mocks.AuthHeaders(t, userMock.ID)
+	headers["If-Match"] = etag
+
+	// 取得したETagを指定した更新は成功する
+	res = PutMicropost(Request{
+		Headers:        headers,
+		Body:           mocks.MarshalJSON(t, map[string]interface{}{"content": "Content_update1"}),
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	// 同じETagを指定した更新・削除は、バージョンが進んでいるため失敗する
+	res = PutMicropost(Request{
+		Headers:        headers,
+		Body:           mocks.MarshalJSON(t, map[string]interface{}{"content": "Content_update2"}),
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 412, res.StatusCode)
+
+	res = DeleteMicropost(Request{
+		Headers:        headers,
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 412, res.StatusCode)
+
+	// DynamoDBには1回目の更新だけが反映されていることをチェック
+	micropost, err := tables.MicropostOperator.GetMicropostByID(context.Background(), micropostMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Content_update1", micropost.Content)
+
+	// 最新のETagを指定すれば削除できる
+	headers["If-Match"] = ETag(micropost.Version)
+	res = DeleteMicropost(Request{
+		Headers:        headers,
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 200, res.StatusCode)
+}
+
+// TestGetPublicTimeline 公開タイムライン取得
+func TestGetPublicTimeline(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 複数のユーザーのマイクロポストを作成
+	userMock := tables.CreateUserMock(t, 1)
+	otherUserMock := tables.CreateUserMock(t, 2)
+	var micropostMocks []*domain.MicropostModel
+	for i := 1; i <= 4; i++ {
+		userID := userMock.ID
+		if i%2 == 0 {
+			userID = otherUserMock.ID
+		}
+		m, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+			Content: fmt.Sprintf("Content_%d", i),
+			UserID:  userID,
+		})
+		assert.NoError(t, err)
+		micropostMocks = append(micropostMocks, m)
+	}
+
+	// 1ページ目を取得
+	res := GetPublicTimeline(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		QueryStringParameters: map[string]string{
+			"limit": "3",
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	// 新しい順に取得できているかをチェック
+	body := mocks.UnmarshalJSON(t, res.Body)
+	actualMicroposts := body["microposts"].([]interface{})
+	if assert.Len(t, actualMicroposts, 3) {
+		for i, expected := range []*domain.MicropostModel{micropostMocks[3], micropostMocks[2], micropostMocks[1]} {
+			actual := actualMicroposts[i].(map[string]interface{})
+			assert.Equal(t, float64(expected.ID), actual["id"])
+			assert.Equal(t, float64(expected.UserID), actual["user_id"])
+			assert.Equal(t, expected.Content, actual["content"])
+		}
+	}
+	cursor := body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// 2ページ目を取得
+	res = GetPublicTimeline(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		QueryStringParameters: map[string]string{
+			"limit":  "3",
+			"cursor": cursor,
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	actualMicroposts = body["microposts"].([]interface{})
+	if assert.Len(t, actualMicroposts, 1) {
+		assert.Equal(t, float64(micropostMocks[0].ID), actualMicroposts[0].(map[string]interface{})["id"])
+	}
+	assert.Nil(t, body["next_cursor"])
+
+	// since_idとmax_idで範囲を指定
+	res = GetPublicTimeline(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		QueryStringParameters: map[string]string{
+			"since_id": fmt.Sprintf("%d", micropostMocks[0].ID),
+			"max_id":   fmt.Sprintf("%d", micropostMocks[2].ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	actualMicroposts = body["microposts"].([]interface{})
+	if assert.Len(t, actualMicroposts, 2) {
+		assert.Equal(t, float64(micropostMocks[2].ID), actualMicroposts[0].(map[string]interface{})["id"])
+		assert.Equal(t, float64(micropostMocks[1].ID), actualMicroposts[1].(map[string]interface{})["id"])
+	}
+}
+
+// TestGetPublicTimeline_400 公開タイムライン取得 不正なクエリパラメータの場合
+func TestGetPublicTimeline_400(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	cases := []struct {
+		Query    map[string]string
+		Expected map[string]interface{}
+	}{
+		{
+			Query:    map[string]string{"since_id": "abc"},
+			Expected: map[string]interface{}{"since_id": "取得範囲の開始IDは0以上の数値を入力してください。"},
+		},
+		{
+			Query:    map[string]string{"max_id": "-1"},
+			Expected: map[string]interface{}{"max_id": "取得範囲の終了IDは0以上の数値を入力してください。"},
+		},
+		{
+			Query:    map[string]string{"limit": "0"},
+			Expected: map[string]interface{}{"limit": "取得件数は1から100の範囲で指定してください。"},
+		},
+	}
+
+	for i, c := range cases {
+		msg := fmt.Sprintf("Case:%d", i+1)
+
+		res := GetPublicTimeline(Request{
+			Headers:               mocks.AuthHeaders(t, 1),
+			QueryStringParameters: c.Query,
+		})
+		assert.Equal(t, 400, res.StatusCode, msg)
+
+		body := mocks.UnmarshalJSON(t, res.Body)
+		assert.Equal(t, c.Expected, body["errors"], msg)
+	}
+}
+
+// TestPostMicroposts_Reply 返信の新規作成
+func TestPostMicroposts_Reply(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	author := tables.CreateUserMock(t, 1)
+	replier := tables.CreateUserMock(t, 2)
+	micropost, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", author.ID))
+	assert.NoError(t, err)
+
+	post := func(inReplyToID uint64) Response {
+		bodyStr, err := json.Marshal(map[string]interface{}{
+			"content":        "Reply_1",
+			"in_reply_to_id": inReplyToID,
+		})
+		assert.NoError(t, err)
+
+		return PostMicroposts(Request{
+			Headers: mocks.AuthHeaders(t, replier.ID),
+			Body:    string(bodyStr),
+			PathParameters: map[string]string{
+				"user_id": fmt.Sprintf("%d", replier.ID),
+			},
+		})
+	}
+
+	// 返信を作成すると、返信先の返信数が増える
+	res := post(micropost.ID)
+	assert.Equal(t, 201, res.StatusCode)
+	replyID := uint64(mocks.UnmarshalJSON(t, res.Body)["id"].(float64))
+
+	res = GetMicropost(Request{
+		Headers: mocks.AuthHeaders(t, replier.ID),
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", replier.ID),
+			"micropost_id": fmt.Sprintf("%d", replyID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, float64(micropost.ID), mocks.UnmarshalJSON(t, res.Body)["in_reply_to_id"])
+
+	res = GetMicropost(Request{
+		Headers: mocks.AuthHeaders(t, author.ID),
+		PathParameters: map[string]string{
+			"user_id":      fmt.Sprintf("%d", author.ID),
+			"micropost_id": fmt.Sprintf("%d", micropost.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+	body := mocks.UnmarshalJSON(t, res.Body)
+	assert.Equal(t, float64(1), body["reply_count"])
+	assert.NotContains(t, body, "in_reply_to_id")
+
+	// 存在しないマイクロポストには返信できない
+	res = post(micropost.ID + 1000)
+	assert.Equal(t, 400, res.StatusCode)
+	assert.Equal(t, map[string]interface{}{
+		"in_reply_to_id": "返信先のマイクロポストが存在しません。",
+	}, mocks.UnmarshalJSON(t, res.Body)["errors"])
+}
+
+// TestGetReplies 返信一覧取得
+func TestGetReplies(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	author := tables.CreateUserMock(t, 1)
+	replier := tables.CreateUserMock(t, 2)
+	micropost, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewMicropostModel("Content_1", author.ID))
+	assert.NoError(t, err)
+	var replies []*domain.MicropostModel
+	for i := 1; i <= 3; i++ {
+		reply, err := tables.MicropostOperator.CreateMicropost(context.Background(), domain.NewReplyModel(fmt.Sprintf("Reply_%d", i), replier.ID, micropost.ID))
+		assert.NoError(t, err)
+		replies = append(replies, reply)
+	}
+
+	get := func(micropostID uint64, query map[string]string) Response {
+		return GetReplies(Request{
+			Headers: mocks.AuthHeaders(t, replier.ID),
+			PathParameters: map[string]string{
+				"micropost_id": fmt.Sprintf("%d", micropostID),
+			},
+			QueryStringParameters: query,
+		})
+	}
+
+	// 1ページ目を取得。返信先と返信が古い順に返る
+	res := get(micropost.ID, map[string]string{"limit": "2"})
+	assert.Equal(t, 200, res.StatusCode)
+	body := mocks.UnmarshalJSON(t, res.Body)
+	inReplyTo := body["in_reply_to"].(map[string]interface{})
+	assert.Equal(t, float64(micropost.ID), inReplyTo["id"])
+	assert.Equal(t, float64(3), inReplyTo["reply_count"])
+	assert.Equal(t, false, body["in_reply_to_deleted"])
+	actualReplies := body["microposts"].([]interface{})
+	if assert.Len(t, actualReplies, 2) {
+		for i, expected := range replies[:2] {
+			actual := actualReplies[i].(map[string]interface{})
+			assert.Equal(t, float64(expected.ID), actual["id"])
+			assert.Equal(t, float64(micropost.ID), actual["in_reply_to_id"])
+			assert.Equal(t, expected.Content, actual["content"])
+		}
+	}
+	cursor := body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// 2ページ目を取得
+	res = get(micropost.ID, map[string]string{"limit": "2", "cursor": cursor})
+	assert.Equal(t, 200, res.StatusCode)
+	body = mocks.UnmarshalJSON(t, res.Body)
+	actualReplies = body["microposts"].([]interface{})
+	if assert.Len(t, actualReplies, 1) {
+		assert.Equal(t, float64(replies[2].ID), actualReplies[0].(map[string]interface{})["id"])
+	}
+	assert.Nil(t, body["next_cursor"])
+
+	// 返信先を削除しても返信は残り、返信先は削除済みとして返る
+	err = tables.MicropostOperator.DeleteMicropost(context.Background(), &domain.MicropostModel{ID: micropost.ID})
+	assert.NoError(t, err)
+
+	res = get(micropost.ID, nil)
+	assert.Equal(t, 200, res.StatusCode)
+	body = mocks.UnmarshalJSON(t, res.Body)
+	assert.Nil(t, body["in_reply_to"])
+	assert.Equal(t, true, body["in_reply_to_deleted"])
+	assert.Len(t, body["microposts"].([]interface{}), 3)
+
+	// 返信先も返信も存在しない場合
+	res = get(micropost.ID+1000, nil)
+	assert.Equal(t, 404, res.StatusCode)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/utils"
+	"gopkg.in/validator.v2"
+)
+
+const (
+	// defaultPagingLimit limitが指定されなかった場合の取得件数
+	defaultPagingLimit = 20
+	// maxPagingLimit 一度に取得できる最大件数
+	maxPagingLimit = 100
+)
+
+// RequestPaging クエリパラメータで送られてくるページング条件
+type RequestPaging struct {
+	Limit  int
+	Cursor string
+}
+
+// ParsePaging クエリパラメータからページング条件を取得する
+func ParsePaging(request Request) (*RequestPaging, map[string]error) {
+	paging := &RequestPaging{
+		Limit:  defaultPagingLimit,
+		Cursor: request.QueryStringParameters["cursor"],
+	}
+
+	limit := request.QueryStringParameters["limit"]
+	if limit == "" {
+		return paging, nil
+	}
+
+	n, err := utils.ParseUint(limit)
+	if err != nil {
+		return nil, map[string]error{"limit": validator.ErrUnsupported}
+	}
+	if n < 1 || n > maxPagingLimit {
+		return nil, map[string]error{"limit": ErrLimit}
+	}
+	paging.Limit = int(n)
+
+	return paging, nil
+}
+
+// ParseIDRange クエリパラメータのsince_idとmax_idを取得する。指定されなかった場合は0を返す
+func ParseIDRange(request Request) (uint64, uint64, map[string]error) {
+	var ids [2]uint64
+	for i, name := range []string{"since_id", "max_id"} {
+		value := request.QueryStringParameters[name]
+		if value == "" {
+			continue
+		}
+
+		n, err := utils.ParseUint(value)
+		if err != nil {
+			return 0, 0, map[string]error{name: ErrUint}
+		}
+		ids[i] = n
+	}
+
+	return ids[0], ids[1], nil
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/registry"
+	"clean-serverless-book-sample-v2/usecase"
+	"clean-serverless-book-sample-v2/utils"
+)
+
+// PutFollowing フォロー
+func PutFollowing(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得する
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// パスパラメータからフォロー対象のユーザーIDを取得する
+	targetID, err := utils.ParseUint(request.PathParameters["target_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// フォロー処理
+	follower := registry.GetFactory().BuildFollowUser()
+	_, err = follower.Execute(request.Context(), &usecase.FollowUserRequest{
+		UserID:    userID,
+		TargetID:  targetID,
+		Principal: principal,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// 200レスポンス
+	return Response200OK()
+}
+
+// DeleteFollowing フォロー解除
+func DeleteFollowing(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得する
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// パスパラメータからフォロー対象のユーザーIDを取得する
+	targetID, err := utils.ParseUint(request.PathParameters["target_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// フォロー解除処理
+	unfollower := registry.GetFactory().BuildUnfollowUser()
+	_, err = unfollower.Execute(request.Context(), &usecase.UnfollowUserRequest{
+		UserID:    userID,
+		TargetID:  targetID,
+		Principal: principal,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// レスポンス
+	return Response200OK()
+}
+
+// GetFollowers フォロワー一覧取得
+func GetFollowers(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
+	// フォロワー取得処理
+	getter := registry.GetFactory().BuildGetFollowers()
+	res, err := getter.Execute(request.Context(), &usecase.GetFollowersRequest{
+		UserID: userID,
+		Limit:  paging.Limit,
+		Cursor: paging.Cursor,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// レスポンス処理
+	return Response200(newUsersResponse(res.Users, res.NextCursor))
+}
+
+// GetFollowing フォロー中のユーザー一覧取得
+func GetFollowing(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// パスパラメータからユーザーIDを取得
+	userID, err := utils.ParseUint(request.PathParameters["user_id"])
+	if err != nil {
+		return Response500(err)
+	}
+
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
+	// フォロー中のユーザー取得処理
+	getter := registry.GetFactory().BuildGetFollowing()
+	res, err := getter.Execute(request.Context(), &usecase.GetFollowingRequest{
+		UserID: userID,
+		Limit:  paging.Limit,
+		Cursor: paging.Cursor,
+	})
+	if err != nil {
+		return ResponseError(err)
+	}
+
+	// レスポンス処理
+	return Response200(newUsersResponse(res.Users, res.NextCursor))
+}
+
+// newUsersResponse ドメインモデルからレスポンス用の構造体に詰め替える
+func newUsersResponse(users []*domain.UserModel, nextCursor string) *UsersResponse {
+	var resUsers = make([]*UserResponse, len(users))
+	for i, u := range users {
+		resUsers[i] = newUserResponse(u)
+	}
+	return &UsersResponse{
+		Users:      resUsers,
+		NextCursor: nextCursor,
+	}
+}
@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/mocks"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestPutFollowing_200 フォロー 正常時
+func TestPutFollowing_200(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// フォローするユーザーと、フォロー対象のユーザーを作成
+	userMock := tables.CreateUserMock(t, 1)
+	targetMock := tables.CreateUserMock(t, 2)
+
+	// フォロー処理。すでにフォローしている場合も成功する
+	for i := 0; i < 2; i++ {
+		res := PutFollowing(Request{
+			Headers: mocks.AuthHeaders(t, userMock.ID),
+			PathParameters: map[string]string{
+				"user_id":   fmt.Sprintf("%d", userMock.ID),
+				"target_id": fmt.Sprintf("%d", targetMock.ID),
+			},
+		})
+		assert.Equal(t, 200, res.StatusCode)
+	}
+
+	// フォロー中のユーザー一覧をチェック
+	res := GetFollowing(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	users := body["users"].([]interface{})
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, float64(targetMock.ID), users[0].(map[string]interface{})["id"])
+		assert.Equal(t, targetMock.Name, users[0].(map[string]interface{})["user_name"])
+	}
+
+	// フォロワー一覧をチェック
+	res = GetFollowers(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", targetMock.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	users = body["users"].([]interface{})
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, float64(userMock.ID), users[0].(map[string]interface{})["id"])
+	}
+
+	// フォローは一方向のみ
+	following, err := tables.RelationshipOperator.IsFollowing(context.Background(), targetMock.ID, userMock.ID)
+	assert.NoError(t, err)
+	assert.False(t, following)
+}
+
+// TestPutFollowing_400 フォロー 自分自身をフォローしようとした場合
+func TestPutFollowing_400(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	userMock := tables.CreateUserMock(t, 1)
+
+	res := PutFollowing(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id":   fmt.Sprintf("%d", userMock.ID),
+			"target_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+	assert.Equal(t, 400, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	assert.Equal(t, map[string]interface{}{
+		"target_id": "自分自身をフォローすることはできません。",
+	}, body["errors"])
+}
+
+// TestPutFollowing_404 フォロー 存在しないユーザーをフォローしようとした場合
+func TestPutFollowing_404(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	userMock := tables.CreateUserMock(t, 1)
+
+	res := PutFollowing(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id":   fmt.Sprintf("%d", userMock.ID),
+			"target_id": "999",
+		},
+	})
+	assert.Equal(t, 404, res.StatusCode)
+
+	// DynamoDBに保存されていないことをチェック
+	following, _, err := tables.RelationshipOperator.GetFollowing(context.Background(), userMock.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, following, 0)
+}
+
+// TestFollowing_403 本人以外がフォロー・フォロー解除しようとした場合
+func TestFollowing_403(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	userMock := tables.CreateUserMock(t, 1)
+	targetMock := tables.CreateUserMock(t, 2)
+	otherUserMock := tables.CreateUserMock(t, 3)
+
+	// モックデータを作成
+	err := tables.RelationshipOperator.Follow(context.Background(), domain.NewRelationshipModel(userMock.ID, targetMock.ID))
+	assert.NoError(t, err)
+
+	pathParameters := map[string]string{
+		"user_id":   fmt.Sprintf("%d", userMock.ID),
+		"target_id": fmt.Sprintf("%d", targetMock.ID),
+	}
+
+	// フォロー処理
+	res := PutFollowing(Request{
+		Headers:        mocks.AuthHeaders(t, otherUserMock.ID),
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 403, res.StatusCode)
+
+	// フォロー解除処理
+	res = DeleteFollowing(Request{
+		Headers:        mocks.AuthHeaders(t, otherUserMock.ID),
+		PathParameters: pathParameters,
+	})
+	assert.Equal(t, 403, res.StatusCode)
+
+	// DynamoDBのデータが変更されていないことをチェック
+	following, err := tables.RelationshipOperator.IsFollowing(context.Background(), userMock.ID, targetMock.ID)
+	assert.NoError(t, err)
+	assert.True(t, following)
+}
+
+// TestDeleteFollowing フォロー解除
+func TestDeleteFollowing(t *testing.T) {
+	// テスト用DynamoDBの設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	userMock := tables.CreateUserMock(t, 1)
+	targetMock := tables.CreateUserMock(t, 2)
+
+	// モックデータを作成
+	err := tables.RelationshipOperator.Follow(context.Background(), domain.NewRelationshipModel(userMock.ID, targetMock.ID))
+	assert.NoError(t, err)
+
+	request := Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id":   fmt.Sprintf("%d", userMock.ID),
+			"target_id": fmt.Sprintf("%d", targetMock.ID),
+		},
+	}
+
+	// フォロー解除処理
+	res := DeleteFollowing(request)
+	assert.Equal(t, 200, res.StatusCode)
+
+	// DynamoDBから削除されているかをチェック
+	following, err := tables.RelationshipOperator.IsFollowing(context.Background(), userMock.ID, targetMock.ID)
+	assert.NoError(t, err)
+	assert.False(t, following)
+
+	// フォローしていない場合
+	res = DeleteFollowing(request)
+	assert.Equal(t, 404, res.StatusCode)
+}
+
+// TestGetFollowers_Paging フォロワー一覧取得 ページング
+func TestGetFollowers_Paging(t *testing.T) {
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// フォローされるユーザーと、フォロワーを作成
+	userMock := tables.CreateUserMock(t, 1)
+	var followerMocks []*domain.UserModel
+	for i := 2; i <= 4; i++ {
+		followerMock := tables.CreateUserMock(t, i)
+		err := tables.RelationshipOperator.Follow(context.Background(), domain.NewRelationshipModel(followerMock.ID, userMock.ID))
+		assert.NoError(t, err)
+		followerMocks = append(followerMocks, followerMock)
+	}
+
+	// 1ページ目を取得
+	res := GetFollowers(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+		QueryStringParameters: map[string]string{
+			"limit": "2",
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	actualUsers := body["users"].([]interface{})
+	assert.Len(t, actualUsers, 2)
+	assert.Equal(t, float64(followerMocks[0].ID), actualUsers[0].(map[string]interface{})["id"])
+	assert.Equal(t, float64(followerMocks[1].ID), actualUsers[1].(map[string]interface{})["id"])
+	cursor := body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// フォロー中のユーザー一覧にはカーソルを使えない
+	res = GetFollowing(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+		QueryStringParameters: map[string]string{
+			"cursor": cursor,
+		},
+	})
+	assert.Equal(t, 400, res.StatusCode)
+
+	// 2ページ目を取得
+	res = GetFollowers(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+		QueryStringParameters: map[string]string{
+			"limit":  "2",
+			"cursor": cursor,
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	actualUsers = body["users"].([]interface{})
+	assert.Len(t, actualUsers, 1)
+	assert.Equal(t, float64(followerMocks[2].ID), actualUsers[0].(map[string]interface{})["id"])
+	assert.Nil(t, body["next_cursor"])
+}
@@ -0,0 +1,49 @@
+package controller
+
+import "context"
+
+// Request コントローラーが受け取るリクエスト。
+// API Gateway(REST API・HTTP API)やALBのイベントは、adapter/gatewayでこの形式に変換してから渡す
+type Request struct {
+	// RequestID API GatewayやLambdaが採番したリクエストID。ログの出力に使う
+	RequestID  string
+	HTTPMethod string
+	Path       string
+	// Resource {user_id}のようなパスパラメータを含むパスのテンプレート。分からない場合は空にする
+	Resource              string
+	Headers               map[string]string
+	QueryStringParameters map[string]string
+	PathParameters        map[string]string
+	Body                  string
+
+	ctx context.Context
+}
+
+// Context リクエストのコンテキストを返す。設定されていない場合は context.Background() を返す
+func (r Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// WithContext コンテキストを差し替えたリクエストを返す
+func (r Request) WithContext(ctx context.Context) Request {
+	r.ctx = ctx
+	return r
+}
+
+// Response コントローラーが返すレスポンス
+type Response struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+
+	// err エラーレスポンスの原因。アクセスログに出力する
+	err error
+	// errorID 5xxレスポンスに含めたエラーID。アクセスログに出力する
+	errorID string
+}
+
+// HandlerFunc リクエストを処理するコントローラーの関数
+type HandlerFunc func(request Request) Response
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// RequestIDHeader リクエストIDを返すレスポンスヘッダー
+const RequestIDHeader = "X-Request-Id"
+
+// WithRequestID 全てのレスポンスにリクエストIDを付与する。
+// API GatewayやLambdaからリクエストIDを受け取れなかった場合は新たに採番する
+func WithRequestID(h HandlerFunc) HandlerFunc {
+	return func(request Request) Response {
+		if request.RequestID == "" {
+			request.RequestID = newID()
+		}
+
+		res := h(request)
+
+		if res.Headers == nil {
+			res.Headers = map[string]string{}
+		}
+		res.Headers[RequestIDHeader] = request.RequestID
+		exposeHeader(res.Headers, RequestIDHeader)
+		return res
+	}
+}
+
+// Middleware 全てのコントローラーに共通の処理として、リクエストIDの付与・スパンの作成・アクセスログの出力を追加する
+func Middleware(h HandlerFunc) HandlerFunc {
+	return WithRequestID(WithTracing(WithAccessLog(h)))
+}
+
+// newID UUID(バージョン4)形式のランダムなIDを生成する
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
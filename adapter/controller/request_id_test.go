@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"bytes"
+	"clean-serverless-book-sample-v2/adapter/logging"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+// TestWithRequestID 受け取ったリクエストIDがレスポンスヘッダーに付与されること
+func TestWithRequestID(t *testing.T) {
+	var got string
+	res := WithRequestID(func(request Request) Response {
+		got = request.RequestID
+		return Response{StatusCode: 200}
+	})(Request{RequestID: "req-1"})
+
+	assert.Equal(t, "req-1", got)
+	assert.Equal(t, "req-1", res.Headers[RequestIDHeader])
+	assert.Equal(t, RequestIDHeader, res.Headers["Access-Control-Expose-Headers"])
+}
+
+// TestWithRequestID_Generate リクエストIDがない場合は採番されること
+func TestWithRequestID_Generate(t *testing.T) {
+	var got string
+	handler := WithRequestID(func(request Request) Response {
+		got = request.RequestID
+		return withETag(Response{StatusCode: 200, Headers: commonHeaders()}, 1)
+	})
+
+	res := handler(Request{})
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), got)
+	assert.Equal(t, got, res.Headers[RequestIDHeader])
+	assert.Equal(t, "ETag, "+RequestIDHeader, res.Headers["Access-Control-Expose-Headers"])
+
+	// リクエストごとに異なるIDが採番される
+	first := got
+	handler(Request{})
+	assert.NotEqual(t, first, got)
+}
+
+// TestMiddleware_ErrorID 5xxレスポンスのエラーIDがアクセスログにも出力されること
+func TestMiddleware_ErrorID(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), logging.New(&buf, logging.LevelInfo))
+
+	res := Middleware(func(Request) Response {
+		return Response503(errors.New("throttled"))
+	})(Request{}.WithContext(ctx))
+
+	var body Response5xxBody
+	require.NoError(t, json.Unmarshal([]byte(res.Body), &body))
+	assert.NotEmpty(t, body.Message)
+	assert.NotEmpty(t, body.ErrorID)
+
+	var accessLog struct {
+		RequestID string `json:"request_id"`
+		ErrorID   string `json:"error_id"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &accessLog))
+	assert.Equal(t, body.ErrorID, accessLog.ErrorID)
+	assert.Equal(t, res.Headers[RequestIDHeader], accessLog.RequestID)
+}
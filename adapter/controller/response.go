@@ -3,8 +3,7 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/golang/glog"
+	"strings"
 )
 
 // Response201Body IDを含めた201レスポンス
@@ -24,6 +23,12 @@ type Response401Body struct {
 	Message string `json:"message"`
 }
 
+// Response5xxBody ログと突き合わせるためのエラーIDを含めた5xxレスポンス
+type Response5xxBody struct {
+	Message string `json:"message"`
+	ErrorID string `json:"error_id"`
+}
+
 // commonHeaders 各レスポンスに共通で含むヘッダー
 func commonHeaders() map[string]string {
 	return map[string]string{
@@ -32,14 +37,22 @@ func commonHeaders() map[string]string {
 	}
 }
 
+// exposeHeader ブラウザのJavaScriptから参照できるヘッダーに追加する
+func exposeHeader(headers map[string]string, name string) {
+	if exposed := headers["Access-Control-Expose-Headers"]; exposed != "" {
+		name = exposed + ", " + name
+	}
+	headers["Access-Control-Expose-Headers"] = name
+}
+
 // Response200 JSONを含めた200レスポンス
-func Response200(body interface{}) events.APIGatewayProxyResponse {
+func Response200(body interface{}) Response {
 	b, err := json.Marshal(body)
 	if err != nil {
 		return Response500(err)
 	}
 
-	return events.APIGatewayProxyResponse{
+	return Response{
 		StatusCode: 200,
 		Body:       string(b),
 		Headers:    commonHeaders(),
@@ -47,8 +60,8 @@ func Response200(body interface{}) events.APIGatewayProxyResponse {
 }
 
 // Response200OK okメッセージを含めた200レスポンス
-func Response200OK() events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{
+func Response200OK() Response {
+	return Response{
 		StatusCode: 200,
 		Headers:    commonHeaders(),
 		Body:       `{"message":"OK"}`,
@@ -56,8 +69,8 @@ func Response200OK() events.APIGatewayProxyResponse {
 }
 
 // Response201 IDを含めた201レスポンス
-func Response201(id uint64) events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{
+func Response201(id uint64) Response {
+	return Response{
 		StatusCode: 201,
 		Headers:    commonHeaders(),
 		Body:       fmt.Sprintf(`{"message":"OK","id":%d}`, id),
@@ -65,8 +78,7 @@ func Response201(id uint64) events.APIGatewayProxyResponse {
 }
 
 // Response400 エラーメッセージを含めた400レスポンス
-func Response400(errs map[string]error) events.APIGatewayProxyResponse {
-	glog.Warningf("%+v", errs)
+func Response400(errs map[string]error) Response {
 	res := &Response400Body{
 		Message: "入力値を確認してください。",
 		Errors:  ConvertErrorsToMessage(errs),
@@ -77,28 +89,109 @@ func Response400(errs map[string]error) events.APIGatewayProxyResponse {
 		return Response500(err)
 	}
 
-	return events.APIGatewayProxyResponse{
+	return Response{
 		StatusCode: 400,
 		Headers:    commonHeaders(),
 		Body:       string(b),
+		err:        fmt.Errorf("validation failed: %v", errs),
+	}
+}
+
+// Response401 401レスポンス
+func Response401() Response {
+	b, err := json.Marshal(&Response401Body{
+		Message: "認証に失敗しました。",
+	})
+	if err != nil {
+		return Response500(err)
+	}
+
+	headers := commonHeaders()
+	headers["WWW-Authenticate"] = "Bearer"
+
+	return Response{
+		StatusCode: 401,
+		Headers:    headers,
+		Body:       string(b),
+	}
+}
+
+// Response403 403レスポンス
+func Response403() Response {
+	return Response{
+		StatusCode: 403,
+		Headers:    commonHeaders(),
+		Body:       `{"message":"この操作を行う権限がありません。"}`,
 	}
 }
 
 // Response404 404レスポンス
-func Response404() events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{
+func Response404() Response {
+	return Response{
 		StatusCode: 404,
 		Headers:    commonHeaders(),
 		Body:       `{"message":"結果が見つかりません。"}`,
 	}
 }
 
-// Response500 500レスポンス
-func Response500(err error) events.APIGatewayProxyResponse {
-	glog.Errorf("%+v\n", err)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 500,
+// Response405 405レスポンス。allowedには対応しているメソッドを指定する
+func Response405(allowed []string) Response {
+	headers := commonHeaders()
+	headers["Allow"] = strings.Join(allowed, ", ")
+
+	return Response{
+		StatusCode: 405,
+		Headers:    headers,
+		Body:       `{"message":"対応していないメソッドです。"}`,
+	}
+}
+
+// Response409 409レスポンス
+func Response409(err error) Response {
+	return Response{
+		StatusCode: 409,
+		Headers:    commonHeaders(),
+		Body:       `{"message":"他の操作と競合したため、処理を実行できませんでした。"}`,
+		err:        err,
+	}
+}
+
+// Response412 412レスポンス
+func Response412() Response {
+	return Response{
+		StatusCode: 412,
 		Headers:    commonHeaders(),
-		Body:       `{"message":"サーバエラーが発生しました。"}`,
+		Body:       `{"message":"データが更新されています。最新のデータを取得してから再度実行してください。"}`,
+	}
+}
+
+// Response500 500レスポンス
+func Response500(err error) Response {
+	return responseServerError(500, commonHeaders(), "サーバエラーが発生しました。", err)
+}
+
+// Response503 503レスポンス。時間をおいて再実行すれば成功する可能性がある場合に返す
+func Response503(err error) Response {
+	headers := commonHeaders()
+	headers["Retry-After"] = "1"
+
+	return responseServerError(503, headers, "混み合っているため、処理を実行できませんでした。時間をおいて再度お試しください。", err)
+}
+
+// responseServerError エラーIDを含めた5xxレスポンス。エラーIDは原因となったエラーと一緒にアクセスログに出力する
+func responseServerError(statusCode int, headers map[string]string, message string, err error) Response {
+	errorID := newID()
+	// 文字列だけの構造体のため、変換に失敗することはない
+	b, _ := json.Marshal(&Response5xxBody{
+		Message: message,
+		ErrorID: errorID,
+	})
+
+	return Response{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       string(b),
+		err:        err,
+		errorID:    errorID,
 	}
 }
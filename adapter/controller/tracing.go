@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"clean-serverless-book-sample-v2/adapter/tracing"
+	"clean-serverless-book-sample-v2/registry"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+// WithTracing コントローラーの呼び出しごとにスパンを作成する。
+// スパン名はメソッドとルートを組み合わせたもので、ルートが分からない場合はメソッドのみにする。
+// ステータスコードが5xxの場合は、原因となったエラーとともにスパンをエラーにする
+func WithTracing(h HandlerFunc) HandlerFunc {
+	return func(request Request) Response {
+		name := strings.TrimSpace(fmt.Sprintf("%s %s", request.HTTPMethod, request.Resource))
+		ctx, span := registry.GetFactory().BuildTracer().Start(request.Context(), name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", request.HTTPMethod),
+				attribute.String("http.route", request.Resource),
+				attribute.String("url.path", request.Path),
+				attribute.String("request_id", request.RequestID),
+			))
+
+		res := h(request.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+		if res.errorID != "" {
+			span.SetAttributes(attribute.String("error_id", res.errorID))
+		}
+		if res.StatusCode >= 500 {
+			err := res.err
+			if err == nil {
+				err = fmt.Errorf("status %d", res.StatusCode)
+			}
+			tracing.End(span, err)
+		} else {
+			span.End()
+		}
+
+		return res
+	}
+}
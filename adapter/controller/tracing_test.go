@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"bytes"
+	"clean-serverless-book-sample-v2/adapter/logging"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"testing"
+)
+
+var (
+	spanExporter     *tracetest.InMemoryExporter
+	spanExporterOnce sync.Once
+)
+
+// recordSpans 作成されたスパンをメモリ上に記録する。
+// グローバルなTracerProviderは一度しか差し替えられないため、テスト間で使い回して記録済みのスパンだけを消す
+func recordSpans() *tracetest.InMemoryExporter {
+	spanExporterOnce.Do(func() {
+		spanExporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+	})
+	spanExporter.Reset()
+	return spanExporter
+}
+
+// TestWithTracing コントローラーの呼び出しごとにスパンが作成され、5xxの場合はエラーになること
+func TestWithTracing(t *testing.T) {
+	exporter := recordSpans()
+
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), logging.New(&buf, logging.LevelInfo))
+
+	var inController trace.SpanContext
+	handler := Middleware(func(request Request) Response {
+		inController = trace.SpanContextFromContext(request.Context())
+		return Response500(errors.New("failed"))
+	})
+
+	res := handler(Request{
+		RequestID:  "req-1",
+		HTTPMethod: "GET",
+		Path:       "/v1/users/1",
+		Resource:   "/v1/users/{user_id}",
+	}.WithContext(ctx))
+	assert.Equal(t, 500, res.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /v1/users/{user_id}", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assert.Contains(t, span.Attributes, attribute.String("http.route", "/v1/users/{user_id}"))
+	assert.Contains(t, span.Attributes, attribute.Int("http.response.status_code", 500))
+	assert.Contains(t, span.Attributes, attribute.String("request_id", "req-1"))
+
+	// コントローラーにスパンが引き継がれる
+	assert.Equal(t, span.SpanContext.SpanID(), inController.SpanID())
+
+	// アクセスログからトレースを辿れる
+	var accessLog map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &accessLog))
+	assert.Equal(t, span.SpanContext.TraceID().String(), accessLog["trace_id"])
+}
+
+// TestWithTracing_ClientError 4xxの場合はスパンをエラーにしないこと
+func TestWithTracing_ClientError(t *testing.T) {
+	exporter := recordSpans()
+
+	handler := WithTracing(func(request Request) Response {
+		return Response404()
+	})
+	res := handler(Request{HTTPMethod: "GET", Path: "/v1/users/1", Resource: "/v1/users/{user_id}"})
+	assert.Equal(t, 404, res.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", 404))
+}
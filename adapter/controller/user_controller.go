@@ -2,13 +2,11 @@ package controller
 
 import (
 	"clean-serverless-book-sample-v2/domain"
-	"clean-serverless-book-sample-v2/interactor"
 	"clean-serverless-book-sample-v2/registry"
 	"clean-serverless-book-sample-v2/usecase"
 	"clean-serverless-book-sample-v2/utils"
 	"encoding/json"
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/pkg/errors"
+	"time"
 )
 
 // PostSettingValidator バリデーション設定
@@ -35,18 +33,30 @@ type RequestPutUser struct {
 
 // UserResponse レスポンス用のJSON形式を表した構造体
 type UserResponse struct {
-	ID    uint64 `json:"id"`
-	Name  string `json:"user_name"`
-	Email string `json:"email"`
+	ID              uint64     `json:"id"`
+	Name            string     `json:"user_name"`
+	Email           string     `json:"email"`
+	MicropostsCount int        `json:"microposts_count"`
+	FirstPostedAt   *time.Time `json:"first_posted_at"`
+	LastPostedAt    *time.Time `json:"last_posted_at"`
 }
 
 // UserResponse Userリストレスポンス用のJSON形式を表した構造体
 type UsersResponse struct {
-	Users []*UserResponse `json:"users"`
+	Users      []*UserResponse `json:"users"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// DeleteUserResponse ユーザー削除レスポンス用のJSON形式を表した構造体
+type DeleteUserResponse struct {
+	Message                string `json:"message"`
+	DeletedMicropostsCount int    `json:"deleted_microposts_count"`
 }
 
 // PostUsers 新規作成
-func PostUsers(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+// ユーザー登録の入口となるため認証は行わない。
+// 作成されたユーザーのIDを、以降のリクエストで使うトークンのsubjectとして発行する想定
+func PostUsers(request Request) Response {
 	// バリデーション処理
 	validator := PostSettingValidator()
 	validErr := validator.ValidateBody(request.Body)
@@ -63,17 +73,12 @@ func PostUsers(request events.APIGatewayProxyRequest) events.APIGatewayProxyResp
 
 	// 新規作成処理
 	creator := registry.GetFactory().BuildCreateUser()
-	res, err := creator.Execute(&usecase.CreateUserRequest{
+	res, err := creator.Execute(request.Context(), &usecase.CreateUserRequest{
 		Name:  req.Name,
 		Email: req.Email,
 	})
 	if err != nil {
-		if err.Error() == interactor.ErrUniqEmail.Error() {
-			return Response400(map[string]error{
-				"email": errors.New("すでに登録されているメールアドレスです。"),
-			})
-		}
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// 201レスポンス
@@ -81,7 +86,13 @@ func PostUsers(request events.APIGatewayProxyRequest) events.APIGatewayProxyResp
 }
 
 // PutUser 更新
-func PutUser(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func PutUser(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// バリデーション処理
 	validator := PostSettingValidator()
 	validErr := validator.ValidateBody(request.Body)
@@ -102,20 +113,23 @@ func PutUser(request events.APIGatewayProxyRequest) events.APIGatewayProxyRespon
 		return Response500(err)
 	}
 
+	// If-Matchヘッダーから操作対象のバージョンを取得する
+	version, preErr := IfMatchVersion(request.Headers, currentUserVersion(request, userID))
+	if preErr != nil {
+		return *preErr
+	}
+
 	// 更新処理
 	updater := registry.GetFactory().BuildUpdateUser()
-	_, err = updater.Execute(&usecase.UpdateUserRequest{
-		ID:    userID,
-		Name:  req.Name,
-		Email: req.Email,
+	_, err = updater.Execute(request.Context(), &usecase.UpdateUserRequest{
+		ID:        userID,
+		Name:      req.Name,
+		Email:     req.Email,
+		Version:   version,
+		Principal: principal,
 	})
 	if err != nil {
-		if err.Error() == interactor.ErrUniqEmail.Error() {
-			return Response400(map[string]error{
-				"email": errors.New("すでに登録されているメールアドレスです。"),
-			})
-		}
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// 200レスポンス
@@ -123,32 +137,50 @@ func PutUser(request events.APIGatewayProxyRequest) events.APIGatewayProxyRespon
 }
 
 // GetUsers 一覧取得処理
-func GetUsers(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func GetUsers(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
+	// クエリパラメータからページング条件を取得
+	paging, validErr := ParsePaging(request)
+	if validErr != nil {
+		return Response400(validErr)
+	}
+
 	// 一覧取得処理
 	getter := registry.GetFactory().BuildGetUserList()
-	res, err := getter.Execute(&usecase.GetUserListRequest{})
+	res, err := getter.Execute(request.Context(), &usecase.GetUserListRequest{
+		Limit:  paging.Limit,
+		Cursor: paging.Cursor,
+	})
 	if err != nil {
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// ドメインモデルからレスポンス用の構造体に詰め替える
 	var resUsers = make([]*UserResponse, res.UserCount())
 	for i, u := range res.Users {
-		resUsers[i] = &UserResponse{
-			ID:    u.ID,
-			Name:  u.Name,
-			Email: u.Email,
-		}
+		resUsers[i] = newUserResponse(u)
 	}
 
 	// レスポンス処理
 	return Response200(&UsersResponse{
-		Users: resUsers,
+		Users:      resUsers,
+		NextCursor: res.NextCursor,
 	})
 }
 
 // GetUser IDから取得
-func GetUser(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func GetUser(request Request) Response {
+	// 認証処理
+	_, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// パスパラメータからユーザーIDを取得する
 	userID, err := utils.ParseUint(request.PathParameters["user_id"])
 	if err != nil {
@@ -157,39 +189,75 @@ func GetUser(request events.APIGatewayProxyRequest) events.APIGatewayProxyRespon
 
 	// ユーザー取得処理
 	getter := registry.GetFactory().BuildGetUserByID()
-	res, err := getter.Execute(&usecase.GetUserByIDRequest{UserID: userID})
+	res, err := getter.Execute(request.Context(), &usecase.GetUserByIDRequest{UserID: userID})
 	if err != nil {
-		if err.Error() == domain.ErrNotFound.Error() {
-			return Response404()
-		}
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// ドメインモデルからレスポンス用構造体に詰め替えて、レスポンス
-	return Response200(&UserResponse{
-		ID:    res.User.ID,
-		Name:  res.User.Name,
-		Email: res.User.Email,
-	})
+	return withETag(Response200(newUserResponse(res.User)), res.User.Version)
 }
 
 // DeleteUser 削除処理
-func DeleteUser(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+func DeleteUser(request Request) Response {
+	// 認証処理
+	principal, authErr := Authenticate(request)
+	if authErr != nil {
+		return *authErr
+	}
+
 	// パスパラメータからユーザーIDを取得する
 	userID, err := utils.ParseUint(request.PathParameters["user_id"])
 	if err != nil {
 		return Response500(err)
 	}
 
+	// If-Matchヘッダーから操作対象のバージョンを取得する
+	version, preErr := IfMatchVersion(request.Headers, currentUserVersion(request, userID))
+	if preErr != nil {
+		return *preErr
+	}
+
 	// 削除処理
 	deleter := registry.GetFactory().BuildUserDeleter()
-	_, err = deleter.Execute(&usecase.DeleteUserRequest{
-		UserID: userID,
+	res, err := deleter.Execute(request.Context(), &usecase.DeleteUserRequest{
+		UserID:    userID,
+		Version:   version,
+		Principal: principal,
 	})
 	if err != nil {
-		return Response500(err)
+		return ResponseError(err)
 	}
 
 	// レスポンス
-	return Response200OK()
+	return Response200(&DeleteUserResponse{
+		Message:                "OK",
+		DeletedMicropostsCount: res.DeletedMicropostCount,
+	})
+}
+
+// newUserResponse ドメインモデルからレスポンス用の構造体に詰め替える
+func newUserResponse(u *domain.UserModel) *UserResponse {
+	return &UserResponse{
+		ID:              u.ID,
+		Name:            u.Name,
+		Email:           u.Email,
+		MicropostsCount: u.MicropostsCount,
+		FirstPostedAt:   u.FirstPostedAt,
+		LastPostedAt:    u.LastPostedAt,
+	}
+}
+
+// currentUserVersion If-Matchに複数のETagが指定された場合に、ユーザーの現在のバージョンを取得する
+func currentUserVersion(request Request, userID uint64) func() (int, error) {
+	return func() (int, error) {
+		getter := registry.GetFactory().BuildGetUserByID()
+		res, err := getter.Execute(request.Context(), &usecase.GetUserByIDRequest{
+			UserID: userID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return res.User.Version, nil
+	}
 }
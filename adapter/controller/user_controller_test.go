@@ -3,11 +3,12 @@ package controller
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/mocks"
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 // TestPostUsers_201 新規作成 成功時
@@ -25,7 +26,7 @@ func TestPostUsers_201(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 新規作成処理
-	res := PostUsers(events.APIGatewayProxyRequest{
+	res := PostUsers(Request{
 		Body: string(bodyStr),
 	})
 
@@ -42,12 +43,32 @@ func TestPostUsers_201(t *testing.T) {
 	assert.Equal(t, uint64(1), id)
 
 	// DynamoDBに保存されたデータをチェック
-	user, err := tables.UserOperator.GetUserByID(id)
+	user, err := tables.UserOperator.GetUserByID(context.Background(), id)
 	assert.NoError(t, err)
 	assert.Equal(t, body["user_name"].(string), user.Name)
 	assert.Equal(t, body["email"].(string), user.Email)
 }
 
+// TestPostUsers_201_InvalidToken 新規作成 ユーザー登録は認証を行わないため、不正なトークンでも作成できる
+func TestPostUsers_201_InvalidToken(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	bodyStr, err := json.Marshal(map[string]interface{}{
+		"user_name": "テスト名前",
+		"email":     "test@example.com",
+	})
+	assert.NoError(t, err)
+
+	res := PostUsers(Request{
+		Headers: map[string]string{"Authorization": "Bearer invalid"},
+		Body:    string(bodyStr),
+	})
+
+	assert.Equal(t, 201, res.StatusCode)
+}
+
 // TestPostUsers_400 新規登録 バリデーションエラー時
 func TestPostUsers_400(t *testing.T) {
 	// テスト用DynamoDBを設定
@@ -55,7 +76,7 @@ func TestPostUsers_400(t *testing.T) {
 	defer tables.Cleanup()
 
 	// 重複エラーテスト用のモックデータを作成
-	userMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
@@ -106,7 +127,7 @@ func TestPostUsers_400(t *testing.T) {
 		bodyStr, err := json.Marshal(body)
 		assert.NoError(t, err)
 
-		res := PostUsers(events.APIGatewayProxyRequest{
+		res := PostUsers(Request{
 			Body: string(bodyStr),
 		})
 
@@ -128,7 +149,7 @@ func TestPutUser_200(t *testing.T) {
 	defer tables.Cleanup()
 
 	// 更新用モックデータを作成
-	userMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
@@ -144,8 +165,9 @@ func TestPutUser_200(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 更新処理
-	res := PutUser(events.APIGatewayProxyRequest{
-		Body: string(bodyStr),
+	res := PutUser(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		Body:    string(bodyStr),
 		PathParameters: map[string]string{
 			"user_id": fmt.Sprintf("%d", userMock.ID),
 		},
@@ -160,7 +182,7 @@ func TestPutUser_200(t *testing.T) {
 	assert.NoError(t, err)
 
 	// DynamoDBのデータが更新されているかをチェック
-	user, err := tables.UserOperator.GetUserByID(userMock.ID)
+	user, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, body["user_name"].(string), user.Name)
 	assert.Equal(t, body["email"].(string), user.Email)
@@ -173,7 +195,7 @@ func TestPutUser_200_dup(t *testing.T) {
 	defer tables.Cleanup()
 
 	// モックデータを作成
-	userMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
@@ -189,8 +211,9 @@ func TestPutUser_200_dup(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 更新処理
-	res := PutUser(events.APIGatewayProxyRequest{
-		Body: string(bodyStr),
+	res := PutUser(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		Body:    string(bodyStr),
 		PathParameters: map[string]string{
 			"user_id": fmt.Sprintf("%d", userMock.ID),
 		},
@@ -205,7 +228,7 @@ func TestPutUser_200_dup(t *testing.T) {
 	assert.NoError(t, err)
 
 	// DynamoDBのデータをチェック
-	user, err := tables.UserOperator.GetUserByID(userMock.ID)
+	user, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, body["user_name"].(string), user.Name)
 	assert.Equal(t, body["email"].(string), user.Email)
@@ -218,7 +241,7 @@ func TestPutUser_400(t *testing.T) {
 	defer tables.Cleanup()
 
 	// 更新用モックデータを作成
-	userMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
@@ -226,7 +249,7 @@ func TestPutUser_400(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 重複エラー用モックデータを作成
-	dupUserMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	dupUserMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    2,
 		Name:  "Name_2",
 		Email: "test1@cample.com",
@@ -277,8 +300,9 @@ func TestPutUser_400(t *testing.T) {
 		bodyStr, err := json.Marshal(body)
 		assert.NoError(t, err)
 
-		res := PutUser(events.APIGatewayProxyRequest{
-			Body: string(bodyStr),
+		res := PutUser(Request{
+			Headers: mocks.AuthHeaders(t, userMock.ID),
+			Body:    string(bodyStr),
 			PathParameters: map[string]string{
 				"user_id": fmt.Sprintf("%d", userMock.ID),
 			},
@@ -302,7 +326,7 @@ func TestGetUser(t *testing.T) {
 	defer tables.Cleanup()
 
 	// 取得用モックデータを作成
-	userMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
@@ -310,7 +334,8 @@ func TestGetUser(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 取得処理
-	res := GetUser(events.APIGatewayProxyRequest{
+	res := GetUser(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
 		PathParameters: map[string]string{
 			"user_id": fmt.Sprintf("%d", userMock.ID),
 		},
@@ -326,6 +351,60 @@ func TestGetUser(t *testing.T) {
 	assert.Equal(t, float64(userMock.ID), body["id"])
 	assert.Equal(t, userMock.Name, body["user_name"])
 	assert.Equal(t, userMock.Email, body["email"])
+
+	// 投稿していないユーザーは件数が0で、投稿日時はnullになる
+	assert.Equal(t, float64(0), body["microposts_count"])
+	assert.Contains(t, body, "first_posted_at")
+	assert.Nil(t, body["first_posted_at"])
+	assert.Contains(t, body, "last_posted_at")
+	assert.Nil(t, body["last_posted_at"])
+
+	// バージョンがETagとして返されているかをチェック
+	assert.Equal(t, `"1"`, res.Headers["ETag"])
+}
+
+// TestGetUser_MicropostStats マイクロポストの件数と投稿日時の取得
+func TestGetUser_MicropostStats(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 取得用モックデータを作成
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
+		Name:  "Name_1",
+		Email: "test1@example.com",
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+			Content: fmt.Sprintf("Content_%d", i),
+			UserID:  userMock.ID,
+		})
+		assert.NoError(t, err)
+	}
+
+	// 取得処理
+	res := GetUser(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	// 取得したデータをチェック
+	var body struct {
+		MicropostsCount int        `json:"microposts_count"`
+		FirstPostedAt   *time.Time `json:"first_posted_at"`
+		LastPostedAt    *time.Time `json:"last_posted_at"`
+	}
+	err = json.Unmarshal([]byte(res.Body), &body)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, body.MicropostsCount)
+	if assert.NotNil(t, body.FirstPostedAt) && assert.NotNil(t, body.LastPostedAt) {
+		assert.False(t, body.LastPostedAt.Before(*body.FirstPostedAt))
+	}
 }
 
 // TestGetUsers 一覧取得
@@ -335,21 +414,24 @@ func TestGetUsers(t *testing.T) {
 	defer tables.Cleanup()
 
 	// 取得用モックデータを作成
-	userMock1, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock1, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
 	})
 	assert.NoError(t, err)
 
-	userMock2, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock2, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    2,
 		Name:  "Name_2",
 		Email: "test2@example.com",
 	})
+	assert.NoError(t, err)
 
 	// 一覧取得処理
-	res := GetUsers(events.APIGatewayProxyRequest{})
+	res := GetUsers(Request{
+		Headers: mocks.AuthHeaders(t, 1),
+	})
 
 	// ステータスコードをチェック
 	assert.Equal(t, 200, res.StatusCode)
@@ -382,15 +464,39 @@ func TestDeleteUser(t *testing.T) {
 	defer tables.Cleanup()
 
 	// 削除用モックデータを作成
-	userMock, err := tables.UserOperator.CreateUser(&domain.UserModel{
+	userMock, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
 		ID:    1,
 		Name:  "Name_1",
 		Email: "test1@example.com",
 	})
 	assert.NoError(t, err)
 
+	// 削除対象ユーザーのマイクロポストを作成
+	for i := 1; i <= 30; i++ {
+		_, err = tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+			Content: fmt.Sprintf("Content_%d", i),
+			UserID:  userMock.ID,
+		})
+		assert.NoError(t, err)
+	}
+
+	// このマイクロポストはユーザーが異なるので削除されない想定
+	otherUserMock := tables.CreateUserMock(t, 2)
+	_, err = tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+		Content: "Content_other",
+		UserID:  otherUserMock.ID,
+	})
+	assert.NoError(t, err)
+
+	// 削除対象ユーザーのフォロー関係を作成
+	err = tables.RelationshipOperator.Follow(context.Background(), domain.NewRelationshipModel(userMock.ID, otherUserMock.ID))
+	assert.NoError(t, err)
+	err = tables.RelationshipOperator.Follow(context.Background(), domain.NewRelationshipModel(otherUserMock.ID, userMock.ID))
+	assert.NoError(t, err)
+
 	// 削除処理
-	res := DeleteUser(events.APIGatewayProxyRequest{
+	res := DeleteUser(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
 		PathParameters: map[string]string{
 			"user_id": fmt.Sprintf("%d", userMock.ID),
 		},
@@ -399,8 +505,423 @@ func TestDeleteUser(t *testing.T) {
 	// ステータスコードをチェック
 	assert.Equal(t, 200, res.StatusCode)
 
+	// 削除したマイクロポストの件数をチェック
+	body := mocks.UnmarshalJSON(t, res.Body)
+	assert.Equal(t, float64(30), body["deleted_microposts_count"])
+
 	// DynamoDBからデータが削除されているかをチェック
-	users, err := tables.UserOperator.GetUsers()
+	_, err = tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	if assert.Error(t, err) {
+		assert.Equal(t, domain.ErrNotFound.Error(), err.Error())
+	}
+
+	microposts, _, err := tables.MicropostOperator.GetMicropostsByUserID(context.Background(), userMock.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, microposts, 0)
+
+	microposts, _, err = tables.MicropostOperator.GetMicropostsByUserID(context.Background(), otherUserMock.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, microposts, 1)
+
+	following, _, err := tables.RelationshipOperator.GetFollowing(context.Background(), otherUserMock.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, following, 0)
+
+	followers, _, err := tables.RelationshipOperator.GetFollowers(context.Background(), otherUserMock.ID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, followers, 0)
+}
+
+// TestGetUsers_Paging 一覧取得 ページング
+func TestGetUsers_Paging(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 取得用モックデータを作成
+	for i := 1; i <= 3; i++ {
+		_, err := tables.UserOperator.CreateUser(context.Background(), &domain.UserModel{
+			Name:  fmt.Sprintf("Name_%d", i),
+			Email: fmt.Sprintf("test%d@example.com", i),
+		})
+		assert.NoError(t, err)
+	}
+
+	// 1ページ目を取得
+	res := GetUsers(Request{
+		Headers: mocks.AuthHeaders(t, 1),
+		QueryStringParameters: map[string]string{
+			"limit": "2",
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body := mocks.UnmarshalJSON(t, res.Body)
+	assert.Len(t, body["users"].([]interface{}), 2)
+	cursor := body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// 2ページ目を取得
+	res = GetUsers(Request{
+		Headers: mocks.AuthHeaders(t, 1),
+		QueryStringParameters: map[string]string{
+			"limit":  "2",
+			"cursor": cursor,
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	body = mocks.UnmarshalJSON(t, res.Body)
+	assert.Len(t, body["users"].([]interface{}), 1)
+	assert.Nil(t, body["next_cursor"])
+}
+
+// TestGetUsers_400 一覧取得 ページング条件が不正な場合
+func TestGetUsers_400(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	cases := []struct {
+		Request  map[string]string
+		Expected map[string]interface{}
+	}{
+		// 件数が数値でない場合
+		{
+			Request: map[string]string{
+				"limit": "abc",
+			},
+			Expected: map[string]interface{}{
+				"limit": "取得件数は不正な値です。",
+			},
+		},
+		// 件数が上限を超えている場合
+		{
+			Request: map[string]string{
+				"limit": "101",
+			},
+			Expected: map[string]interface{}{
+				"limit": "取得件数は1から100の範囲で指定してください。",
+			},
+		},
+		// カーソルが改ざんされている場合
+		{
+			Request: map[string]string{
+				"cursor": "eyJQSyI6IlVzZXJSZXNvdXJjZS0wMDAwMDAwMDAwMSJ9.invalid",
+			},
+			Expected: map[string]interface{}{
+				"cursor": "カーソルは不正な値です。",
+			},
+		},
+	}
+
+	for i, c := range cases {
+		msg := fmt.Sprintf("Case:%d", i+1)
+
+		res := GetUsers(Request{
+			Headers:               mocks.AuthHeaders(t, 1),
+			QueryStringParameters: c.Request,
+		})
+
+		body := mocks.UnmarshalJSON(t, res.Body)
+
+		assert.Equal(t, 400, res.StatusCode, msg)
+		assert.Equal(t, c.Expected, body["errors"], msg)
+	}
+}
+
+// TestGetUsers_401 一覧取得 認証エラー時
+func TestGetUsers_401(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	cases := []map[string]string{
+		// Authorizationヘッダーがない場合
+		{},
+		// Bearerトークンではない場合
+		{"Authorization": "Basic dXNlcjpwYXNz"},
+		// 署名鍵が異なる場合
+		{"Authorization": "Bearer " + mocks.SignJWT(t, "wrong-secret", map[string]interface{}{
+			"sub": "1",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})},
+		// 有効期限切れの場合
+		{"Authorization": "Bearer " + mocks.SignJWT(t, mocks.TestJWTSecret, map[string]interface{}{
+			"sub": "1",
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		})},
+	}
+
+	for i, headers := range cases {
+		msg := fmt.Sprintf("Case:%d", i+1)
+
+		res := GetUsers(Request{
+			Headers: headers,
+		})
+
+		assert.Equal(t, 401, res.StatusCode, msg)
+		assert.Equal(t, "Bearer", res.Headers["WWW-Authenticate"], msg)
+	}
+}
+
+// TestPutUser_403 更新 本人以外が更新しようとした場合
+func TestPutUser_403(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 更新用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+	otherUserMock := tables.CreateUserMock(t, 2)
+
+	// 更新パラメータ
+	body := map[string]interface{}{
+		"user_name": "テスト名前更新",
+		"email":     "test_update@example.com",
+	}
+
+	// 別のユーザーとして更新処理
+	res := PutUser(Request{
+		Headers: mocks.AuthHeaders(t, otherUserMock.ID),
+		Body:    mocks.MarshalJSON(t, body),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+
+	// ステータスコードをチェック
+	assert.Equal(t, 403, res.StatusCode)
+
+	// DynamoDBのデータが更新されていないことをチェック
+	user, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, userMock.Name, user.Name)
+	assert.Equal(t, userMock.Email, user.Email)
+}
+
+// TestDeleteUser_403 削除 本人以外が削除しようとした場合
+func TestDeleteUser_403(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 削除用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+	otherUserMock := tables.CreateUserMock(t, 2)
+
+	// 別のユーザーとして削除処理
+	res := DeleteUser(Request{
+		Headers: mocks.AuthHeaders(t, otherUserMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+
+	// ステータスコードをチェック
+	assert.Equal(t, 403, res.StatusCode)
+
+	// DynamoDBのデータが削除されていないことをチェック
+	_, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+}
+
+// TestDeleteUser_Admin 削除 管理者は他のユーザーを削除できる
+func TestDeleteUser_Admin(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 削除用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+	adminUserMock := tables.CreateUserMock(t, 2)
+
+	// 管理者として削除処理
+	res := DeleteUser(Request{
+		Headers: mocks.AuthHeaders(t, adminUserMock.ID, domain.RoleAdmin),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+
+	// ステータスコードをチェック
+	assert.Equal(t, 200, res.StatusCode)
+
+	// DynamoDBからデータが削除されているかをチェック
+	_, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	if assert.Error(t, err) {
+		assert.Equal(t, domain.ErrNotFound.Error(), err.Error())
+	}
+}
+
+// TestPutUser_412 更新 If-Matchのバージョンが一致しない場合
+func TestPutUser_412(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 更新用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+
+	// 取得処理でETagを取得
+	res := GetUser(Request{
+		Headers: mocks.AuthHeaders(t, userMock.ID),
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+	assert.Equal(t, 200, res.StatusCode)
+	etag := res.Headers["ETag"]
+
+	put := func(ifMatch, name string) Response {
+		headers := mocks.AuthHeaders(t, userMock.ID)
+		headers["If-Match"] = ifMatch
+		return PutUser(Request{
+			Headers: headers,
+			Body: mocks.MarshalJSON(t, map[string]interface{}{
+				"user_name": name,
+				"email":     userMock.Email,
+			}),
+			PathParameters: map[string]string{
+				"user_id": fmt.Sprintf("%d", userMock.ID),
+			},
+		})
+	}
+
+	// 取得したETagを指定した更新は成功する
+	res = put(etag, "テスト名前更新1")
+	assert.Equal(t, 200, res.StatusCode)
+
+	// 同じETagを指定した2回目の更新は、バージョンが進んでいるため失敗する
+	res = put(etag, "テスト名前更新2")
+	assert.Equal(t, 412, res.StatusCode)
+
+	// このAPIが発行していないETagや弱いETagは一致しないものとして扱う
+	res = put(`"invalid"`, "テスト名前更新3")
+	assert.Equal(t, 412, res.StatusCode)
+	res = put("W/"+ETag(2), "テスト名前更新4")
+	assert.Equal(t, 412, res.StatusCode)
+	res = put(fmt.Sprintf("%s, W/%s", etag, ETag(2)), "テスト名前更新5")
+	assert.Equal(t, 412, res.StatusCode)
+
+	// DynamoDBには1回目の更新だけが反映されていることをチェック
+	user, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "テスト名前更新1", user.Name)
+	assert.Equal(t, 2, user.Version)
+
+	// 複数のETagのうち、現在のバージョンと一致するものがあれば更新できる
+	res = put(fmt.Sprintf(`%s, "invalid", %s`, etag, ETag(2)), "テスト名前更新6")
+	assert.Equal(t, 200, res.StatusCode)
+	user, err = tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "テスト名前更新6", user.Name)
+	assert.Equal(t, 3, user.Version)
+}
+
+// TestPutUser_400_IfMatch 更新 If-Matchヘッダーの形式が不正な場合
+func TestPutUser_400_IfMatch(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 更新用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+
+	for _, ifMatch := range []string{"invalid", `"1`, "W/1", `"1", *`, `"1 2"`, ","} {
+		headers := mocks.AuthHeaders(t, userMock.ID)
+		headers["If-Match"] = ifMatch
+		res := PutUser(Request{
+			Headers: headers,
+			Body: mocks.MarshalJSON(t, map[string]interface{}{
+				"user_name": "テスト名前更新",
+				"email":     userMock.Email,
+			}),
+			PathParameters: map[string]string{
+				"user_id": fmt.Sprintf("%d", userMock.ID),
+			},
+		})
+
+		var resBody map[string]interface{}
+		err := json.Unmarshal([]byte(res.Body), &resBody)
+		assert.NoError(t, err)
+
+		assert.Equal(t, 400, res.StatusCode, ifMatch)
+		assert.Equal(t, map[string]interface{}{
+			"If-Match": "If-Matchヘッダーは不正な値です。",
+		}, resBody["errors"], ifMatch)
+	}
+
+	// ユーザーが更新されていないことをチェック
+	user, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, userMock.Name, user.Name)
+	assert.Equal(t, userMock.Version, user.Version)
+}
+
+// TestPutUser_IfMatchMissing 更新 If-Matchヘッダーがない場合はバージョンを確認せずに更新する
+func TestPutUser_IfMatchMissing(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 更新用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+
+	for i, ifMatch := range []string{"", "*"} {
+		headers := mocks.AuthHeaders(t, userMock.ID)
+		if ifMatch != "" {
+			headers["If-Match"] = ifMatch
+		}
+		res := PutUser(Request{
+			Headers: headers,
+			Body: mocks.MarshalJSON(t, map[string]interface{}{
+				"user_name": fmt.Sprintf("テスト名前更新%d", i+1),
+				"email":     userMock.Email,
+			}),
+			PathParameters: map[string]string{
+				"user_id": fmt.Sprintf("%d", userMock.ID),
+			},
+		})
+		assert.Equal(t, 200, res.StatusCode, ifMatch)
+	}
+
+	user, err := tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "テスト名前更新2", user.Name)
+}
+
+// TestDeleteUser_412 削除 If-Matchのバージョンが一致しない場合
+func TestDeleteUser_412(t *testing.T) {
+	// テスト用DynamoDBを設定
+	tables := mocks.SetupDB(t)
+	defer tables.Cleanup()
+
+	// 削除用モックデータを作成
+	userMock := tables.CreateUserMock(t, 1)
+	_, err := tables.MicropostOperator.CreateMicropost(context.Background(), &domain.MicropostModel{
+		Content: "Content_1",
+		UserID:  userMock.ID,
+	})
+	assert.NoError(t, err)
+
+	// 古いバージョンを指定して削除処理
+	headers := mocks.AuthHeaders(t, userMock.ID)
+	headers["If-Match"] = ETag(userMock.Version + 1)
+	res := DeleteUser(Request{
+		Headers: headers,
+		PathParameters: map[string]string{
+			"user_id": fmt.Sprintf("%d", userMock.ID),
+		},
+	})
+
+	// ステータスコードをチェック
+	assert.Equal(t, 412, res.StatusCode)
+
+	// ユーザーもマイクロポストも削除されていないことをチェック
+	_, err = tables.UserOperator.GetUserByID(context.Background(), userMock.ID)
+	assert.NoError(t, err)
+	microposts, _, err := tables.MicropostOperator.GetMicropostsByUserID(context.Background(), userMock.ID, nil)
 	assert.NoError(t, err)
-	assert.Len(t, users, 0)
+	assert.Len(t, microposts, 1)
 }
@@ -1,10 +1,11 @@
 package controller
 
 import (
+	"clean-serverless-book-sample-v2/registry"
 	"encoding/json"
 	"errors"
-	"github.com/golang/glog"
 	"gopkg.in/validator.v2"
+	"math"
 	"net/mail"
 	"reflect"
 	"strconv"
@@ -15,6 +16,7 @@ var (
 	ErrUint     = validator.TextErr{Err: errors.New("invalid uint")}
 	ErrEmail    = validator.TextErr{Err: errors.New("invalid email")}
 	ErrUniq     = validator.TextErr{Err: errors.New("unique email")}
+	ErrLimit    = validator.TextErr{Err: errors.New("invalid limit")}
 )
 
 type ValidatorSetting struct {
@@ -29,6 +31,7 @@ type Validator struct {
 func initValidator() {
 	validator.SetValidationFunc("required", requiredValidator)
 	validator.SetValidationFunc("uint", uintValidator)
+	validator.SetValidationFunc("json_uint", jsonUintValidator)
 	validator.SetValidationFunc("email", emailValidator)
 }
 
@@ -91,7 +94,7 @@ func uintValidator(v interface{}, param string) error {
 	case reflect.String:
 		n64, err := strconv.ParseInt(st.String(), 10, 64)
 		if err != nil {
-			glog.Warningf("%s:%s", param, err.Error())
+			registry.GetFactory().BuildLogger().Debug("failed to parse uint", "param", param, "error", err)
 			return validator.ErrUnsupported
 		}
 		n = int(n64)
@@ -100,7 +103,7 @@ func uintValidator(v interface{}, param string) error {
 	case reflect.Float64:
 		n = int(v.(float64))
 	default:
-		glog.Warningf("%s:%s", param, st.Kind())
+		registry.GetFactory().BuildLogger().Debug("unsupported kind", "param", param, "kind", st.Kind().String())
 		return validator.ErrUnsupported
 	}
 
@@ -111,6 +114,20 @@ func uintValidator(v interface{}, param string) error {
 	return nil
 }
 
+// jsonUintValidator JSONの本文の値が0以上の整数であることを検証する。文字列や小数は受け付けない
+func jsonUintValidator(v interface{}, param string) error {
+	if v == nil {
+		return nil
+	}
+
+	n, ok := v.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return ErrUint
+	}
+
+	return nil
+}
+
 func emailValidator(v interface{}, param string) error {
 	if v == nil {
 		return nil
@@ -124,9 +141,18 @@ func emailValidator(v interface{}, param string) error {
 
 	_, err := mail.ParseAddress(st.String())
 	if err != nil {
-		glog.Warningf("failed to parse %s: %s", st.String(), err.Error())
+		registry.GetFactory().BuildLogger().Debug("failed to parse email", "error", err)
 		return ErrEmail
 	}
 
 	return nil
 }
+
+// unmarshalBodyErrors JSONの本文を構造体に変換できなかった場合のエラーを、項目ごとのエラーに変換する
+func unmarshalBodyErrors(err error) map[string]error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]error{typeErr.Field: validator.ErrUnsupported}
+	}
+	return map[string]error{}
+}
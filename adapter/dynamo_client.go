@@ -9,6 +9,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// maxTransactionItems 1回のトランザクションで扱える最大アイテム数
+const maxTransactionItems = 25
+
 type DynamoClient struct {
 	Client *dynamo.DB
 	Config *aws.Config
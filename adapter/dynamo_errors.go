@@ -0,0 +1,59 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+// isTransactionConditionFailed トランザクションのindex番目の操作が条件チェックにより失敗したかどうかを判定する
+func isTransactionConditionFailed(err error, index int) bool {
+	canceled, ok := errors.Cause(err).(*dynamodb.TransactionCanceledException)
+	if !ok || index >= len(canceled.CancellationReasons) {
+		return false
+	}
+	return aws.StringValue(canceled.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
+
+// isConditionFailed 単一の操作が条件チェックにより失敗したかどうかを判定する
+func isConditionFailed(err error) bool {
+	aerr, ok := errors.Cause(err).(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// translateDynamoError スロットリングやタイムアウトなど、時間をおいて再実行すれば成功する可能性があるエラーをErrUnavailableに、
+// 他のトランザクションと競合したエラーをErrConflictに変換する
+func translateDynamoError(err error) error {
+	cause := errors.Cause(err)
+	if isTransactionConflict(cause) {
+		return domain.ErrConflict.Wrap(err)
+	}
+	// コンテキストのキャンセルや期限切れでリクエストが中断された場合
+	if aerr, ok := cause.(awserr.Error); ok && aerr.Code() == request.CanceledErrorCode {
+		return domain.ErrUnavailable.Wrap(err)
+	}
+	if request.IsErrorThrottle(cause) {
+		return domain.ErrUnavailable.Wrap(err)
+	}
+	if reqErr, ok := cause.(awserr.RequestFailure); ok && reqErr.StatusCode() >= 500 {
+		return domain.ErrUnavailable.Wrap(err)
+	}
+	return err
+}
+
+// isTransactionConflict 同じ項目を操作する他のトランザクションと競合して失敗したかどうかを判定する
+func isTransactionConflict(err error) bool {
+	canceled, ok := errors.Cause(err).(*dynamodb.TransactionCanceledException)
+	if !ok {
+		return false
+	}
+	for _, reason := range canceled.CancellationReasons {
+		if aws.StringValue(reason.Code) == "TransactionConflict" {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,21 @@
 package adapter
 
 import (
+	"clean-serverless-book-sample-v2/adapter/tracing"
+	"context"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/guregu/dynamo"
 	"github.com/memememomo/nomof"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"reflect"
+	"sort"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,11 +35,66 @@ type DynamoResource interface {
 	SetUpdatedAt(t time.Time)
 }
 
+// DynamoIndexedResource GSI1 に載せるリソースが実装するインタフェース
+type DynamoIndexedResource interface {
+	SetGSI1()
+}
+
+// DynamoAggregatedResource 他のリソースの作成・削除と同じトランザクションで更新する集計値を持つリソースが実装するインタフェース。
+// 集計値を読み込んだ時点の値で上書きしないよう、BuildQueryUpdateでは更新しない
+type DynamoAggregatedResource interface {
+	AggregatedAttributes() []string
+}
+
+// DynamoTimelineResource 公開タイムラインに載せるリソースが実装するインタフェース
+type DynamoTimelineResource interface {
+	TimelineResource() *TimelineResource
+}
+
 type DynamoModelMapper struct {
 	Client    *ResourceTableOperator
 	TableName string
 	PKName    string
 	SKName    string
+	// Metrics 消費キャパシティの記録先。nilの場合は記録しない
+	Metrics CapacityMetrics
+	// Tracer 操作ごとのスパンを作成する。nilの場合はスパンを作成しない
+	Tracer trace.Tracer
+	// Now 作成日時・更新日時に使う現在時刻。nilの場合は time.Now を使う
+	Now func() time.Time
+	// timelineStartRecorded 公開タイムラインの開始日時を記録済みの場合は1
+	timelineStartRecorded int32
+}
+
+func (d *DynamoModelMapper) now() time.Time {
+	if d.Now == nil {
+		return time.Now()
+	}
+	return d.Now()
+}
+
+// startOperation DynamoDBの操作ごとにスパンを開始する。
+// 返り値の関数で、操作で消費したキャパシティユニットの記録とスパンの終了を行う
+func (d *DynamoModelMapper) startOperation(ctx context.Context, operation string) (context.Context, func(cc *dynamo.ConsumedCapacity, err error)) {
+	tracer := d.Tracer
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer(tracing.InstrumentationName)
+	}
+
+	ctx, span := tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "dynamodb"),
+			attribute.StringSlice("aws.dynamodb.table_names", []string{d.TableName}),
+		))
+
+	return ctx, func(cc *dynamo.ConsumedCapacity, err error) {
+		if d.Metrics != nil {
+			d.Metrics.ObserveConsumedCapacity(ctx, operation, cc.Total)
+		}
+		span.SetAttributes(attribute.Float64("aws.dynamodb.consumed_capacity", cc.Total))
+		tracing.End(span, err)
+	}
 }
 
 func (d *DynamoModelMapper) GetEntityNameFromStruct(s interface{}) string {
@@ -40,35 +102,52 @@ func (d *DynamoModelMapper) GetEntityNameFromStruct(s interface{}) string {
 	return r.Name()
 }
 
-func (d *DynamoModelMapper) BuildQueryCreate(resource DynamoResource) (*dynamo.Put, error) {
+// BuildQueryCreate リソースを新規作成するクエリを生成する。
+// 先頭がリソース本体で、公開タイムラインに載せるリソースの場合はインデックス項目を作成するクエリが続く
+func (d *DynamoModelMapper) BuildQueryCreate(ctx context.Context, resource DynamoResource) ([]*dynamo.Put, error) {
 	table, err := d.Client.ConnectTable()
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	id, err := d.generateID(resource.EntityName())
+	// 採番を待つ間に日付が変わっても、IDの順と作成日時の順が逆転しないよう先に日時を決める
+	now := d.now()
+	id, err := d.generateID(ctx, resource.EntityName())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	resource.SetCreatedAt(time.Now())
-	resource.SetUpdatedAt(time.Now())
+	resource.SetCreatedAt(now)
+	resource.SetUpdatedAt(now)
 	resource.SetID(id)
 	resource.SetVersion(1)
 	resource.SetPK()
 	resource.SetSK()
+	if indexed, ok := resource.(DynamoIndexedResource); ok {
+		indexed.SetGSI1()
+	}
 
 	fb := nomof.NewBuilder()
 	fb.AttributeNotExists(d.PKName)
 
-	query := table.
-		Put(resource).
-		If(fb.JoinAnd(), fb.Arg...)
+	queries := []*dynamo.Put{
+		table.
+			Put(resource).
+			If(fb.JoinAnd(), fb.Arg...),
+	}
 
-	return query, nil
+	if timeline, ok := resource.(DynamoTimelineResource); ok {
+		entry := timeline.TimelineResource()
+		entry.SetKeys()
+		queries = append(queries, table.Put(entry))
+	}
+
+	return queries, nil
 }
 
-func (d *DynamoModelMapper) BuildQueryUpdate(resource DynamoResource) (*dynamo.Put, error) {
+// BuildQueryUpdate バージョンが一致する場合のみリソースを更新するクエリを生成する。
+// 集計値を持つリソースの場合、集計値以外の属性だけを更新する
+func (d *DynamoModelMapper) BuildQueryUpdate(resource DynamoResource) (*dynamo.Update, error) {
 	table, err := d.Client.ConnectTable()
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -76,14 +155,57 @@ func (d *DynamoModelMapper) BuildQueryUpdate(resource DynamoResource) (*dynamo.P
 
 	oldVersion := resource.Version()
 
-	resource.SetUpdatedAt(time.Now())
+	resource.SetUpdatedAt(d.now())
 	resource.SetVersion(oldVersion + 1)
 
+	item, err := dynamo.MarshalItem(resource)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	skip := map[string]bool{d.PKName: true, d.SKName: true}
+	if aggregated, ok := resource.(DynamoAggregatedResource); ok {
+		for _, name := range aggregated.AggregatedAttributes() {
+			skip[name] = true
+		}
+	}
+
+	var names []string
+	for name := range item {
+		if !skip[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	query := table.
+		Update(d.PKName, resource.PK()).
+		Range(d.SKName, resource.SK())
+	for _, name := range names {
+		query.Set(name, item[name])
+	}
+
 	fb := nomof.NewBuilder()
 	fb.Equal("Version", oldVersion)
 
+	return query.If(fb.JoinAnd(), fb.Arg...), nil
+}
+
+// BuildQueryIncrement リソースの集計値を、atomicCountと同様に読み込まずにADDで増減するクエリを生成する。
+// リソースが存在しない場合は条件チェックで失敗する
+func (d *DynamoModelMapper) BuildQueryIncrement(resource DynamoResource, counterName string, value int) (*dynamo.Update, error) {
+	table, err := d.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	fb := nomof.NewBuilder()
+	fb.AttributeExists(d.PKName)
+
 	query := table.
-		Put(resource).
+		Update(d.PKName, resource.PK()).
+		Range(d.SKName, resource.SK()).
+		Add(counterName, value).
 		If(fb.JoinAnd(), fb.Arg...)
 
 	return query, nil
@@ -102,53 +224,112 @@ func (d *DynamoModelMapper) BuildQueryDelete(resource DynamoResource) (*dynamo.D
 	return query, nil
 }
 
-func (d *DynamoModelMapper) CreateResource(resource DynamoResource) error {
-	query, err := d.BuildQueryCreate(resource)
+// BuildQueryDeleteWithVersion バージョンが一致する場合のみ削除するクエリを生成する。バージョンが0の場合は無条件に削除する
+func (d *DynamoModelMapper) BuildQueryDeleteWithVersion(resource DynamoResource) (*dynamo.Delete, error) {
+	query, err := d.BuildQueryDelete(resource)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
+	}
+
+	if resource.Version() == 0 {
+		return query, nil
+	}
+
+	fb := nomof.NewBuilder()
+	fb.Equal("Version", resource.Version())
+
+	return query.If(fb.JoinAnd(), fb.Arg...), nil
+}
+
+// BuildQueryCheckExists トランザクション内でリソースが存在することを確認するクエリを生成する
+func (d *DynamoModelMapper) BuildQueryCheckExists(resource DynamoResource) (*dynamo.ConditionCheck, error) {
+	table, err := d.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
-	err = query.Run()
+	query := table.
+		Check(d.PKName, resource.PK()).
+		Range(d.SKName, resource.SK()).
+		IfExists()
+
+	return query, nil
+}
+
+func (d *DynamoModelMapper) CreateResource(ctx context.Context, resource DynamoResource) error {
+	queries, err := d.BuildQueryCreate(ctx, resource)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := d.startOperation(ctx, "DynamoModelMapper.CreateResource")
+	if len(queries) == 1 {
+		err = queries[0].ConsumedCapacity(&cc).RunWithContext(opCtx)
+	} else {
+		err = d.runPutTx(opCtx, queries, &cc)
+	}
+	end(&cc, err)
+	if err != nil {
+		return errors.WithStack(translateDynamoError(err))
+	}
+
 	return nil
 }
 
-func (d *DynamoModelMapper) UpdateResource(resource DynamoResource) error {
-	query, err := d.BuildQueryUpdate(resource)
+// runPutTx 複数のPutを同じトランザクションで実行する
+func (d *DynamoModelMapper) runPutTx(ctx context.Context, queries []*dynamo.Put, cc *dynamo.ConsumedCapacity) error {
+	conn, err := d.Client.ConnectDB()
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	err = query.Run()
+	tx := conn.WriteTx()
+	for _, q := range queries {
+		tx.Put(q)
+	}
+	return tx.ConsumedCapacity(cc).RunWithContext(ctx)
+}
+
+func (d *DynamoModelMapper) UpdateResource(ctx context.Context, resource DynamoResource) error {
+	query, err := d.BuildQueryUpdate(resource)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := d.startOperation(ctx, "DynamoModelMapper.UpdateResource")
+	err = query.ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
+	if err != nil {
+		return errors.WithStack(translateDynamoError(err))
+	}
+
 	return nil
 }
 
-func (d *DynamoModelMapper) DeleteResource(resource DynamoResource) error {
+func (d *DynamoModelMapper) DeleteResource(ctx context.Context, resource DynamoResource) error {
 	query, err := d.BuildQueryDelete(resource)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	err = query.Run()
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := d.startOperation(ctx, "DynamoModelMapper.DeleteResource")
+	err = query.ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
 	if err != nil {
-		return errors.WithStack(err)
+		return errors.WithStack(translateDynamoError(err))
 	}
 
 	return nil
 }
 
-func (d *DynamoModelMapper) PutResource(resource DynamoResource) error {
+func (d *DynamoModelMapper) PutResource(ctx context.Context, resource DynamoResource) error {
 	if d.isNewEntity(resource) {
-		return d.CreateResource(resource)
+		return d.CreateResource(ctx, resource)
 	}
-	return d.UpdateResource(resource)
+	return d.UpdateResource(ctx, resource)
 }
 
 func (d *DynamoModelMapper) GetPK(resource DynamoResource) string {
@@ -159,20 +340,153 @@ func (d *DynamoModelMapper) GetSK(resource DynamoResource) string {
 	return fmt.Sprintf("%011d", resource.ID())
 }
 
-func (d *DynamoModelMapper) GetEntityByID(id uint64, resource DynamoResource, ret interface{}) (interface{}, error) {
+// GetUserPartitionKey ユーザー単位でまとめるためのパーティションキー
+func (d *DynamoModelMapper) GetUserPartitionKey(userID uint64) string {
+	return fmt.Sprintf("%s-%011d", d.GetEntityNameFromStruct(UserResource{}), userID)
+}
+
+// GetFollowingPartitionKey ユーザーがフォローしている関係をまとめるためのパーティションキー
+func (d *DynamoModelMapper) GetFollowingPartitionKey(userID uint64) string {
+	return fmt.Sprintf("%s-%011d", d.GetEntityNameFromStruct(RelationshipResource{}), userID)
+}
+
+// GetFollowersPartitionKey ユーザーがフォローされている関係をまとめるためのパーティションキー
+func (d *DynamoModelMapper) GetFollowersPartitionKey(userID uint64) string {
+	return fmt.Sprintf("%s-Followers-%011d", d.GetEntityNameFromStruct(RelationshipResource{}), userID)
+}
+
+// GetMicropostPartitionKey マイクロポストのパーティションキー。マイクロポストへのいいねと返信のインデックス項目も同じパーティションに保存する
+func (d *DynamoModelMapper) GetMicropostPartitionKey(micropostID uint64) string {
+	return fmt.Sprintf("%s-%011d", d.GetEntityNameFromStruct(MicropostResource{}), micropostID)
+}
+
+// GetLikeSortKeyPrefix マイクロポストのパーティション内で、いいねを本体と区別するためのソートキーの接頭辞
+func (d *DynamoModelMapper) GetLikeSortKeyPrefix() string {
+	return fmt.Sprintf("%s-", d.GetEntityNameFromStruct(LikeResource{}))
+}
+
+// GetReplySortKeyPrefix マイクロポストのパーティション内で、返信のインデックス項目を本体と区別するためのソートキーの接頭辞
+func (d *DynamoModelMapper) GetReplySortKeyPrefix() string {
+	return fmt.Sprintf("%s-", d.GetEntityNameFromStruct(ReplyResource{}))
+}
+
+// GetUserLikesPartitionKey ユーザーのいいねをまとめるためのパーティションキー
+func (d *DynamoModelMapper) GetUserLikesPartitionKey(userID uint64) string {
+	return fmt.Sprintf("%s-%011d", d.GetEntityNameFromStruct(LikeResource{}), userID)
+}
+
+// GetTimeSortKey 作成日時順に並べるためのソートキー。同時刻の場合はIDで順序を決める
+func (d *DynamoModelMapper) GetTimeSortKey(t time.Time, id uint64) string {
+	return fmt.Sprintf("%s-%011d", t.UTC().Format("2006-01-02T15:04:05.000000000Z"), id)
+}
+
+// timelineShardCount 公開タイムラインのインデックス項目を1日あたりに分けるパーティションの数
+const timelineShardCount = 4
+
+// GetTimelineBucket 公開タイムラインのインデックス項目をまとめる日付
+func (d *DynamoModelMapper) GetTimelineBucket(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// GetTimelineShard マイクロポストのインデックス項目を書き込むパーティションの番号。
+// IDは作成順に採番されるため、分割数で割った余りで同じ日の書き込みを各パーティションに均等に分散できる
+func (d *DynamoModelMapper) GetTimelineShard(micropostID uint64) int {
+	return int(micropostID % timelineShardCount)
+}
+
+// GetTimelinePartitionKey 公開タイムラインのインデックス項目を日ごと・パーティションの番号ごとにまとめるためのパーティションキー
+func (d *DynamoModelMapper) GetTimelinePartitionKey(t time.Time, shard int) string {
+	return fmt.Sprintf("%s-%s-%d", d.GetEntityNameFromStruct(TimelineResource{}), d.GetTimelineBucket(t).Format("2006-01-02"), shard)
+}
+
+// TimelineStart 公開タイムラインの開始日時を表した構造体。タイムラインを遡る際にこれより前の日付は読まない
+type TimelineStart struct {
+	PK        string    `dynamo:"PK,hash"`
+	SK        string    `dynamo:"SK,range"`
+	StartedAt time.Time `dynamo:"StartedAt"`
+}
+
+// buildQueryRecordTimelineStart 公開タイムラインの開始日時がまだ記録されていなければ記録するクエリを生成する。
+// マイクロポストの作成と同じトランザクションで実行し、成功したら markTimelineStartRecorded を呼ぶ。
+// 全ての作成で同じ項目に書き込まないよう、記録済みのプロセスではnilを返す
+func (d *DynamoModelMapper) buildQueryRecordTimelineStart(t time.Time) (*dynamo.Update, error) {
+	if atomic.LoadInt32(&d.timelineStartRecorded) == 1 {
+		return nil, nil
+	}
+
+	table, err := d.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	key := d.newTimelineStart(time.Time{})
+	return table.
+		Update(d.PKName, key.PK).
+		Range(d.SKName, key.SK).
+		SetIfNotExists("StartedAt", t), nil
+}
+
+// markTimelineStartRecorded 公開タイムラインの開始日時を記録済みとし、以降は記録しない
+func (d *DynamoModelMapper) markTimelineStartRecorded() {
+	atomic.StoreInt32(&d.timelineStartRecorded, 1)
+}
+
+// getTimelineStart 公開タイムラインの開始日時を取得する。まだ何も投稿されていない場合はfalseを返す
+func (d *DynamoModelMapper) getTimelineStart(ctx context.Context) (time.Time, bool, error) {
+	table, err := d.Client.ConnectTable()
+	if err != nil {
+		return time.Time{}, false, errors.WithStack(err)
+	}
+
+	key := d.newTimelineStart(time.Time{})
+
+	var start TimelineStart
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := d.startOperation(ctx, "DynamoModelMapper.GetTimelineStart")
+	err = table.
+		Get(d.PKName, key.PK).
+		Range(d.SKName, dynamo.Equal, key.SK).
+		ConsumedCapacity(&cc).
+		OneWithContext(opCtx, &start)
+	end(&cc, err)
+	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, errors.WithStack(translateDynamoError(err))
+	}
+
+	return start.StartedAt, true, nil
+}
+
+func (d *DynamoModelMapper) newTimelineStart(t time.Time) *TimelineStart {
+	name := d.GetEntityNameFromStruct(TimelineStart{})
+	return &TimelineStart{
+		PK:        name,
+		SK:        name,
+		StartedAt: t,
+	}
+}
+
+func (d *DynamoModelMapper) GetEntityByID(ctx context.Context, id uint64, resource DynamoResource, ret interface{}) (interface{}, error) {
 	table, err := d.Client.ConnectTable()
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
 	resource.SetID(id)
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := d.startOperation(ctx, "DynamoModelMapper.GetEntityByID")
 	err = table.
 		Get(d.PKName, resource.PK()).
 		Range(d.SKName, dynamo.Equal, resource.SK()).
-		One(ret)
+		ConsumedCapacity(&cc).
+		OneWithContext(opCtx, ret)
+	end(&cc, err)
 
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, errors.WithStack(translateDynamoError(err))
 	}
 
 	return ret, nil
@@ -182,8 +496,8 @@ func (d *DynamoModelMapper) isNewEntity(resource DynamoResource) bool {
 	return resource.Version() == 0
 }
 
-func (d *DynamoModelMapper) generateID(tableName string) (uint64, error) {
-	attr, err := d.atomicCount(fmt.Sprintf("AtomicCounter-%s", tableName), "AtomicCounter", "CurrentNumber", 1)
+func (d *DynamoModelMapper) generateID(ctx context.Context, tableName string) (uint64, error) {
+	attr, err := d.atomicCount(ctx, fmt.Sprintf("AtomicCounter-%s", tableName), "AtomicCounter", "CurrentNumber", 1)
 	if err != nil {
 		return 0, errors.WithStack(err)
 	}
@@ -197,13 +511,15 @@ func (d *DynamoModelMapper) generateID(tableName string) (uint64, error) {
 	return n, nil
 }
 
-func (d *DynamoModelMapper) atomicCount(pk, sk, counterName string, value int) (*dynamodb.AttributeValue, error) {
+func (d *DynamoModelMapper) atomicCount(ctx context.Context, pk, sk, counterName string, value int) (*dynamodb.AttributeValue, error) {
 	db, err := d.Client.ConnectDB()
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	output, err := db.Client().UpdateItem(&dynamodb.UpdateItemInput{
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := d.startOperation(ctx, "DynamoModelMapper.AtomicCount")
+	output, err := db.Client().UpdateItemWithContext(opCtx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(d.TableName),
 		Key: map[string]*dynamodb.AttributeValue{
 			"PK": {
@@ -221,11 +537,16 @@ func (d *DynamoModelMapper) atomicCount(pk, sk, counterName string, value int) (
 				},
 			},
 		},
-		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+		ReturnValues:           aws.String(dynamodb.ReturnValueUpdatedNew),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
 	})
+	if output != nil && output.ConsumedCapacity != nil {
+		cc.Total = aws.Float64Value(output.ConsumedCapacity.CapacityUnits)
+	}
+	end(&cc, err)
 
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, errors.WithStack(translateDynamoError(err))
 	}
 
 	return output.Attributes[counterName], nil
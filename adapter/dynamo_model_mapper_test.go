@@ -0,0 +1,125 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"github.com/guregu/dynamo"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capacityObservation 記録された消費キャパシティ
+type capacityObservation struct {
+	Operation string
+	Units     float64
+}
+
+type fakeCapacityMetrics struct {
+	observations []capacityObservation
+}
+
+func (m *fakeCapacityMetrics) ObserveConsumedCapacity(ctx context.Context, operation string, units float64) {
+	m.observations = append(m.observations, capacityObservation{Operation: operation, Units: units})
+}
+
+// TestDynamoModelMapper_StartOperation 操作ごとにスパンが作成され、消費キャパシティが記録されること
+func TestDynamoModelMapper_StartOperation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	metrics := &fakeCapacityMetrics{}
+	mapper := &DynamoModelMapper{TableName: "test-table", Metrics: metrics, Tracer: provider.Tracer("test")}
+
+	ctx, end := mapper.startOperation(context.Background(), "UserOperator.CreateUser")
+	assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+	end(&dynamo.ConsumedCapacity{Total: 2}, nil)
+
+	_, end = mapper.startOperation(context.Background(), "UserOperator.UpdateUser")
+	end(&dynamo.ConsumedCapacity{}, errors.New("failed"))
+
+	assert.Equal(t, []capacityObservation{
+		{Operation: "UserOperator.CreateUser", Units: 2},
+		{Operation: "UserOperator.UpdateUser", Units: 0},
+	}, metrics.observations)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "UserOperator.CreateUser", spans[0].Name)
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+	assert.Contains(t, spans[0].Attributes, attribute.String("db.system", "dynamodb"))
+	assert.Contains(t, spans[0].Attributes, attribute.StringSlice("aws.dynamodb.table_names", []string{"test-table"}))
+	assert.Contains(t, spans[0].Attributes, attribute.Float64("aws.dynamodb.consumed_capacity", 2))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+}
+
+// TestDynamoModelMapper_StartOperation_NoTracer Tracerが設定されていない場合も操作できること
+func TestDynamoModelMapper_StartOperation_NoTracer(t *testing.T) {
+	mapper := &DynamoModelMapper{TableName: "test-table"}
+
+	ctx, end := mapper.startOperation(context.Background(), "UserOperator.CreateUser")
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+	end(&dynamo.ConsumedCapacity{Total: 1}, nil)
+}
+
+// TestDynamoModelMapper_GetTimelinePartitionKey 公開タイムラインのインデックス項目はUTCの日付ごとに、IDで複数のパーティションに分けてまとめること
+func TestDynamoModelMapper_GetTimelinePartitionKey(t *testing.T) {
+	mapper := &DynamoModelMapper{}
+	jst := time.FixedZone("JST", 9*60*60)
+
+	assert.Equal(t, "TimelineResource-2024-01-31-0", mapper.GetTimelinePartitionKey(time.Date(2024, 2, 1, 8, 59, 59, 0, jst), 0))
+	assert.Equal(t, "TimelineResource-2024-02-01-3", mapper.GetTimelinePartitionKey(time.Date(2024, 2, 1, 9, 0, 0, 0, jst), 3))
+
+	entry := NewTimelineResource(12, 3, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), mapper)
+	entry.SetKeys()
+	assert.Equal(t, "TimelineResource-2024-02-01-0", entry.ResourceSchema.PK)
+	assert.Equal(t, "00000000012", entry.ResourceSchema.SK)
+
+	// 連続したIDの書き込みは全てのパーティションに分散する
+	shards := map[int]bool{}
+	for id := uint64(1); id <= timelineShardCount; id++ {
+		shards[mapper.GetTimelineShard(id)] = true
+	}
+	assert.Len(t, shards, timelineShardCount)
+}
+
+// TestDynamoModelMapper_LikeKeys いいねはマイクロポストと同じパーティションに、本体と区別できるソートキーで保存すること
+func TestDynamoModelMapper_LikeKeys(t *testing.T) {
+	mapper := &DynamoModelMapper{}
+
+	micropost := NewMicropostResource(&domain.MicropostModel{ID: 12}, mapper)
+	assert.Equal(t, micropost.PK(), mapper.GetMicropostPartitionKey(12))
+
+	like := NewLikeResource(domain.NewLikeModel(12, 3), mapper)
+	like.SetKeys()
+	assert.Equal(t, micropost.PK(), like.ResourceSchema.PK)
+	assert.Equal(t, "LikeResource-00000000003", like.ResourceSchema.SK)
+	assert.NotEqual(t, micropost.SK(), like.ResourceSchema.SK)
+	assert.Equal(t, "LikeResource-00000000003", like.ResourceSchema.GSI1PK)
+	assert.Equal(t, "00000000012", like.ResourceSchema.GSI1SK)
+}
+
+// TestDynamoModelMapper_ReplyKeys 返信のインデックス項目は返信先のマイクロポストと同じパーティションに、いいねと区別できるソートキーで保存すること
+func TestDynamoModelMapper_ReplyKeys(t *testing.T) {
+	mapper := &DynamoModelMapper{}
+
+	micropost := NewMicropostResource(&domain.MicropostModel{ID: 12}, mapper)
+	assert.Nil(t, micropost.ReplyResource())
+
+	reply := NewMicropostResource(domain.NewReplyModel("Reply", 3, 12), mapper)
+	reply.SetID(15)
+	entry := reply.ReplyResource()
+	require.NotNil(t, entry)
+	assert.Equal(t, micropost.PK(), entry.ResourceSchema.PK)
+	assert.Equal(t, "ReplyResource-00000000015", entry.ResourceSchema.SK)
+	assert.False(t, strings.HasPrefix(entry.ResourceSchema.SK, mapper.GetLikeSortKeyPrefix()))
+	assert.Empty(t, entry.ResourceSchema.GSI1PK)
+}
@@ -0,0 +1,116 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// CapacityMetrics DynamoDBの操作で消費したキャパシティユニットを記録する
+type CapacityMetrics interface {
+	ObserveConsumedCapacity(ctx context.Context, operation string, units float64)
+}
+
+// Metrics UseCaseとDynamoDBの操作のメトリクスを記録する
+type Metrics interface {
+	usecase.Metrics
+	CapacityMetrics
+}
+
+// EMFMetrics CloudWatchのEmbedded Metric Format(EMF)のJSONを1行ずつ書き出す。
+// Lambdaの標準出力に書き出すと、CloudWatch Logsに取り込まれた時点でメトリクスとして記録される
+type EMFMetrics struct {
+	Namespace string
+	Now       func() time.Time
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewEMFMetrics EMFMetrics インスタンスを生成
+func NewEMFMetrics(namespace string, out io.Writer) *EMFMetrics {
+	return &EMFMetrics{
+		Namespace: namespace,
+		Now:       time.Now,
+		out:       out,
+	}
+}
+
+// emfMetric 記録するメトリクスの名前と単位
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfDirective どのプロパティをメトリクス・ディメンションとして扱うかの指定
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// ObserveUseCase UseCaseの処理時間・成功数・失敗数を、UseCase名をディメンションとして記録する。
+// 失敗した場合は、UseCase名とエラーの種類の組み合わせでも記録する
+func (m *EMFMetrics) ObserveUseCase(ctx context.Context, name string, duration time.Duration, err error) {
+	success, failure := 1, 0
+	dimensions := [][]string{{"UseCase"}}
+	values := map[string]interface{}{"UseCase": name}
+	if err != nil {
+		success, failure = 0, 1
+		dimensions = append(dimensions, []string{"UseCase", "ErrorKind"})
+		values["ErrorKind"] = domain.KindOf(err).String()
+	}
+	values["Latency"] = float64(duration.Microseconds()) / 1000
+	values["Success"] = success
+	values["Error"] = failure
+
+	m.write(dimensions, []emfMetric{
+		{Name: "Latency", Unit: "Milliseconds"},
+		{Name: "Success", Unit: "Count"},
+		{Name: "Error", Unit: "Count"},
+	}, values)
+}
+
+// ObserveConsumedCapacity DynamoDBの消費キャパシティユニットを、操作名をディメンションとして記録する
+func (m *EMFMetrics) ObserveConsumedCapacity(ctx context.Context, operation string, units float64) {
+	m.write([][]string{{"Operation"}}, []emfMetric{
+		{Name: "ConsumedCapacity", Unit: "Count"},
+	}, map[string]interface{}{
+		"Operation":        operation,
+		"ConsumedCapacity": units,
+	})
+}
+
+func (m *EMFMetrics) write(dimensions [][]string, metrics []emfMetric, values map[string]interface{}) {
+	values["_aws"] = emfMetadata{
+		Timestamp: m.Now().UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  m.Namespace,
+			Dimensions: dimensions,
+			Metrics:    metrics,
+		}},
+	}
+
+	// 値は全て数値と文字列のため、変換に失敗することはない
+	b, _ := json.Marshal(values)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, _ = m.out.Write(append(b, '\n'))
+}
+
+// NopMetrics メトリクスを記録しない。メトリクスの名前空間が設定されていない場合に使う
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveUseCase(context.Context, string, time.Duration, error) {}
+
+func (NopMetrics) ObserveConsumedCapacity(context.Context, string, float64) {}
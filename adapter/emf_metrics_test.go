@@ -0,0 +1,96 @@
+package adapter
+
+import (
+	"bytes"
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestEMFMetrics(buf *bytes.Buffer) *EMFMetrics {
+	m := NewEMFMetrics("TestNamespace", buf)
+	m.Now = func() time.Time { return time.Unix(1577934245, 0) }
+	return m
+}
+
+func decodeEMFRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestEMFMetrics_ObserveUseCase 成功したUseCaseの処理時間と件数が記録されること
+func TestEMFMetrics_ObserveUseCase(t *testing.T) {
+	var buf bytes.Buffer
+	newTestEMFMetrics(&buf).ObserveUseCase(context.Background(), "CreateUser", 1500*time.Microsecond, nil)
+
+	assert.JSONEq(t, `{
+		"_aws": {
+			"Timestamp": 1577934245000,
+			"CloudWatchMetrics": [{
+				"Namespace": "TestNamespace",
+				"Dimensions": [["UseCase"]],
+				"Metrics": [
+					{"Name": "Latency", "Unit": "Milliseconds"},
+					{"Name": "Success", "Unit": "Count"},
+					{"Name": "Error", "Unit": "Count"}
+				]
+			}]
+		},
+		"UseCase": "CreateUser",
+		"Latency": 1.5,
+		"Success": 1,
+		"Error": 0
+	}`, buf.String())
+}
+
+// TestEMFMetrics_ObserveUseCase_Error 失敗したUseCaseはエラーの種類ごとにも記録されること
+func TestEMFMetrics_ObserveUseCase_Error(t *testing.T) {
+	var buf bytes.Buffer
+	m := newTestEMFMetrics(&buf)
+	m.ObserveUseCase(context.Background(), "UpdateUser", time.Millisecond, errors.WithStack(domain.ErrPreconditionFailed))
+	m.ObserveUseCase(context.Background(), "UpdateUser", time.Millisecond, errors.New("unknown"))
+
+	records := decodeEMFRecords(t, &buf)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "PreconditionFailed", records[0]["ErrorKind"])
+	assert.Equal(t, "Internal", records[1]["ErrorKind"])
+	for _, record := range records {
+		assert.Equal(t, float64(0), record["Success"])
+		assert.Equal(t, float64(1), record["Error"])
+
+		directive := record["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, []interface{}{
+			[]interface{}{"UseCase"},
+			[]interface{}{"UseCase", "ErrorKind"},
+		}, directive["Dimensions"])
+	}
+}
+
+// TestEMFMetrics_ObserveConsumedCapacity DynamoDBの消費キャパシティが操作ごとに記録されること
+func TestEMFMetrics_ObserveConsumedCapacity(t *testing.T) {
+	var buf bytes.Buffer
+	newTestEMFMetrics(&buf).ObserveConsumedCapacity(context.Background(), "UserOperator.CreateUser", 4)
+
+	records := decodeEMFRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "UserOperator.CreateUser", records[0]["Operation"])
+	assert.Equal(t, float64(4), records[0]["ConsumedCapacity"])
+
+	directive := records[0]["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{[]interface{}{"Operation"}}, directive["Dimensions"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"Name": "ConsumedCapacity", "Unit": "Count"},
+	}, directive["Metrics"])
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+)
+
+// ALBHandler ALBのターゲットグループのイベントを受け付けるLambdaのハンドラーを生成。
+// ALBはパスパラメータを渡さないため、hにはパスからルートを探すadapter/routerのRouterを指定する
+func ALBHandler(h controller.HandlerFunc) func(context.Context, events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	mustLoadSecrets()
+	return func(ctx context.Context, request events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+		multiValue := request.MultiValueHeaders != nil
+		req, err := NewRequestFromALB(request)
+		if err != nil {
+			return NewALBResponse(responseConvertError(ctx, err), multiValue), nil
+		}
+		// ALBのイベントにはリクエストIDが含まれないため、LambdaのリクエストIDを使う
+		req = withLambdaRequestID(ctx, req)
+		return NewALBResponse(invoke(ctx, h, req), multiValue), nil
+	}
+}
+
+// NewRequestFromALB ALBのイベントをコントローラーのリクエストに変換する
+func NewRequestFromALB(request events.ALBTargetGroupRequest) (controller.Request, error) {
+	body, err := decodeBody(request.Body, request.IsBase64Encoded)
+	if err != nil {
+		return controller.Request{}, err
+	}
+
+	// ALBはクエリパラメータをURLエンコードされたまま渡す
+	var query map[string]string
+	for name, value := range singleValues(request.QueryStringParameters, request.MultiValueQueryStringParameters) {
+		if query == nil {
+			query = map[string]string{}
+		}
+		key, err := url.QueryUnescape(name)
+		if err != nil {
+			return controller.Request{}, err
+		}
+		query[key], err = url.QueryUnescape(value)
+		if err != nil {
+			return controller.Request{}, err
+		}
+	}
+
+	return controller.Request{
+		HTTPMethod:            request.HTTPMethod,
+		Path:                  request.Path,
+		Headers:               singleValues(request.Headers, request.MultiValueHeaders),
+		QueryStringParameters: query,
+		Body:                  body,
+	}, nil
+}
+
+// NewALBResponse コントローラーのレスポンスをALBのレスポンスに変換する。
+// ターゲットグループで複数値ヘッダーを有効にしている場合は、ヘッダーを複数値の形式で返す必要がある
+func NewALBResponse(res controller.Response, multiValue bool) events.ALBTargetGroupResponse {
+	albRes := events.ALBTargetGroupResponse{
+		StatusCode:        res.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+		Body:              res.Body,
+	}
+
+	if !multiValue {
+		albRes.Headers = res.Headers
+		return albRes
+	}
+
+	albRes.MultiValueHeaders = map[string][]string{}
+	for name, value := range res.Headers {
+		albRes.MultiValueHeaders[name] = []string{value}
+	}
+	return albRes
+}
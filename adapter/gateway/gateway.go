@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"clean-serverless-book-sample-v2/adapter/tracing"
+	"clean-serverless-book-sample-v2/registry"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"time"
+)
+
+// deadlineMargin Lambdaがタイムアウトで強制終了される前に処理を打ち切り、エラーレスポンスを返すための猶予
+const deadlineMargin = 500 * time.Millisecond
+
+// mustLoadSecrets 暗号化された環境変数を、Lambdaの初期化時に復号する。
+// 復号できない場合はリクエストごとに失敗させず、初期化のエラーとして起動を中止する
+func mustLoadSecrets() {
+	if err := registry.GetFactory().Envs.LoadSecrets(); err != nil {
+		panic(err)
+	}
+}
+
+// Handler 受け取ったイベントの形式を判別して、API Gateway(REST API・HTTP API)とALBのどれからでも呼び出せるLambdaのハンドラーを生成
+func Handler(h controller.HandlerFunc) func(context.Context, json.RawMessage) (interface{}, error) {
+	proxy := ProxyHandler(h)
+	httpAPI := HTTPAPIHandler(h)
+	alb := ALBHandler(h)
+
+	return func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var probe struct {
+			Version        string `json:"version"`
+			RequestContext struct {
+				ELB *json.RawMessage `json:"elb"`
+			} `json:"requestContext"`
+		}
+		if err := json.Unmarshal(payload, &probe); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case probe.Version == "2.0":
+			var request events.APIGatewayV2HTTPRequest
+			if err := json.Unmarshal(payload, &request); err != nil {
+				return nil, err
+			}
+			return httpAPI(ctx, request)
+		case probe.RequestContext.ELB != nil:
+			var request events.ALBTargetGroupRequest
+			if err := json.Unmarshal(payload, &request); err != nil {
+				return nil, err
+			}
+			return alb(ctx, request)
+		default:
+			var request events.APIGatewayProxyRequest
+			if err := json.Unmarshal(payload, &request); err != nil {
+				return nil, err
+			}
+			return proxy(ctx, request)
+		}
+	}
+}
+
+// invoke 実行期限をLambdaより早めてコントローラーを呼び出す
+func invoke(ctx context.Context, h controller.HandlerFunc, req controller.Request) controller.Response {
+	dctx, cancel := withDeadlineMargin(ctx)
+	defer cancel()
+	res := h(req.WithContext(dctx))
+	flushSpans(ctx)
+	return res
+}
+
+// flushSpans Lambdaは呼び出しの合間に停止するため、レスポンスを返す前にバッファリングされているスパンを書き出す
+func flushSpans(ctx context.Context) {
+	if err := tracing.ForceFlush(ctx); err != nil {
+		registry.GetFactory().BuildLogger().Warn("failed to flush spans", "error", err)
+	}
+}
+
+// withDeadlineMargin Lambdaの実行期限よりdeadlineMarginだけ早く期限切れになるコンテキストを返す。
+// 期限が設定されていない場合は、キャンセルできるだけのコンテキストを返す
+func withDeadlineMargin(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-deadlineMargin))
+}
+
+// withLambdaRequestID イベントにリクエストIDが含まれていない場合は、LambdaのリクエストIDを使う
+func withLambdaRequestID(ctx context.Context, req controller.Request) controller.Request {
+	if req.RequestID != "" {
+		return req
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		req.RequestID = lc.AwsRequestID
+	}
+	return req
+}
+
+// responseConvertError イベントをコントローラーのリクエストに変換できなかった場合のレスポンス。
+// コントローラーを経由した場合と同じように、リクエストIDの付与とアクセスログの出力を行う
+func responseConvertError(ctx context.Context, err error) controller.Response {
+	req := withLambdaRequestID(ctx, controller.Request{}.WithContext(ctx))
+	res := controller.Middleware(func(controller.Request) controller.Response {
+		return controller.Response500(err)
+	})(req)
+	flushSpans(ctx)
+	return res
+}
+
+// decodeBody Base64エンコードされている場合はデコードする
+func decodeBody(body string, isBase64Encoded bool) (string, error) {
+	if !isBase64Encoded {
+		return body, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// singleValues 単一値の形式で渡されていればそれを使い、複数値の形式でしか渡されていない場合は最後の値を使う
+func singleValues(single map[string]string, multi map[string][]string) map[string]string {
+	if single != nil || multi == nil {
+		return single
+	}
+
+	values := map[string]string{}
+	for name, v := range multi {
+		if len(v) > 0 {
+			values[name] = v[len(v)-1]
+		}
+	}
+	return values
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// echoHandler 受け取ったリクエストをそのままJSONで返す
+func echoHandler(request controller.Request) controller.Response {
+	b, _ := json.Marshal(request)
+	return controller.Response{
+		StatusCode: 201,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}
+}
+
+func decodeEcho(t *testing.T, body string) controller.Request {
+	t.Helper()
+	var request controller.Request
+	err := json.Unmarshal([]byte(body), &request)
+	assert.NoError(t, err)
+	return request
+}
+
+// TestProxyHandler API Gateway(REST API)のイベントの変換
+func TestProxyHandler(t *testing.T) {
+	res, err := ProxyHandler(echoHandler)(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod:        "PUT",
+		Path:              "/v1/users/1",
+		Resource:          "/v1/users/{user_id}",
+		MultiValueHeaders: map[string][]string{"Authorization": {"Bearer token"}},
+		PathParameters:    map[string]string{"user_id": "1"},
+		Body:              base64.StdEncoding.EncodeToString([]byte(`{"user_name":"a"}`)),
+		IsBase64Encoded:   true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 201, res.StatusCode)
+	assert.Equal(t, "application/json", res.Headers["Content-Type"])
+
+	request := decodeEcho(t, res.Body)
+	assert.Equal(t, controller.Request{
+		HTTPMethod:     "PUT",
+		Path:           "/v1/users/1",
+		Resource:       "/v1/users/{user_id}",
+		Headers:        map[string]string{"Authorization": "Bearer token"},
+		PathParameters: map[string]string{"user_id": "1"},
+		Body:           `{"user_name":"a"}`,
+	}, request)
+}
+
+// TestHTTPAPIHandler API Gateway(HTTP API)のイベントの変換
+func TestHTTPAPIHandler(t *testing.T) {
+	request := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "GET /v1/users/{user_id}/microposts",
+		RawPath:               "/v1/users/1/microposts",
+		Cookies:               []string{"a=1", "b=2"},
+		Headers:               map[string]string{"authorization": "Bearer token"},
+		QueryStringParameters: map[string]string{"limit": "10"},
+		PathParameters:        map[string]string{"user_id": "1"},
+	}
+	request.RequestContext.HTTP.Method = "GET"
+
+	res, err := HTTPAPIHandler(echoHandler)(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, 201, res.StatusCode)
+
+	assert.Equal(t, controller.Request{
+		HTTPMethod:            "GET",
+		Path:                  "/v1/users/1/microposts",
+		Resource:              "/v1/users/{user_id}/microposts",
+		Headers:               map[string]string{"authorization": "Bearer token", "cookie": "a=1; b=2"},
+		QueryStringParameters: map[string]string{"limit": "10"},
+		PathParameters:        map[string]string{"user_id": "1"},
+	}, decodeEcho(t, res.Body))
+
+	// $defaultルートやプロキシルートの場合はテンプレートが分からない
+	assert.Equal(t, "", resourceFromRouteKey("$default"))
+	assert.Equal(t, "", resourceFromRouteKey("ANY /{proxy+}"))
+}
+
+// TestALBHandler ALBのイベントの変換
+func TestALBHandler(t *testing.T) {
+	request := events.ALBTargetGroupRequest{
+		HTTPMethod:                      "GET",
+		Path:                            "/v1/users",
+		MultiValueHeaders:               map[string][]string{"authorization": {"Bearer token"}},
+		MultiValueQueryStringParameters: map[string][]string{"cursor": {"a%2Bb"}},
+	}
+
+	res, err := ALBHandler(echoHandler)(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, 201, res.StatusCode)
+	assert.Equal(t, "201 Created", res.StatusDescription)
+
+	// 複数値ヘッダーで受け取った場合は複数値ヘッダーで返す
+	assert.Nil(t, res.Headers)
+	assert.Equal(t, []string{"application/json"}, res.MultiValueHeaders["Content-Type"])
+
+	assert.Equal(t, controller.Request{
+		HTTPMethod:            "GET",
+		Path:                  "/v1/users",
+		Headers:               map[string]string{"authorization": "Bearer token"},
+		QueryStringParameters: map[string]string{"cursor": "a+b"},
+	}, decodeEcho(t, res.Body))
+}
+
+// TestHandler イベントの形式の判別
+func TestHandler(t *testing.T) {
+	handler := Handler(echoHandler)
+
+	cases := []struct {
+		Msg      string
+		Payload  string
+		Expected interface{}
+	}{
+		{
+			Msg:      "REST API",
+			Payload:  `{"httpMethod":"GET","path":"/v1/users","resource":"/v1/users","requestContext":{"stage":"dev"}}`,
+			Expected: events.APIGatewayProxyResponse{},
+		},
+		{
+			Msg:      "HTTP API",
+			Payload:  `{"version":"2.0","routeKey":"GET /v1/users","rawPath":"/v1/users","requestContext":{"http":{"method":"GET"}}}`,
+			Expected: events.APIGatewayV2HTTPResponse{},
+		},
+		{
+			Msg:      "ALB",
+			Payload:  `{"httpMethod":"GET","path":"/v1/users","requestContext":{"elb":{"targetGroupArn":"arn"}}}`,
+			Expected: events.ALBTargetGroupResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		res, err := handler(context.Background(), json.RawMessage(c.Payload))
+		assert.NoError(t, err, c.Msg)
+		assert.IsType(t, c.Expected, res, c.Msg)
+	}
+}
+
+// TestProxyHandler_Deadline Lambdaの実行期限より早く期限切れになるコンテキストがコントローラーに渡される
+func TestProxyHandler_Deadline(t *testing.T) {
+	deadline := time.Now().Add(3 * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var got time.Time
+	var ok bool
+	_, err := ProxyHandler(func(request controller.Request) controller.Response {
+		got, ok = request.Context().Deadline()
+		return controller.Response{StatusCode: 200}
+	})(ctx, events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/v1/users"})
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, deadline.Add(-deadlineMargin), got)
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+)
+
+// HTTPAPIHandler API Gateway(HTTP API)のペイロード形式2.0のイベントを受け付けるLambdaのハンドラーを生成
+func HTTPAPIHandler(h controller.HandlerFunc) func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	mustLoadSecrets()
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		req, err := NewRequestFromHTTPAPI(request)
+		if err != nil {
+			return NewHTTPAPIResponse(responseConvertError(ctx, err)), nil
+		}
+		req = withLambdaRequestID(ctx, req)
+		return NewHTTPAPIResponse(invoke(ctx, h, req)), nil
+	}
+}
+
+// NewRequestFromHTTPAPI API Gateway(HTTP API)のイベントをコントローラーのリクエストに変換する
+func NewRequestFromHTTPAPI(request events.APIGatewayV2HTTPRequest) (controller.Request, error) {
+	body, err := decodeBody(request.Body, request.IsBase64Encoded)
+	if err != nil {
+		return controller.Request{}, err
+	}
+
+	headers := map[string]string{}
+	for name, value := range request.Headers {
+		headers[name] = value
+	}
+	// ペイロード形式2.0ではCookieヘッダーがcookiesに分けられているため、元に戻す
+	if len(request.Cookies) > 0 {
+		headers["cookie"] = strings.Join(request.Cookies, "; ")
+	}
+
+	return controller.Request{
+		RequestID:             request.RequestContext.RequestID,
+		HTTPMethod:            request.RequestContext.HTTP.Method,
+		Path:                  request.RawPath,
+		Resource:              resourceFromRouteKey(request.RouteKey),
+		Headers:               headers,
+		QueryStringParameters: request.QueryStringParameters,
+		PathParameters:        request.PathParameters,
+		Body:                  body,
+	}, nil
+}
+
+// NewHTTPAPIResponse コントローラーのレスポンスをAPI Gateway(HTTP API)のレスポンスに変換する
+func NewHTTPAPIResponse(res controller.Response) events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: res.StatusCode,
+		Headers:    res.Headers,
+		Body:       res.Body,
+	}
+}
+
+// resourceFromRouteKey "GET /v1/users/{user_id}" の形式のルートキーからパスのテンプレートを取り出す。
+// $default などのパスを含まないルートキーの場合は空を返す
+func resourceFromRouteKey(routeKey string) string {
+	i := strings.Index(routeKey, " ")
+	if i < 0 {
+		return ""
+	}
+	resource := routeKey[i+1:]
+	if strings.Contains(resource, "{proxy+}") {
+		return ""
+	}
+	return resource
+}
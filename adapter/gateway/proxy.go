@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ProxyHandler API Gateway(REST API)のプロキシ統合のイベントを受け付けるLambdaのハンドラーを生成
+func ProxyHandler(h controller.HandlerFunc) func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	mustLoadSecrets()
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		req, err := NewRequestFromProxy(request)
+		if err != nil {
+			return NewProxyResponse(responseConvertError(ctx, err)), nil
+		}
+		req = withLambdaRequestID(ctx, req)
+		return NewProxyResponse(invoke(ctx, h, req)), nil
+	}
+}
+
+// NewRequestFromProxy API Gateway(REST API)のイベントをコントローラーのリクエストに変換する
+func NewRequestFromProxy(request events.APIGatewayProxyRequest) (controller.Request, error) {
+	body, err := decodeBody(request.Body, request.IsBase64Encoded)
+	if err != nil {
+		return controller.Request{}, err
+	}
+
+	return controller.Request{
+		RequestID:             request.RequestContext.RequestID,
+		HTTPMethod:            request.HTTPMethod,
+		Path:                  request.Path,
+		Resource:              request.Resource,
+		Headers:               singleValues(request.Headers, request.MultiValueHeaders),
+		QueryStringParameters: singleValues(request.QueryStringParameters, request.MultiValueQueryStringParameters),
+		PathParameters:        request.PathParameters,
+		Body:                  body,
+	}, nil
+}
+
+// NewProxyResponse コントローラーのレスポンスをAPI Gateway(REST API)のレスポンスに変換する
+func NewProxyResponse(res controller.Response) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: res.StatusCode,
+		Headers:    res.Headers,
+		Body:       res.Body,
+	}
+}
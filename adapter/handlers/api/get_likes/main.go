@@ -0,0 +1,11 @@
+package main
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"clean-serverless-book-sample-v2/adapter/gateway"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	lambda.Start(gateway.ProxyHandler(controller.Middleware(controller.GetLikes)))
+}
@@ -2,14 +2,10 @@ package main
 
 import (
 	"clean-serverless-book-sample-v2/adapter/controller"
-	"github.com/aws/aws-lambda-go/events"
+	"clean-serverless-book-sample-v2/adapter/gateway"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return controller.GetUser(request), nil
-}
-
 func main() {
-	lambda.Start(handler)
+	lambda.Start(gateway.ProxyHandler(controller.Middleware(controller.GetUser)))
 }
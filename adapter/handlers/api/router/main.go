@@ -0,0 +1,12 @@
+package main
+
+import (
+	"clean-serverless-book-sample-v2/adapter/gateway"
+	"clean-serverless-book-sample-v2/adapter/router"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	// API Gateway(REST API・HTTP API)とALBのどれから呼び出されても、同じルーティングで処理する
+	lambda.Start(gateway.Handler(router.NewRouter(router.Routes()).Handle))
+}
@@ -0,0 +1,217 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+// JWTVerifier HS256/RS256で署名されたJWTを検証する
+type JWTVerifier struct {
+	HS256Secret       []byte
+	RS256PublicKeyPEM string
+	Issuer            string
+	Audience          string
+	Now               func() time.Time
+}
+
+// jwtHeader JWTのヘッダー部
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// jwtClaims JWTのクレームのうち、検証に使う項目
+type jwtClaims struct {
+	Subject   string      `json:"sub"`
+	Issuer    string      `json:"iss"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt *int64      `json:"exp"`
+	NotBefore *int64      `json:"nbf"`
+	Roles     []string    `json:"roles"`
+}
+
+// jwtAudience audクレームは文字列と文字列の配列のどちらも取りうる
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(b, &multi); err != nil {
+		return err
+	}
+	*a = multi
+	return nil
+}
+
+func (a jwtAudience) contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// NewJWTVerifier JWTVerifier インスタンスを生成。鍵が設定されていないアルゴリズムのトークンは受け付けない。
+// どちらの鍵も設定されていない場合は、全てのトークンを拒否することになるためエラーを返す
+func NewJWTVerifier(hs256Secret, rs256PublicKeyPEM, issuer, audience string) (*JWTVerifier, error) {
+	if hs256Secret == "" && rs256PublicKeyPEM == "" {
+		return nil, errors.New("neither HS256 secret nor RS256 public key is set")
+	}
+
+	return &JWTVerifier{
+		HS256Secret:       []byte(hs256Secret),
+		RS256PublicKeyPEM: rs256PublicKeyPEM,
+		Issuer:            issuer,
+		Audience:          audience,
+		Now:               time.Now,
+	}, nil
+}
+
+// Verify トークンの署名とクレームを検証し、subjectをリクエスト送信者として返す
+func (j *JWTVerifier) Verify(token string) (*domain.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.WithStack(domain.ErrUnauthorized)
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, errors.WithStack(domain.ErrUnauthorized)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.WithStack(domain.ErrUnauthorized)
+	}
+
+	err = j.verifySignature(header.Alg, parts[0]+"."+parts[1], signature)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, errors.WithStack(domain.ErrUnauthorized)
+	}
+
+	err = j.verifyClaims(&claims)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return domain.NewPrincipal(claims.Subject, claims.Roles), nil
+}
+
+func (j *JWTVerifier) verifySignature(alg, signingInput string, signature []byte) error {
+	switch alg {
+	case "HS256":
+		if len(j.HS256Secret) == 0 {
+			return errors.WithStack(domain.ErrUnauthorized)
+		}
+		mac := hmac.New(sha256.New, j.HS256Secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errors.WithStack(domain.ErrUnauthorized)
+		}
+		return nil
+	case "RS256":
+		if j.RS256PublicKeyPEM == "" {
+			return errors.WithStack(domain.ErrUnauthorized)
+		}
+		publicKey, err := parseRSAPublicKey(j.RS256PublicKeyPEM)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.WithStack(domain.ErrUnauthorized)
+		}
+		return nil
+	default:
+		// "none" を含め、想定していないアルゴリズムは受け付けない
+		return errors.WithStack(domain.ErrUnauthorized)
+	}
+}
+
+func (j *JWTVerifier) verifyClaims(claims *jwtClaims) error {
+	now := j.Now().Unix()
+
+	if claims.Subject == "" {
+		return errors.WithStack(domain.ErrUnauthorized)
+	}
+	if claims.ExpiresAt == nil || now >= *claims.ExpiresAt {
+		return errors.WithStack(domain.ErrUnauthorized)
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return errors.WithStack(domain.ErrUnauthorized)
+	}
+	if j.Issuer != "" && claims.Issuer != j.Issuer {
+		return errors.WithStack(domain.ErrUnauthorized)
+	}
+	if j.Audience != "" && !claims.Audience.contains(j.Audience) {
+		return errors.WithStack(domain.ErrUnauthorized)
+	}
+
+	return nil
+}
+
+func decodeJWTSegment(segment string, out interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return json.Unmarshal(b, out)
+}
+
+// parseRSAPublicKey PEM形式の公開鍵または証明書からRSA公開鍵を取り出す
+func parseRSAPublicKey(pemString string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("failed to decode RS256 public key PEM")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("certificate does not contain an RSA public key")
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse RSA public key")
+		}
+		return key, nil
+	default:
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse public key")
+		}
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an RSA key")
+		}
+		return key, nil
+	}
+}
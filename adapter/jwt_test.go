@@ -0,0 +1,178 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func encodeJWTSegment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signHS256(t *testing.T, secret string, header, claims map[string]interface{}) string {
+	input := encodeJWTSegment(t, header) + "." + encodeJWTSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	input := encodeJWTSegment(t, map[string]interface{}{"alg": "RS256", "typ": "JWT"}) + "." + encodeJWTSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// TestJWTVerifier_HS256 HS256で署名されたトークンの検証
+func TestJWTVerifier_HS256(t *testing.T) {
+	secret := "secret"
+	verifier, err := NewJWTVerifier(secret, "", "issuer", "audience")
+	assert.NoError(t, err)
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	cases := []struct {
+		Token    string
+		Expected *domain.Principal
+	}{
+		// 正常なトークン
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "exp": exp, "iss": "issuer", "aud": "audience", "roles": []string{"admin"},
+			}),
+			Expected: domain.NewPrincipal("1", []string{"admin"}),
+		},
+		// audが配列の場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "exp": exp, "iss": "issuer", "aud": []string{"other", "audience"},
+			}),
+			Expected: domain.NewPrincipal("1", nil),
+		},
+		// 署名鍵が異なる場合
+		{
+			Token: signHS256(t, "wrong", hs256, map[string]interface{}{
+				"sub": "1", "exp": exp, "iss": "issuer", "aud": "audience",
+			}),
+		},
+		// 有効期限切れの場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "exp": time.Now().Add(-time.Minute).Unix(), "iss": "issuer", "aud": "audience",
+			}),
+		},
+		// 有効期限がない場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "iss": "issuer", "aud": "audience",
+			}),
+		},
+		// 有効期間の開始前の場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "exp": exp, "nbf": time.Now().Add(time.Minute).Unix(), "iss": "issuer", "aud": "audience",
+			}),
+		},
+		// 発行者が異なる場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "exp": exp, "iss": "other", "aud": "audience",
+			}),
+		},
+		// 対象者が異なる場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"sub": "1", "exp": exp, "iss": "issuer", "aud": "other",
+			}),
+		},
+		// subjectがない場合
+		{
+			Token: signHS256(t, secret, hs256, map[string]interface{}{
+				"exp": exp, "iss": "issuer", "aud": "audience",
+			}),
+		},
+		// 署名なしの場合
+		{
+			Token: encodeJWTSegment(t, map[string]interface{}{"alg": "none"}) + "." +
+				encodeJWTSegment(t, map[string]interface{}{"sub": "1", "exp": exp, "iss": "issuer", "aud": "audience"}) + ".",
+		},
+		// RS256の鍵が設定されていない場合
+		{
+			Token: signHS256(t, secret, map[string]interface{}{"alg": "RS256"}, map[string]interface{}{
+				"sub": "1", "exp": exp, "iss": "issuer", "aud": "audience",
+			}),
+		},
+		// JWTの形式ではない場合
+		{
+			Token: "invalid",
+		},
+	}
+
+	for i, c := range cases {
+		msg := fmt.Sprintf("Case:%d", i+1)
+
+		principal, err := verifier.Verify(c.Token)
+		if c.Expected == nil {
+			if assert.Error(t, err, msg) {
+				assert.Equal(t, domain.ErrUnauthorized.Error(), err.Error(), msg)
+			}
+			continue
+		}
+		assert.NoError(t, err, msg)
+		assert.Equal(t, c.Expected, principal, msg)
+	}
+}
+
+// TestJWTVerifier_RS256 RS256で署名されたトークンの検証
+func TestJWTVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	verifier, err := NewJWTVerifier("", publicKeyPEM, "", "")
+	assert.NoError(t, err)
+	claims := map[string]interface{}{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	// 正常なトークン
+	principal, err := verifier.Verify(signRS256(t, key, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, "1", principal.Subject)
+
+	// 別の鍵で署名されたトークン
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(signRS256(t, otherKey, claims))
+	if assert.Error(t, err) {
+		assert.Equal(t, domain.ErrUnauthorized.Error(), err.Error())
+	}
+
+	// HS256の鍵が設定されていない場合は、公開鍵を共通鍵として使ったトークンを受け付けない
+	_, err = verifier.Verify(signHS256(t, publicKeyPEM, map[string]interface{}{"alg": "HS256"}, claims))
+	if assert.Error(t, err) {
+		assert.Equal(t, domain.ErrUnauthorized.Error(), err.Error())
+	}
+}
+
+// TestNewJWTVerifier_NoKey どちらの鍵も設定されていない場合は生成できないこと
+func TestNewJWTVerifier_NoKey(t *testing.T) {
+	_, err := NewJWTVerifier("", "", "issuer", "audience")
+	assert.Error(t, err)
+}
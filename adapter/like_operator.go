@@ -0,0 +1,304 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"github.com/guregu/dynamo"
+	"github.com/memememomo/nomof"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// LikeOperator いいねを操作する構造体
+type LikeOperator struct {
+	Client *ResourceTableOperator
+	Mapper *DynamoModelMapper
+	Cursor *PagingCursor
+}
+
+// Like いいねする。すでにいいねしている場合は何もしない。
+// マイクロポストかいいねするユーザーが存在しない場合はErrNotFoundを返す
+func (l *LikeOperator) Like(ctx context.Context, likeModel *domain.LikeModel) error {
+	conn, err := l.Client.ConnectDB()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	table, err := l.Client.ConnectTable()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	like := NewLikeResource(likeModel, l.Mapper)
+	like.SetKeys()
+	like.CreatedAt = time.Now()
+	like.UpdatedAt = like.CreatedAt
+
+	// いいねした日時と件数が変わらないよう、すでにいいねしている場合はトランザクション全体を失敗させる
+	fb := nomof.NewBuilder()
+	fb.AttributeNotExists(l.Mapper.PKName)
+	put := table.Put(like).If(fb.JoinAnd(), fb.Arg...)
+
+	// マイクロポストが存在しない場合は条件チェックで失敗する
+	count, err := l.buildQueryUpdateLikeCount(likeModel.MicropostID, 1)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 同時にユーザーが削除された場合に備えて、同じトランザクション内でユーザーの存在を確認する
+	user, err := l.Mapper.BuildQueryCheckExists(NewUserResource(&domain.UserModel{ID: likeModel.UserID}, l.Mapper))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := l.Mapper.startOperation(ctx, "LikeOperator.Like")
+	err = conn.WriteTx().Put(put).Update(count).Check(user).ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
+	if err != nil {
+		if isTransactionConditionFailed(err, 1) || isTransactionConditionFailed(err, 2) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
+		if isTransactionConditionFailed(err, 0) {
+			return nil
+		}
+		return errors.WithStack(translateDynamoError(err))
+	}
+
+	return nil
+}
+
+// Unlike いいねを取り消す。いいねしていない場合は何もしない。マイクロポストが存在しない場合はErrNotFoundを返す
+func (l *LikeOperator) Unlike(ctx context.Context, likeModel *domain.LikeModel) error {
+	conn, err := l.Client.ConnectDB()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	like := NewLikeResource(likeModel, l.Mapper)
+	like.SetKeys()
+
+	// いいねしていない場合に件数を減らさないよう、トランザクション全体を失敗させる
+	query, count, err := l.buildQueryDeleteLike(like)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := l.Mapper.startOperation(ctx, "LikeOperator.Unlike")
+	err = conn.WriteTx().Delete(query).Update(count).ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
+	if err != nil {
+		if isTransactionConditionFailed(err, 1) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
+		if isTransactionConditionFailed(err, 0) {
+			return nil
+		}
+		return errors.WithStack(translateDynamoError(err))
+	}
+
+	return nil
+}
+
+// GetLikes 指定されたマイクロポストへのいいねを、いいねしたユーザーのIDの順に取得する
+func (l *LikeOperator) GetLikes(ctx context.Context, micropostID uint64, paging *domain.Paging) ([]*domain.LikeModel, string, error) {
+	table, err := l.Client.ConnectTable()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	// マイクロポスト本体と同じパーティションのため、いいねのソートキーに絞って取得する
+	partitionKey := l.Mapper.GetMicropostPartitionKey(micropostID)
+	prefix := l.Mapper.GetLikeSortKeyPrefix()
+	scope := partitionKey + "-" + prefix
+	query := table.
+		Get(l.Mapper.PKName, partitionKey).
+		Range(l.Mapper.SKName, dynamo.BeginsWith, prefix).
+		Order(dynamo.Ascending)
+
+	if paging != nil && paging.Cursor != "" {
+		key, err := l.Cursor.Decode(scope, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		query = query.StartFrom(key)
+	}
+
+	// 次のページが存在するかを判定するため、1件多く取得する
+	if paging != nil && paging.Limit > 0 {
+		query = query.Limit(int64(paging.Limit + 1))
+	}
+
+	var likeResource []LikeResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := l.Mapper.startOperation(ctx, "LikeOperator.GetLikes")
+	err = query.ConsumedCapacity(&cc).AllWithContext(opCtx, &likeResource)
+	end(&cc, err)
+	if err != nil {
+		return nil, "", errors.WithStack(translateDynamoError(err))
+	}
+
+	var nextCursor string
+	if paging != nil && paging.Limit > 0 && len(likeResource) > paging.Limit {
+		likeResource = likeResource[:paging.Limit]
+		last := likeResource[len(likeResource)-1]
+		nextCursor, err = l.Cursor.Encode(scope, map[string]string{
+			l.Mapper.PKName: last.ResourceSchema.PK,
+			l.Mapper.SKName: last.ResourceSchema.SK,
+		})
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+	}
+
+	var likes = make([]*domain.LikeModel, len(likeResource))
+	for i := range likeResource {
+		likes[i] = likeResource[i].Model()
+	}
+
+	return likes, nextCursor, nil
+}
+
+// DeleteLikesByUserID 指定されたユーザーのいいねを全て取り消し、取り消した件数を返す。
+// いいねの削除とマイクロポストの件数の更新を合わせて、トランザクションの上限件数ごとに分割して取り消す
+func (l *LikeOperator) DeleteLikesByUserID(ctx context.Context, userID uint64) (int, error) {
+	conn, err := l.Client.ConnectDB()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	table, err := l.Client.ConnectTable()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	count := 0
+	var startKey dynamo.PagingKey
+	for {
+		// 1件のいいねにつき、いいねの削除と件数の更新の2件を操作する
+		query := table.
+			Get("GSI1PK", l.Mapper.GetUserLikesPartitionKey(userID)).
+			Index(GSI1Name).
+			Limit(maxTransactionItems / 2)
+		if startKey != nil {
+			query = query.StartFrom(startKey)
+		}
+
+		var likeResource []LikeResource
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := l.Mapper.startOperation(ctx, "LikeOperator.DeleteLikesByUserID")
+		startKey, err = query.ConsumedCapacity(&cc).AllWithLastEvaluatedKeyContext(opCtx, &likeResource)
+		end(&cc, err)
+		if err != nil {
+			return count, errors.WithStack(translateDynamoError(err))
+		}
+
+		n, err := l.deleteLikeBatch(ctx, conn, likeResource)
+		count += n
+		if err != nil {
+			return count, errors.WithStack(err)
+		}
+
+		if startKey == nil {
+			return count, nil
+		}
+	}
+}
+
+// deleteLikeBatch いいねをまとめて1回のトランザクションで取り消し、取り消した件数を返す。
+// 同時に取り消されたいいねは除き、マイクロポストが同時に削除された場合は件数を減らさずにいいねだけを削除する
+func (l *LikeOperator) deleteLikeBatch(ctx context.Context, conn *dynamo.DB, likeResource []LikeResource) (int, error) {
+	type pendingLike struct {
+		like *LikeResource
+		// withCount マイクロポストの件数も減らす場合はtrue
+		withCount bool
+	}
+
+	pending := make([]pendingLike, len(likeResource))
+	for i := range likeResource {
+		likeResource[i].Mapper = l.Mapper
+		pending[i] = pendingLike{like: &likeResource[i], withCount: true}
+	}
+
+	for len(pending) > 0 {
+		tx := conn.WriteTx()
+		// いいねごとに、トランザクション内での削除と件数の更新の位置を覚えておく
+		deleteIndexes := make([]int, len(pending))
+		countIndexes := make([]int, len(pending))
+		n := 0
+		for i, p := range pending {
+			likeDelete, likeCount, err := l.buildQueryDeleteLike(p.like)
+			if err != nil {
+				return 0, errors.WithStack(err)
+			}
+			tx.Delete(likeDelete)
+			deleteIndexes[i] = n
+			n++
+
+			countIndexes[i] = -1
+			if p.withCount {
+				tx.Update(likeCount)
+				countIndexes[i] = n
+				n++
+			}
+		}
+
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := l.Mapper.startOperation(ctx, "LikeOperator.DeleteLikesByUserID")
+		err := tx.ConsumedCapacity(&cc).RunWithContext(opCtx)
+		end(&cc, err)
+		if err == nil {
+			return len(pending), nil
+		}
+
+		// 条件チェックで失敗したいいねだけを扱いを変えて、取り消し直す
+		retry := false
+		for i := len(pending) - 1; i >= 0; i-- {
+			switch {
+			case isTransactionConditionFailed(err, deleteIndexes[i]):
+				// 同時に取り消された場合
+				pending = append(pending[:i], pending[i+1:]...)
+				retry = true
+			case countIndexes[i] >= 0 && isTransactionConditionFailed(err, countIndexes[i]):
+				// マイクロポストが同時に削除された場合
+				pending[i].withCount = false
+				retry = true
+			}
+		}
+		if !retry {
+			return 0, errors.WithStack(translateDynamoError(err))
+		}
+	}
+
+	return 0, nil
+}
+
+// buildQueryDeleteLike いいねを削除するクエリと、マイクロポストのいいねの件数を減らすクエリを生成する。
+// いいねが存在しない場合は削除するクエリが条件チェックで失敗する
+func (l *LikeOperator) buildQueryDeleteLike(like *LikeResource) (*dynamo.Delete, *dynamo.Update, error) {
+	table, err := l.Client.ConnectTable()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	fb := nomof.NewBuilder()
+	fb.AttributeExists(l.Mapper.PKName)
+	query := table.
+		Delete(l.Mapper.PKName, like.ResourceSchema.PK).
+		Range(l.Mapper.SKName, like.ResourceSchema.SK).
+		If(fb.JoinAnd(), fb.Arg...)
+
+	count, err := l.buildQueryUpdateLikeCount(like.MicropostID, -1)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	return query, count, nil
+}
+
+// buildQueryUpdateLikeCount マイクロポストのいいねの件数を増減するクエリを生成する
+func (l *LikeOperator) buildQueryUpdateLikeCount(micropostID uint64, delta int) (*dynamo.Update, error) {
+	micropostResource := NewMicropostResource(&domain.MicropostModel{ID: micropostID}, l.Mapper)
+	return l.Mapper.BuildQueryIncrement(micropostResource, micropostLikeCountAttribute, delta)
+}
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"fmt"
+)
+
+// LikeResource いいねのDynamoDB上のデータ構造を表した構造体。
+// PKはいいねされたマイクロポストと同じにしてマイクロポストの隣に保存し、GSI1PKはいいねしたユーザーごとにまとめるため、
+// マイクロポストへのいいね一覧はテーブルから、ユーザーのいいね一覧はGSI1から取得できる
+type LikeResource struct {
+	ResourceSchema
+	DynamoCreatedUpdated
+	domain.LikeModel
+	Mapper *DynamoModelMapper `dynamo:"-"`
+}
+
+func NewLikeResource(likeModel *domain.LikeModel, mapper *DynamoModelMapper) *LikeResource {
+	return &LikeResource{
+		LikeModel: *likeModel,
+		Mapper:    mapper,
+	}
+}
+
+// Model ドメインモデルを返す
+func (l *LikeResource) Model() *domain.LikeModel {
+	return &l.LikeModel
+}
+
+// SetKeys テーブルとGSI1のキーを設定する
+func (l *LikeResource) SetKeys() {
+	l.ResourceSchema.PK = l.Mapper.GetMicropostPartitionKey(l.MicropostID)
+	l.ResourceSchema.SK = fmt.Sprintf("%s%011d", l.Mapper.GetLikeSortKeyPrefix(), l.UserID)
+	l.ResourceSchema.GSI1PK = l.Mapper.GetUserLikesPartitionKey(l.UserID)
+	l.ResourceSchema.GSI1SK = fmt.Sprintf("%011d", l.MicropostID)
+}
@@ -0,0 +1,201 @@
+// Package logging CloudWatch Logsで検索しやすいように、1行に1つのJSONとしてログを出力する
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level ログの重要度
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// ParseLevel 文字列から重要度を取得する。空文字列の場合は LevelInfo を返す
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "", "INFO":
+		return LevelInfo, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	default:
+		return LevelInfo, errors.Errorf("unknown log level: %s", s)
+	}
+}
+
+// Logger JSON形式でログを出力する。
+// ログの項目はキーと値を交互に並べて指定する。例: logger.Info("message", "user_id", 1, "status", 200)
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	fields []interface{}
+	Now    func() time.Time
+}
+
+// New Logger インスタンスを生成。level未満の重要度のログは出力しない
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+		Now:   time.Now,
+	}
+}
+
+// With 全てのログに指定した項目を追加するLoggerを返す
+func (l *Logger) With(args ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}{}, l.fields...), args...)
+	return &child
+}
+
+// Enabled 指定した重要度のログが出力されるかどうか
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug デバッグ用のログを出力する
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.Log(LevelDebug, msg, args...)
+}
+
+// Info ログを出力する
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.Log(LevelInfo, msg, args...)
+}
+
+// Warn 警告のログを出力する
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Log(LevelWarn, msg, args...)
+}
+
+// Error エラーのログを出力する
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.Log(LevelError, msg, args...)
+}
+
+// Log 重要度を指定してログを出力する
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeField(&buf, "time", l.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteByte(',')
+	writeField(&buf, "level", level.String())
+	buf.WriteByte(',')
+	writeField(&buf, "msg", msg)
+
+	fields := append(append([]interface{}{}, l.fields...), args...)
+	for i := 0; i < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(fields[i])
+		}
+		// 値が指定されていないキーはそのまま出力して、指定漏れに気づけるようにする
+		var value interface{} = "!MISSING"
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+		buf.WriteByte(',')
+		writeField(&buf, key, value)
+	}
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.out.Write(buf.Bytes())
+}
+
+// writeField "key":value の形式で書き込む
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+
+	if err, ok := value.(error); ok {
+		value = NewErrorValue(err)
+	}
+
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	buf.Write(v)
+}
+
+// ErrorValue エラーをログに出力する際の形式
+type ErrorValue struct {
+	Message string   `json:"message"`
+	Stack   []string `json:"stack,omitempty"`
+}
+
+// NewErrorValue エラーメッセージと、pkg/errorsで記録されたスタックトレースを取り出す。
+// スタックトレースが複数記録されている場合は、エラーが発生した箇所に最も近いものを使う
+func NewErrorValue(err error) *ErrorValue {
+	type stackTracer interface {
+		StackTrace() errors.StackTrace
+	}
+
+	value := &ErrorValue{Message: err.Error()}
+
+	var trace errors.StackTrace
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if st, ok := e.(stackTracer); ok {
+			trace = st.StackTrace()
+		}
+	}
+
+	for _, frame := range trace {
+		value.Stack = append(value.Stack, strings.Replace(fmt.Sprintf("%+v", frame), "\n\t", " ", 1))
+	}
+
+	return value
+}
+
+type contextKey struct{}
+
+// NewContext Loggerを保持したコンテキストを返す
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext コンテキストに保持されているLoggerを返す。保持されていない場合はfallbackを返す
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return logger
+	}
+	return fallback
+}
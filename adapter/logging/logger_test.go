@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogger(buf *bytes.Buffer, level Level) *Logger {
+	logger := New(buf, level)
+	logger.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return logger
+}
+
+// TestLogger_Log 1行に1つのJSONとして出力されること
+func TestLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, LevelInfo).With("request_id", "req-1")
+
+	logger.Info("request", "status", 200, "route", "/v1/users")
+
+	assert.Equal(t,
+		`{"time":"2020-01-02T03:04:05Z","level":"INFO","msg":"request","request_id":"req-1","status":200,"route":"/v1/users"}`+"\n",
+		buf.String())
+}
+
+// TestLogger_Level 指定した重要度未満のログは出力されないこと
+func TestLogger_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, LevelWarn)
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"level":"WARN"`)
+	assert.Contains(t, lines[1], `"level":"ERROR"`)
+}
+
+// TestLogger_Error エラーはメッセージとスタックトレースに分けて出力されること
+func TestLogger_Error(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, LevelInfo)
+
+	err := errors.WithStack(errors.Wrap(errors.New("cause"), "failed"))
+	logger.Error("request", "error", err)
+
+	var line struct {
+		Error ErrorValue `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "failed: cause", line.Error.Message)
+	require.NotEmpty(t, line.Error.Stack)
+	assert.Contains(t, line.Error.Stack[0], "TestLogger_Error")
+	assert.Contains(t, line.Error.Stack[0], "logger_test.go:")
+}
+
+// TestLogger_MissingValue 値が指定されていないキーも出力されること
+func TestLogger_MissingValue(t *testing.T) {
+	var buf bytes.Buffer
+	newTestLogger(&buf, LevelInfo).Info("msg", "key")
+
+	assert.Contains(t, buf.String(), `"key":"!MISSING"`)
+}
+
+// TestParseLevel 文字列から重要度への変換
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected Level
+		Err      bool
+	}{
+		{Value: "", Expected: LevelInfo},
+		{Value: "debug", Expected: LevelDebug},
+		{Value: "WARNING", Expected: LevelWarn},
+		{Value: "ERROR", Expected: LevelError},
+		{Value: "verbose", Expected: LevelInfo, Err: true},
+	}
+
+	for _, c := range cases {
+		level, err := ParseLevel(c.Value)
+		assert.Equal(t, c.Expected, level, c.Value)
+		assert.Equal(t, c.Err, err != nil, c.Value)
+	}
+}
+
+// TestFromContext コンテキストに保持したLoggerを取得できること
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo)
+	fallback := New(&buf, LevelError)
+
+	assert.Equal(t, fallback, FromContext(context.Background(), fallback))
+	assert.Equal(t, logger, FromContext(NewContext(context.Background(), logger), fallback))
+}
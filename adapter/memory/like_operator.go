@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// LikeOperator メモリ上のいいねを操作する構造体
+type LikeOperator struct {
+	Store *Store
+}
+
+// NewLikeOperator LikeOperator インスタンスを生成
+func NewLikeOperator(store *Store) *LikeOperator {
+	return &LikeOperator{Store: store}
+}
+
+// Like いいねする。すでにいいねしている場合は何もしない。
+// マイクロポストかいいねするユーザーが存在しない場合はErrNotFoundを返す
+func (l *LikeOperator) Like(ctx context.Context, like *domain.LikeModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	micropost, ok := l.Store.microposts[like.MicropostID]
+	if !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+	if _, ok := l.Store.users[like.UserID]; !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+
+	if l.Store.likes[*like] {
+		return nil
+	}
+	l.Store.likes[*like] = true
+	micropost.LikeCount++
+
+	return nil
+}
+
+// Unlike いいねを取り消す。いいねしていない場合は何もしない。マイクロポストが存在しない場合はErrNotFoundを返す
+func (l *LikeOperator) Unlike(ctx context.Context, like *domain.LikeModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	micropost, ok := l.Store.microposts[like.MicropostID]
+	if !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+
+	if !l.Store.likes[*like] {
+		return nil
+	}
+	delete(l.Store.likes, *like)
+	micropost.LikeCount--
+
+	return nil
+}
+
+// GetLikes 指定されたマイクロポストへのいいねを、いいねしたユーザーのIDの順に取得する
+func (l *LikeOperator) GetLikes(ctx context.Context, micropostID uint64, paging *domain.Paging) ([]*domain.LikeModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	var ids []uint64
+	for like := range l.Store.likes {
+		if like.MicropostID == micropostID {
+			ids = append(ids, like.UserID)
+		}
+	}
+
+	ids, nextCursor, err := paginate(fmt.Sprintf("likes-%d", micropostID), sortedIDs(ids), paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	likes := make([]*domain.LikeModel, len(ids))
+	for i, id := range ids {
+		likes[i] = domain.NewLikeModel(micropostID, id)
+	}
+
+	return likes, nextCursor, nil
+}
+
+// DeleteLikesByUserID 指定されたユーザーのいいねを全て取り消し、取り消した件数を返す
+func (l *LikeOperator) DeleteLikesByUserID(ctx context.Context, userID uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	count := 0
+	for like := range l.Store.likes {
+		if like.UserID != userID {
+			continue
+		}
+		delete(l.Store.likes, like)
+		if micropost, ok := l.Store.microposts[like.MicropostID]; ok {
+			micropost.LikeCount--
+		}
+		count++
+	}
+
+	return count, nil
+}
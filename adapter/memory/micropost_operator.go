@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// MicropostOperator メモリ上のマイクロポストを操作する構造体
+type MicropostOperator struct {
+	Store *Store
+}
+
+// NewMicropostOperator MicropostOperator インスタンスを生成
+func NewMicropostOperator(store *Store) *MicropostOperator {
+	return &MicropostOperator{Store: store}
+}
+
+// CreateMicropost 新規作成する。投稿者のユーザーまたは返信先のマイクロポストが存在しない場合はErrNotFoundを返す
+func (m *MicropostOperator) CreateMicropost(ctx context.Context, micropostModel *domain.MicropostModel) (*domain.MicropostModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	user, ok := m.Store.users[micropostModel.UserID]
+	if !ok {
+		return nil, errors.WithStack(domain.ErrNotFound)
+	}
+
+	var inReplyTo *domain.MicropostModel
+	if micropostModel.InReplyToID != 0 {
+		inReplyTo, ok = m.Store.microposts[micropostModel.InReplyToID]
+		if !ok {
+			return nil, errors.WithStack(domain.ErrNotFound)
+		}
+	}
+
+	m.Store.micropostSeq++
+	micropost := copyMicropost(micropostModel)
+	micropost.ID = m.Store.micropostSeq
+	micropost.LikeCount = 0
+	micropost.ReplyCount = 0
+	micropost.Version = 1
+
+	postedAt := m.Store.Now()
+	m.Store.microposts[micropost.ID] = micropost
+	m.Store.postedAt[micropost.ID] = postedAt
+
+	// 投稿者の集計値を更新する。投稿日時は戻さない
+	user.MicropostsCount++
+	if user.LastPostedAt == nil || postedAt.After(*user.LastPostedAt) {
+		user.LastPostedAt = &postedAt
+	}
+	if user.FirstPostedAt == nil || postedAt.Before(*user.FirstPostedAt) {
+		user.FirstPostedAt = &postedAt
+	}
+
+	if inReplyTo != nil {
+		inReplyTo.ReplyCount++
+	}
+
+	return copyMicropost(micropost), nil
+}
+
+// UpdateMicropost 更新する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ更新する
+func (m *MicropostOperator) UpdateMicropost(ctx context.Context, micropostModel *domain.MicropostModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	micropost, ok := m.Store.microposts[micropostModel.ID]
+	if !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+	if micropostModel.Version != 0 && micropostModel.Version != micropost.Version {
+		return errors.WithStack(domain.ErrPreconditionFailed)
+	}
+
+	micropost.Content = micropostModel.Content
+	micropost.Version++
+
+	return nil
+}
+
+// GetMicropostByID IDでマイクロポストを取得する
+func (m *MicropostOperator) GetMicropostByID(ctx context.Context, id uint64) (*domain.MicropostModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	micropost, ok := m.Store.microposts[id]
+	if !ok {
+		return nil, errors.WithStack(domain.ErrNotFound)
+	}
+	return copyMicropost(micropost), nil
+}
+
+// GetMicropostsByUserID 指定されたユーザーIDに紐づいているマイクロポスト一覧を作成順に取得する
+func (m *MicropostOperator) GetMicropostsByUserID(ctx context.Context, userID uint64, paging *domain.Paging) ([]*domain.MicropostModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := m.micropostIDsByUserID(userID)
+
+	ids, nextCursor, err := paginate(fmt.Sprintf("microposts-%d", userID), ids, paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	microposts := make([]*domain.MicropostModel, len(ids))
+	for i, id := range ids {
+		microposts[i] = copyMicropost(m.Store.microposts[id])
+	}
+
+	return microposts, nextCursor, nil
+}
+
+// GetPublicTimeline 全てのユーザーのマイクロポストを新しい順に取得する。
+// sinceIDより大きくmaxID以下のIDのマイクロポストを対象とし、0の場合はその条件を指定しない
+func (m *MicropostOperator) GetPublicTimeline(ctx context.Context, paging *domain.Paging, sinceID, maxID uint64) ([]*domain.MicropostModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var ids []uint64
+	for id := range m.Store.microposts {
+		if id > sinceID && (maxID == 0 || id <= maxID) {
+			ids = append(ids, id)
+		}
+	}
+
+	ids, nextCursor, err := paginateDesc("timeline", sortedIDsDesc(ids), paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	microposts := make([]*domain.MicropostModel, len(ids))
+	for i, id := range ids {
+		microposts[i] = copyMicropost(m.Store.microposts[id])
+	}
+
+	return microposts, nextCursor, nil
+}
+
+// GetReplies 指定されたマイクロポストへの返信を古い順に取得する。返信先が削除されていても返信は取得できる
+func (m *MicropostOperator) GetReplies(ctx context.Context, micropostID uint64, paging *domain.Paging) ([]*domain.MicropostModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	var ids []uint64
+	for id, micropost := range m.Store.microposts {
+		if micropost.InReplyToID == micropostID {
+			ids = append(ids, id)
+		}
+	}
+
+	ids, nextCursor, err := paginate(fmt.Sprintf("replies-%d", micropostID), sortedIDs(ids), paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	microposts := make([]*domain.MicropostModel, len(ids))
+	for i, id := range ids {
+		microposts[i] = copyMicropost(m.Store.microposts[id])
+	}
+
+	return microposts, nextCursor, nil
+}
+
+// DeleteMicropost 指定されたマイクロポストを削除する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ削除する
+func (m *MicropostOperator) DeleteMicropost(ctx context.Context, micropostModel *domain.MicropostModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	micropost, ok := m.Store.microposts[micropostModel.ID]
+	if !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+	if micropostModel.Version != 0 && micropostModel.Version != micropost.Version {
+		return errors.WithStack(domain.ErrPreconditionFailed)
+	}
+
+	m.deleteMicropost(micropost)
+
+	return nil
+}
+
+// DeleteMicropostsByUserID 指定されたユーザーIDに紐づいているマイクロポストを全て削除し、削除した件数を返す
+func (m *MicropostOperator) DeleteMicropostsByUserID(ctx context.Context, userID uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ids := m.micropostIDsByUserID(userID)
+	for _, id := range ids {
+		m.deleteMicropost(m.Store.microposts[id])
+	}
+
+	return len(ids), nil
+}
+
+// deleteMicropost マイクロポストとそのいいねを削除し、投稿者の件数と返信先の返信数を減らす。
+// マイクロポストへの返信は削除しない。呼び出し側でロックを取得しておくこと
+func (m *MicropostOperator) deleteMicropost(micropost *domain.MicropostModel) {
+	delete(m.Store.microposts, micropost.ID)
+	delete(m.Store.postedAt, micropost.ID)
+
+	for like := range m.Store.likes {
+		if like.MicropostID == micropost.ID {
+			delete(m.Store.likes, like)
+		}
+	}
+
+	if user, ok := m.Store.users[micropost.UserID]; ok {
+		user.MicropostsCount--
+	}
+
+	if micropost.InReplyToID != 0 {
+		if inReplyTo, ok := m.Store.microposts[micropost.InReplyToID]; ok {
+			inReplyTo.ReplyCount--
+		}
+	}
+}
+
+// micropostIDsByUserID ユーザーのマイクロポストのIDを昇順に返す。呼び出し側でロックを取得しておくこと
+func (m *MicropostOperator) micropostIDsByUserID(userID uint64) []uint64 {
+	var ids []uint64
+	for id, micropost := range m.Store.microposts {
+		if micropost.UserID == userID {
+			ids = append(ids, id)
+		}
+	}
+	return sortedIDs(ids)
+}
+
+func copyMicropost(micropost *domain.MicropostModel) *domain.MicropostModel {
+	copied := *micropost
+	return &copied
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"clean-serverless-book-sample-v2/domain/repositorytest"
+	"testing"
+	"time"
+)
+
+// TestRepositoryConformance メモリ上のリポジトリがリポジトリの振る舞いを満たしているかをチェック
+func TestRepositoryConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) (*repositorytest.Repositories, func()) {
+		store := NewStore()
+		return &repositorytest.Repositories{
+			Users:         NewUserOperator(store),
+			Microposts:    NewMicropostOperator(store),
+			Relationships: NewRelationshipOperator(store),
+			Likes:         NewLikeOperator(store),
+			SetNow:        func(now func() time.Time) { store.Now = now },
+		}, func() {}
+	})
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// RelationshipOperator メモリ上のフォロー関係を操作する構造体
+type RelationshipOperator struct {
+	Store *Store
+}
+
+// NewRelationshipOperator RelationshipOperator インスタンスを生成
+func NewRelationshipOperator(store *Store) *RelationshipOperator {
+	return &RelationshipOperator{Store: store}
+}
+
+// Follow フォローする。すでにフォローしている場合は何もしない。
+// フォローするユーザーかフォロー対象のユーザーが存在しない場合はErrNotFoundを返す
+func (r *RelationshipOperator) Follow(ctx context.Context, relationship *domain.RelationshipModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	if _, ok := r.Store.users[relationship.FollowerID]; !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+	if _, ok := r.Store.users[relationship.FollowedID]; !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+
+	r.Store.relationships[*relationship] = true
+
+	return nil
+}
+
+// Unfollow フォローを解除する。フォローしていない場合はErrNotFoundを返す
+func (r *RelationshipOperator) Unfollow(ctx context.Context, relationship *domain.RelationshipModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	if !r.Store.relationships[*relationship] {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+	delete(r.Store.relationships, *relationship)
+
+	return nil
+}
+
+// IsFollowing followerIDのユーザーがfollowedIDのユーザーをフォローしているかどうか
+func (r *RelationshipOperator) IsFollowing(ctx context.Context, followerID, followedID uint64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	return r.Store.relationships[domain.RelationshipModel{FollowerID: followerID, FollowedID: followedID}], nil
+}
+
+// GetFollowers 指定されたユーザーのフォロワーをユーザーIDの順に取得する
+func (r *RelationshipOperator) GetFollowers(ctx context.Context, userID uint64, paging *domain.Paging) ([]*domain.RelationshipModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	var ids []uint64
+	for relationship := range r.Store.relationships {
+		if relationship.FollowedID == userID {
+			ids = append(ids, relationship.FollowerID)
+		}
+	}
+
+	ids, nextCursor, err := paginate(fmt.Sprintf("followers-%d", userID), sortedIDs(ids), paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	relationships := make([]*domain.RelationshipModel, len(ids))
+	for i, id := range ids {
+		relationships[i] = domain.NewRelationshipModel(id, userID)
+	}
+
+	return relationships, nextCursor, nil
+}
+
+// GetFollowing 指定されたユーザーがフォローしているユーザーをユーザーIDの順に取得する
+func (r *RelationshipOperator) GetFollowing(ctx context.Context, userID uint64, paging *domain.Paging) ([]*domain.RelationshipModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	var ids []uint64
+	for relationship := range r.Store.relationships {
+		if relationship.FollowerID == userID {
+			ids = append(ids, relationship.FollowedID)
+		}
+	}
+
+	ids, nextCursor, err := paginate(fmt.Sprintf("following-%d", userID), sortedIDs(ids), paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	relationships := make([]*domain.RelationshipModel, len(ids))
+	for i, id := range ids {
+		relationships[i] = domain.NewRelationshipModel(userID, id)
+	}
+
+	return relationships, nextCursor, nil
+}
+
+// DeleteRelationshipsByUserID 指定されたユーザーがフォローしている関係とフォローされている関係を全て削除し、削除した件数を返す
+func (r *RelationshipOperator) DeleteRelationshipsByUserID(ctx context.Context, userID uint64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	count := 0
+	for relationship := range r.Store.relationships {
+		if relationship.FollowerID == userID || relationship.FollowedID == userID {
+			delete(r.Store.relationships, relationship)
+			count++
+		}
+	}
+
+	return count, nil
+}
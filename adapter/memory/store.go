@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"encoding/base64"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store ユーザー・マイクロポスト・フォロー関係・いいねをメモリ上に保持する。
+// DynamoDBを使わずにテストやローカル実行を行うためのもので、プロセスが終了するとデータは失われる
+type Store struct {
+	mu         sync.Mutex
+	users      map[uint64]*domain.UserModel
+	emails     map[string]uint64
+	microposts map[uint64]*domain.MicropostModel
+	// postedAt マイクロポストごとの投稿日時。ユーザーの集計値を計算し直す際に使う
+	postedAt map[uint64]time.Time
+	// relationships フォローしているユーザーのIDとフォローされているユーザーのIDの組み合わせ
+	relationships map[domain.RelationshipModel]bool
+	// likes いいねされたマイクロポストのIDといいねしたユーザーのIDの組み合わせ
+	likes        map[domain.LikeModel]bool
+	userSeq      uint64
+	micropostSeq uint64
+	// Now 投稿日時に使う現在時刻
+	Now func() time.Time
+}
+
+// NewStore Store インスタンスを生成
+func NewStore() *Store {
+	return &Store{
+		users:         map[uint64]*domain.UserModel{},
+		emails:        map[string]uint64{},
+		microposts:    map[uint64]*domain.MicropostModel{},
+		postedAt:      map[uint64]time.Time{},
+		relationships: map[domain.RelationshipModel]bool{},
+		likes:         map[domain.LikeModel]bool{},
+		Now:           time.Now,
+	}
+}
+
+// sortedIDs IDの昇順に並べる。IDは採番順のため、作成日時順と同じ並びになる
+func sortedIDs(ids []uint64) []uint64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// sortedIDsDesc IDの降順に並べる。作成日時の新しい順と同じ並びになる
+func sortedIDsDesc(ids []uint64) []uint64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	return ids
+}
+
+// paginate 昇順に並んだIDからページング条件に合う範囲を取り出し、次のページのカーソルを返す
+func paginate(scope string, ids []uint64, paging *domain.Paging) ([]uint64, string, error) {
+	if paging == nil {
+		return ids, "", nil
+	}
+
+	if paging.Cursor != "" {
+		after, err := decodeCursor(scope, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		start := sort.Search(len(ids), func(i int) bool { return ids[i] > after })
+		ids = ids[start:]
+	}
+
+	if paging.Limit <= 0 || len(ids) <= paging.Limit {
+		return ids, "", nil
+	}
+
+	ids = ids[:paging.Limit]
+	return ids, encodeCursor(scope, ids[len(ids)-1]), nil
+}
+
+// paginateDesc 降順に並んだIDからページング条件に合う範囲を取り出し、次のページのカーソルを返す
+func paginateDesc(scope string, ids []uint64, paging *domain.Paging) ([]uint64, string, error) {
+	if paging == nil {
+		return ids, "", nil
+	}
+
+	if paging.Cursor != "" {
+		before, err := decodeCursor(scope, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		start := sort.Search(len(ids), func(i int) bool { return ids[i] < before })
+		ids = ids[start:]
+	}
+
+	if paging.Limit <= 0 || len(ids) <= paging.Limit {
+		return ids, "", nil
+	}
+
+	ids = ids[:paging.Limit]
+	return ids, encodeCursor(scope, ids[len(ids)-1]), nil
+}
+
+func encodeCursor(scope string, id uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", scope, id)))
+}
+
+func decodeCursor(scope, cursor string) (uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	value := string(b)
+	if !strings.HasPrefix(value, scope+":") {
+		return 0, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(value, scope+":"), 10, 64)
+	if err != nil {
+		return 0, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// userListCursorScope ユーザー一覧のカーソルのスコープ
+const userListCursorScope = "users"
+
+// UserOperator メモリ上のユーザーを操作する構造体
+type UserOperator struct {
+	Store *Store
+}
+
+// NewUserOperator UserOperator インスタンスを生成
+func NewUserOperator(store *Store) *UserOperator {
+	return &UserOperator{Store: store}
+}
+
+// GetUsers ユーザー一覧をIDの降順に取得する。
+// DynamoDBのScanはキーのハッシュ順に返すため、テストで並び順を比べられるよう一定の順に揃える
+func (u *UserOperator) GetUsers(ctx context.Context, paging *domain.Paging) ([]*domain.UserModel, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	ids := make([]uint64, 0, len(u.Store.users))
+	for id := range u.Store.users {
+		ids = append(ids, id)
+	}
+
+	ids, nextCursor, err := paginateDesc(userListCursorScope, sortedIDsDesc(ids), paging)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	users := make([]*domain.UserModel, len(ids))
+	for i, id := range ids {
+		users[i] = copyUser(u.Store.users[id])
+	}
+
+	return users, nextCursor, nil
+}
+
+// GetUserByID IDからユーザー情報を取得する
+func (u *UserOperator) GetUserByID(ctx context.Context, id uint64) (*domain.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	user, ok := u.Store.users[id]
+	if !ok {
+		return nil, errors.WithStack(domain.ErrNotFound)
+	}
+	return copyUser(user), nil
+}
+
+// GetUsersByIDs 指定したIDの順にユーザーをまとめて取得する。存在しないユーザーは結果に含めない
+func (u *UserOperator) GetUsersByIDs(ctx context.Context, ids []uint64) ([]*domain.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	users := make([]*domain.UserModel, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := u.Store.users[id]; ok {
+			users = append(users, copyUser(user))
+		}
+	}
+	return users, nil
+}
+
+// GetUserByEmail メールアドレスからユーザー情報を取得する
+func (u *UserOperator) GetUserByEmail(ctx context.Context, email string) (*domain.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	id, ok := u.Store.emails[email]
+	if !ok {
+		return nil, errors.WithStack(domain.ErrNotFound)
+	}
+	return copyUser(u.Store.users[id]), nil
+}
+
+// CreateUser ユーザーを新規作成する。メールアドレスが登録済みの場合はErrConflictを返す
+func (u *UserOperator) CreateUser(ctx context.Context, userModel *domain.UserModel) (*domain.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	if _, ok := u.Store.emails[userModel.Email]; ok {
+		return nil, errors.WithStack(domain.ErrConflict)
+	}
+
+	u.Store.userSeq++
+	user := copyUser(userModel)
+	user.ID = u.Store.userSeq
+	user.Version = 1
+
+	u.Store.users[user.ID] = user
+	u.Store.emails[user.Email] = user.ID
+
+	return copyUser(user), nil
+}
+
+// UpdateUser ユーザーを更新する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ更新する
+func (u *UserOperator) UpdateUser(ctx context.Context, newUserModel *domain.UserModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	user, ok := u.Store.users[newUserModel.ID]
+	if !ok {
+		return errors.WithStack(domain.ErrNotFound)
+	}
+	if newUserModel.Version != 0 && newUserModel.Version != user.Version {
+		return errors.WithStack(domain.ErrPreconditionFailed)
+	}
+	if id, ok := u.Store.emails[newUserModel.Email]; ok && id != user.ID {
+		return errors.WithStack(domain.ErrConflict)
+	}
+
+	delete(u.Store.emails, user.Email)
+	user.Name = newUserModel.Name
+	user.Email = newUserModel.Email
+	user.Version++
+	u.Store.emails[user.Email] = user.ID
+
+	return nil
+}
+
+// DeleteUser ユーザー情報を削除する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ削除する
+func (u *UserOperator) DeleteUser(ctx context.Context, userModel *domain.UserModel) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	user, ok := u.Store.users[userModel.ID]
+	if !ok {
+		// DynamoDBと同様に、存在しないユーザーの削除はバージョンを指定した場合のみ失敗させる
+		if userModel.Version != 0 {
+			return errors.WithStack(domain.ErrPreconditionFailed)
+		}
+		return nil
+	}
+	if userModel.Version != 0 && userModel.Version != user.Version {
+		return errors.WithStack(domain.ErrPreconditionFailed)
+	}
+
+	delete(u.Store.users, user.ID)
+	delete(u.Store.emails, user.Email)
+
+	return nil
+}
+
+// RepairUserStats マイクロポストの件数と投稿日時を、保存されているマイクロポストから計算し直して保存する。
+// 保存されている値より最初の投稿日時を遅く、最後の投稿日時を早くはしない
+func (u *UserOperator) RepairUserStats(ctx context.Context, id uint64) (*domain.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	user, ok := u.Store.users[id]
+	if !ok {
+		return nil, errors.WithStack(domain.ErrNotFound)
+	}
+
+	// 投稿日時は削除では戻さないため、保存されている値に残っているマイクロポストの投稿日時を合わせる
+	user.MicropostsCount = 0
+	for micropostID, micropost := range u.Store.microposts {
+		if micropost.UserID != id {
+			continue
+		}
+
+		postedAt := u.Store.postedAt[micropostID]
+		user.MicropostsCount++
+		if user.FirstPostedAt == nil || postedAt.Before(*user.FirstPostedAt) {
+			user.FirstPostedAt = &postedAt
+		}
+		if user.LastPostedAt == nil || postedAt.After(*user.LastPostedAt) {
+			user.LastPostedAt = &postedAt
+		}
+	}
+
+	return copyUser(user), nil
+}
+
+func copyUser(user *domain.UserModel) *domain.UserModel {
+	copied := *user
+	return &copied
+}
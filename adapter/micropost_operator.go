@@ -2,20 +2,30 @@ package adapter
 
 import (
 	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/guregu/dynamo"
-	"github.com/memememomo/nomof"
 	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// maxTimelineBucketsPerPage 公開タイムラインを1回の取得で遡る最大の日数
+const maxTimelineBucketsPerPage = 31
+
 // MicropostOperator マイクロポストを操作する構造体
 type MicropostOperator struct {
 	Client *ResourceTableOperator
 	Mapper *DynamoModelMapper
+	Cursor *PagingCursor
 }
 
-func (m *MicropostOperator) getMicropostResourceByID(id uint64) (*MicropostResource, error) {
+func (m *MicropostOperator) getMicropostResourceByID(ctx context.Context, id uint64) (*MicropostResource, error) {
 	var micropostResource MicropostResource
-	_, err := m.Mapper.GetEntityByID(id, &MicropostResource{}, &micropostResource)
+	_, err := m.Mapper.GetEntityByID(ctx, id, &MicropostResource{}, &micropostResource)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -23,80 +33,861 @@ func (m *MicropostOperator) getMicropostResourceByID(id uint64) (*MicropostResou
 }
 
 // GetMicropostByID IDでマイクロポストを取得する
-func (m *MicropostOperator) GetMicropostByID(id uint64) (*domain.MicropostModel, error) {
-	micropostResource, err := m.getMicropostResourceByID(id)
+func (m *MicropostOperator) GetMicropostByID(ctx context.Context, id uint64) (*domain.MicropostModel, error) {
+	micropostResource, err := m.getMicropostResourceByID(ctx, id)
 	if err != nil {
-		if err.Error() == dynamo.ErrNotFound.Error() {
+		if errors.Is(err, dynamo.ErrNotFound) {
 			return nil, errors.WithStack(domain.ErrNotFound)
 		}
 		return nil, errors.WithStack(err)
 	}
-	return &micropostResource.MicropostModel, nil
+	return micropostResource.Model(), nil
 }
 
-// GetMicropostsByUserID 指定されたユーザーIDに紐づいているマイクロポスト一覧を取得する
-func (m *MicropostOperator) GetMicropostsByUserID(userID uint64) ([]*domain.MicropostModel, error) {
+// GetMicropostsByUserID 指定されたユーザーIDに紐づいているマイクロポスト一覧を作成日時順に取得する
+func (m *MicropostOperator) GetMicropostsByUserID(ctx context.Context, userID uint64, paging *domain.Paging) ([]*domain.MicropostModel, string, error) {
 	table, err := m.Client.ConnectTable()
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, "", errors.WithStack(err)
 	}
 
-	fb := nomof.NewBuilder()
-	fb.Equal("UserID", userID)
-	fb.BeginsWith("PK", m.Mapper.GetEntityNameFromStruct(MicropostResource{}))
+	partitionKey := m.Mapper.GetUserPartitionKey(userID)
+
+	query := table.
+		Get("GSI1PK", partitionKey).
+		Index(GSI1Name).
+		Order(dynamo.Ascending)
+
+	if paging != nil && paging.Cursor != "" {
+		key, err := m.Cursor.Decode(partitionKey, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		query = query.StartFrom(key)
+	}
+
+	// 次のページが存在するかを判定するため、1件多く取得する
+	if paging != nil && paging.Limit > 0 {
+		query = query.Limit(int64(paging.Limit + 1))
+	}
 
 	var micropostResource []MicropostResource
-	err = table.Scan().Filter(fb.JoinAnd(), fb.Arg...).All(&micropostResource)
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.GetMicropostsByUserID")
+	err = query.ConsumedCapacity(&cc).AllWithContext(opCtx, &micropostResource)
+	end(&cc, err)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, "", errors.WithStack(translateDynamoError(err))
+	}
+
+	var nextCursor string
+	if paging != nil && paging.Limit > 0 && len(micropostResource) > paging.Limit {
+		micropostResource = micropostResource[:paging.Limit]
+		last := micropostResource[len(micropostResource)-1]
+		nextCursor, err = m.Cursor.Encode(partitionKey, map[string]string{
+			m.Mapper.PKName: last.ResourceSchema.PK,
+			m.Mapper.SKName: last.ResourceSchema.SK,
+			"GSI1PK":        last.ResourceSchema.GSI1PK,
+			"GSI1SK":        last.ResourceSchema.GSI1SK,
+		})
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
 	}
 
 	var microposts = make([]*domain.MicropostModel, len(micropostResource))
 	for i := range micropostResource {
-		microposts[i] = &micropostResource[i].MicropostModel
+		microposts[i] = micropostResource[i].Model()
+	}
+
+	return microposts, nextCursor, nil
+}
+
+// GetPublicTimeline 全てのユーザーのマイクロポストを新しい順に取得する。
+// sinceIDより大きくmaxID以下のIDのマイクロポストを対象とし、0の場合はその条件を指定しない
+func (m *MicropostOperator) GetPublicTimeline(ctx context.Context, paging *domain.Paging, sinceID, maxID uint64) ([]*domain.MicropostModel, string, error) {
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	// 開始日時より前の日付にはインデックス項目が存在しないため、そこで遡るのをやめる
+	start, ok, err := m.Mapper.getTimelineStart(ctx)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	if !ok {
+		return []*domain.MicropostModel{}, "", nil
+	}
+	first := m.Mapper.GetTimelineBucket(start)
+
+	bucket := m.Mapper.GetTimelineBucket(m.Mapper.now())
+	upper := maxID
+	if paging != nil && paging.Cursor != "" {
+		bucket, upper, err = m.decodeTimelineCursor(paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		if upper == 0 {
+			upper = maxID
+		}
+	}
+
+	limit := 0
+	if paging != nil {
+		limit = paging.Limit
+	}
+
+	var entries []TimelineResource
+	var nextCursor string
+	var sinceBucket time.Time
+	for scanned := 0; !bucket.Before(first); scanned++ {
+		// 日付をまたぐ時刻に各環境の時計のずれで作成日時とIDの順が入れ替わることがあるため、
+		// sinceID以下のIDが現れた日付の前日までは読む
+		if !sinceBucket.IsZero() && bucket.Before(sinceBucket.AddDate(0, 0, -1)) {
+			break
+		}
+
+		// 投稿のない日が続いても1回の取得で読む日数に上限を設け、続きはカーソルで取得させる
+		if limit > 0 && scanned >= maxTimelineBucketsPerPage {
+			nextCursor, err = m.encodeTimelineCursor(bucket, 0)
+			if err != nil {
+				return nil, "", errors.WithStack(err)
+			}
+			break
+		}
+
+		// 次のページが存在するかを判定するため、1件多く取得する
+		need := 0
+		if limit > 0 {
+			need = limit + 1 - len(entries)
+		}
+		timelineResource, err := m.queryTimelineBucket(ctx, table, bucket, upper, need)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+
+		// 同じ日付の中はIDの降順のため、sinceID以下のIDが現れたらその日のそれより後は読まない
+		for _, entry := range timelineResource {
+			if entry.MicropostID <= sinceID {
+				if sinceBucket.IsZero() {
+					sinceBucket = bucket
+				}
+				break
+			}
+			entries = append(entries, entry)
+		}
+
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+			last := entries[len(entries)-1]
+			nextCursor, err = m.encodeTimelineCursor(m.Mapper.GetTimelineBucket(last.CreatedAt), last.MicropostID-1)
+			if err != nil {
+				return nil, "", errors.WithStack(err)
+			}
+			break
+		}
+
+		bucket = bucket.AddDate(0, 0, -1)
+		upper = maxID
+	}
+
+	ids := make([]uint64, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.MicropostID
+	}
+	microposts, err := m.getMicropostsByIDs(ctx, "MicropostOperator.GetPublicTimeline", ids)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	return microposts, nextCursor, nil
+}
+
+// queryTimelineBucket 指定した日付の全てのパーティションから、upper以下のIDのインデックス項目をIDの降順に取得する。
+// limitが0より大きい場合は、各パーティションからlimit件ずつ読んでまとめた上位limit件を返す
+func (m *MicropostOperator) queryTimelineBucket(ctx context.Context, table *dynamo.Table, bucket time.Time, upper uint64, limit int) ([]TimelineResource, error) {
+	results := make([][]TimelineResource, timelineShardCount)
+	errs := make([]error, timelineShardCount)
+
+	// パーティションごとの読み込みは独立しているため、並行して行う
+	var wg sync.WaitGroup
+	for shard := 0; shard < timelineShardCount; shard++ {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+
+			query := table.
+				Get(m.Mapper.PKName, m.Mapper.GetTimelinePartitionKey(bucket, shard)).
+				Order(dynamo.Descending)
+			if upper > 0 {
+				query = query.Range(m.Mapper.SKName, dynamo.LessOrEqual, fmt.Sprintf("%011d", upper))
+			}
+			if limit > 0 {
+				query = query.Limit(int64(limit))
+			}
+
+			var cc dynamo.ConsumedCapacity
+			opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.GetPublicTimeline")
+			err := query.ConsumedCapacity(&cc).AllWithContext(opCtx, &results[shard])
+			end(&cc, err)
+			if err != nil && !errors.Is(err, dynamo.ErrNotFound) {
+				errs[shard] = errors.WithStack(translateDynamoError(err))
+			}
+		}(shard)
+	}
+	wg.Wait()
+
+	var entries []TimelineResource
+	for shard := range results {
+		if errs[shard] != nil {
+			return nil, errs[shard]
+		}
+		entries = append(entries, results[shard]...)
+	}
+
+	// IDは作成順に採番されるため、IDの降順が新しい順になる
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].MicropostID > entries[j].MicropostID
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+// getMicropostsByIDs インデックス項目から読み込んだIDの順にマイクロポストを取得する。取得までの間に削除されたものは除く
+func (m *MicropostOperator) getMicropostsByIDs(ctx context.Context, operation string, ids []uint64) ([]*domain.MicropostModel, error) {
+	microposts := make([]*domain.MicropostModel, 0, len(ids))
+	if len(ids) == 0 {
+		return microposts, nil
+	}
+
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	keys := make([]dynamo.Keyed, len(ids))
+	for i, id := range ids {
+		key := NewMicropostResource(&domain.MicropostModel{ID: id}, m.Mapper)
+		keys[i] = dynamo.Keys{key.PK(), key.SK()}
+	}
+
+	var micropostResource []MicropostResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := m.Mapper.startOperation(ctx, operation)
+	err = table.
+		Batch(m.Mapper.PKName, m.Mapper.SKName).
+		Get(keys...).
+		ConsumedCapacity(&cc).
+		AllWithContext(opCtx, &micropostResource)
+	end(&cc, err)
+	if err != nil && !errors.Is(err, dynamo.ErrNotFound) {
+		return nil, errors.WithStack(translateDynamoError(err))
+	}
+
+	found := make(map[uint64]*MicropostResource, len(micropostResource))
+	for i := range micropostResource {
+		found[micropostResource[i].ID()] = &micropostResource[i]
+	}
+	for _, id := range ids {
+		if r, ok := found[id]; ok {
+			microposts = append(microposts, r.Model())
+		}
 	}
 
 	return microposts, nil
 }
 
-// DeleteMicropost 指定されたIDのマイクロポストを削除する
-func (m *MicropostOperator) DeleteMicropost(id uint64) error {
-	micropost, err := m.getMicropostResourceByID(id)
+// GetReplies 指定されたマイクロポストへの返信を古い順に取得する。返信先が削除されていても返信は取得できる
+func (m *MicropostOperator) GetReplies(ctx context.Context, micropostID uint64, paging *domain.Paging) ([]*domain.MicropostModel, string, error) {
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	// 返信先のマイクロポスト本体と同じパーティションのため、返信のソートキーに絞って取得する
+	partitionKey := m.Mapper.GetMicropostPartitionKey(micropostID)
+	prefix := m.Mapper.GetReplySortKeyPrefix()
+	scope := partitionKey + "-" + prefix
+	query := table.
+		Get(m.Mapper.PKName, partitionKey).
+		Range(m.Mapper.SKName, dynamo.BeginsWith, prefix).
+		Order(dynamo.Ascending)
+
+	if paging != nil && paging.Cursor != "" {
+		key, err := m.Cursor.Decode(scope, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		query = query.StartFrom(key)
+	}
+
+	// 次のページが存在するかを判定するため、1件多く取得する
+	if paging != nil && paging.Limit > 0 {
+		query = query.Limit(int64(paging.Limit + 1))
+	}
+
+	var replyResource []ReplyResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.GetReplies")
+	err = query.ConsumedCapacity(&cc).AllWithContext(opCtx, &replyResource)
+	end(&cc, err)
+	if err != nil {
+		return nil, "", errors.WithStack(translateDynamoError(err))
+	}
+
+	var nextCursor string
+	if paging != nil && paging.Limit > 0 && len(replyResource) > paging.Limit {
+		replyResource = replyResource[:paging.Limit]
+		last := replyResource[len(replyResource)-1]
+		nextCursor, err = m.Cursor.Encode(scope, map[string]string{
+			m.Mapper.PKName: last.ResourceSchema.PK,
+			m.Mapper.SKName: last.ResourceSchema.SK,
+		})
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+	}
+
+	ids := make([]uint64, len(replyResource))
+	for i, reply := range replyResource {
+		ids[i] = reply.MicropostID
+	}
+
+	microposts, err := m.getMicropostsByIDs(ctx, "MicropostOperator.GetReplies", ids)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	return microposts, nextCursor, nil
+}
+
+// encodeTimelineCursor 次のページを読み始める日付と、その日付で読み始めるIDをカーソルにする。IDが0の場合は日付の最初から読む
+func (m *MicropostOperator) encodeTimelineCursor(bucket time.Time, upper uint64) (string, error) {
+	return m.Cursor.Encode(m.timelineCursorScope(), map[string]string{
+		"Bucket": bucket.Format("2006-01-02"),
+		"Upper":  strconv.FormatUint(upper, 10),
+	})
+}
+
+func (m *MicropostOperator) decodeTimelineCursor(cursor string) (time.Time, uint64, error) {
+	key, err := m.Cursor.Decode(m.timelineCursorScope(), cursor)
+	if err != nil {
+		return time.Time{}, 0, errors.WithStack(err)
+	}
+	if key["Bucket"] == nil || key["Upper"] == nil {
+		return time.Time{}, 0, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	bucket, err := time.Parse("2006-01-02", aws.StringValue(key["Bucket"].S))
+	if err != nil {
+		return time.Time{}, 0, errors.WithStack(domain.ErrInvalidCursor)
+	}
+	upper, err := strconv.ParseUint(aws.StringValue(key["Upper"].S), 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	return bucket, upper, nil
+}
+
+func (m *MicropostOperator) timelineCursorScope() string {
+	return m.Mapper.GetEntityNameFromStruct(TimelineResource{})
+}
+
+// buildQueryUpdateUserStats 投稿者のマイクロポストの件数を増減するクエリを生成する
+func (m *MicropostOperator) buildQueryUpdateUserStats(userID uint64, delta int) (*dynamo.Update, error) {
+	userResource := NewUserResource(&domain.UserModel{ID: userID}, m.Mapper)
+	return m.Mapper.BuildQueryIncrement(userResource, userMicropostsCountAttribute, delta)
+}
+
+// buildQueriesUpdatePostedStats 投稿者のマイクロポストの件数を増やし、投稿日時を更新するクエリを、試す順に生成する。
+// 同時に作成されたマイクロポストが前後して保存されても投稿日時が戻らないよう、
+// 最後の投稿日時より新しい場合・最初の投稿日時より古い場合だけ、それぞれの投稿日時を更新する。
+// トランザクション内では条件を満たさないと作成自体が失敗するため、条件を外したクエリで作成し直せるようにする
+func (m *MicropostOperator) buildQueriesUpdatePostedStats(userID uint64, postedAt time.Time) ([]*dynamo.Update, error) {
+	t := formatPostedAt(postedAt)
+
+	latest, err := m.buildQueryUpdateUserStats(userID, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	latest.
+		Set(userLastPostedAtAttribute, t).
+		SetIfNotExists(userFirstPostedAtAttribute, t).
+		If("(attribute_not_exists($) OR $ < ?)", userLastPostedAtAttribute, userLastPostedAtAttribute, t)
+
+	earliest, err := m.buildQueryUpdateUserStats(userID, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	earliest.
+		Set(userFirstPostedAtAttribute, t).
+		If("$ > ?", userFirstPostedAtAttribute, t)
+
+	countOnly, err := m.buildQueryUpdateUserStats(userID, 1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return []*dynamo.Update{latest, earliest, countOnly}, nil
+}
+
+// buildQueryUpdateReplyCount 返信先のマイクロポストの返信数を増減するクエリを生成する
+func (m *MicropostOperator) buildQueryUpdateReplyCount(inReplyToID uint64, delta int) (*dynamo.Update, error) {
+	micropostResource := NewMicropostResource(&domain.MicropostModel{ID: inReplyToID}, m.Mapper)
+	return m.Mapper.BuildQueryIncrement(micropostResource, micropostReplyCountAttribute, delta)
+}
+
+// buildQueryPutReply 返信のインデックス項目を作成するクエリを生成する
+func (m *MicropostOperator) buildQueryPutReply(reply *ReplyResource) (*dynamo.Put, error) {
+	table, err := m.Client.ConnectTable()
 	if err != nil {
-		if err.Error() == dynamo.ErrNotFound.Error() {
+		return nil, errors.WithStack(err)
+	}
+	return table.Put(reply), nil
+}
+
+// buildQueryDeleteReply 返信のインデックス項目を削除するクエリを生成する
+func (m *MicropostOperator) buildQueryDeleteReply(reply *ReplyResource) (*dynamo.Delete, error) {
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	query := table.
+		Delete(m.Mapper.PKName, reply.ResourceSchema.PK).
+		Range(m.Mapper.SKName, reply.ResourceSchema.SK)
+
+	return query, nil
+}
+
+// buildQueryDeleteTimeline マイクロポストの公開タイムラインのインデックス項目を削除するクエリを生成する
+func (m *MicropostOperator) buildQueryDeleteTimeline(micropost *MicropostResource) (*dynamo.Delete, error) {
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	entry := micropost.TimelineResource()
+	entry.SetKeys()
+
+	query := table.
+		Delete(m.Mapper.PKName, entry.ResourceSchema.PK).
+		Range(m.Mapper.SKName, entry.ResourceSchema.SK)
+
+	return query, nil
+}
+
+// DeleteMicropost 指定されたマイクロポストを削除する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ削除する
+func (m *MicropostOperator) DeleteMicropost(ctx context.Context, micropostModel *domain.MicropostModel) error {
+	micropost, err := m.getMicropostResourceByID(ctx, micropostModel.ID)
+	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			// 前回の削除がいいねの削除の途中で失敗していた場合に備えて、残っているいいねを削除する
+			if err := m.deleteLikes(ctx, micropostModel.ID); err != nil {
+				return errors.WithStack(err)
+			}
 			return errors.WithStack(domain.ErrNotFound)
 		}
 		return errors.WithStack(err)
 	}
+	micropost.Mapper = m.Mapper
+	micropost.SetVersion(micropostModel.Version)
+
+	conn, err := m.Client.ConnectDB()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	query, err := m.Mapper.BuildQueryDeleteWithVersion(micropost)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 公開タイムラインのインデックス項目も同じトランザクションで削除する
+	timelineDelete, err := m.buildQueryDeleteTimeline(micropost)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
-	err = m.Mapper.DeleteResource(micropost)
+	stats, err := m.buildQueryUpdateUserStats(micropost.UserID, -1)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	// 返信の場合は返信先のインデックス項目も削除し、返信先の返信数を減らす
+	reply := micropost.ReplyResource()
+	var replyDelete *dynamo.Delete
+	var replyCount *dynamo.Update
+	if reply != nil {
+		replyDelete, err = m.buildQueryDeleteReply(reply)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		replyCount, err = m.buildQueryUpdateReplyCount(reply.InReplyToID, -1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	run := func(withReplyCount bool) error {
+		tx := conn.WriteTx().Delete(query).Delete(timelineDelete).Update(stats)
+		if replyDelete != nil {
+			tx.Delete(replyDelete)
+		}
+		if withReplyCount && replyCount != nil {
+			tx.Update(replyCount)
+		}
+
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.DeleteMicropost")
+		err := tx.ConsumedCapacity(&cc).RunWithContext(opCtx)
+		end(&cc, err)
+		return err
+	}
+
+	err = run(true)
+	// 返信先が既に削除されている場合は、返信数を更新せずに削除し直す
+	if err != nil && replyCount != nil && isTransactionConditionFailed(err, 4) {
+		err = run(false)
+	}
+	if err != nil {
+		if isTransactionConditionFailed(err, 0) {
+			return errors.WithStack(domain.ErrPreconditionFailed)
+		}
+		if isTransactionConditionFailed(err, 2) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
+		return errors.WithStack(translateDynamoError(err))
+	}
+
+	// マイクロポストの削除後にいいねの削除に失敗した場合は、リトライで残りを削除できるため一時的なエラーとして返す
+	err = m.deleteLikes(ctx, micropost.ID())
+	if err != nil {
+		return errors.WithStack(domain.ErrUnavailable.Wrap(err))
+	}
+
 	return nil
 }
 
-// CreateMicropost 新規作成する
-func (m *MicropostOperator) CreateMicropost(micropostModel *domain.MicropostModel) (*domain.MicropostModel, error) {
+// DeleteMicropostsByUserID 指定されたユーザーIDに紐づいているマイクロポストを全て削除し、削除した件数を返す。
+// 公開タイムライン・返信のインデックス項目とユーザーの集計値の更新を合わせて、トランザクションの上限件数ごとに分割して削除する。
+// 返信先の返信数も同じトランザクションで減らす。ユーザー・返信先が既に削除されている場合はそれぞれ更新しない。
+// いいねはリトライで削除し直せるよう、マイクロポストより先に削除する
+func (m *MicropostOperator) DeleteMicropostsByUserID(ctx context.Context, userID uint64) (int, error) {
+	conn, err := m.Client.ConnectDB()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	count := 0
+	var startKey dynamo.PagingKey
+	for {
+		query := table.
+			Get("GSI1PK", m.Mapper.GetUserPartitionKey(userID)).
+			Index(GSI1Name).
+			Limit((maxTransactionItems - 1) / 4)
+		if startKey != nil {
+			query = query.StartFrom(startKey)
+		}
+
+		var micropostResource []MicropostResource
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.DeleteMicropostsByUserID")
+		startKey, err = query.ConsumedCapacity(&cc).AllWithLastEvaluatedKeyContext(opCtx, &micropostResource)
+		end(&cc, err)
+		if err != nil {
+			return count, errors.WithStack(translateDynamoError(err))
+		}
+
+		if len(micropostResource) > 0 {
+			var deletes []*dynamo.Delete
+			replies := map[uint64]int{}
+			for i := range micropostResource {
+				micropostResource[i].Mapper = m.Mapper
+				r, err := m.Mapper.BuildQueryDelete(&micropostResource[i])
+				if err != nil {
+					return count, errors.WithStack(err)
+				}
+				timelineDelete, err := m.buildQueryDeleteTimeline(&micropostResource[i])
+				if err != nil {
+					return count, errors.WithStack(err)
+				}
+				deletes = append(deletes, r, timelineDelete)
+
+				if reply := micropostResource[i].ReplyResource(); reply != nil {
+					replyDelete, err := m.buildQueryDeleteReply(reply)
+					if err != nil {
+						return count, errors.WithStack(err)
+					}
+					deletes = append(deletes, replyDelete)
+					replies[reply.InReplyToID]++
+				}
+			}
+
+			stats, err := m.buildQueryUpdateUserStats(userID, -len(micropostResource))
+			if err != nil {
+				return count, errors.WithStack(err)
+			}
+
+			// 同じトランザクションで削除する返信先は、返信数を更新しない
+			for i := range micropostResource {
+				delete(replies, micropostResource[i].ID())
+			}
+			replyIDs := make([]uint64, 0, len(replies))
+			for inReplyToID := range replies {
+				replyIDs = append(replyIDs, inReplyToID)
+			}
+			replyCounts := make([]*dynamo.Update, len(replyIDs))
+			for i, inReplyToID := range replyIDs {
+				replyCounts[i], err = m.buildQueryUpdateReplyCount(inReplyToID, -replies[inReplyToID])
+				if err != nil {
+					return count, errors.WithStack(err)
+				}
+			}
+
+			withStats := true
+			run := func() error {
+				tx := conn.WriteTx()
+				for _, d := range deletes {
+					tx.Delete(d)
+				}
+				if withStats {
+					tx.Update(stats)
+				}
+				for _, u := range replyCounts {
+					tx.Update(u)
+				}
+
+				var txcc dynamo.ConsumedCapacity
+				opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.DeleteMicropostsByUserID")
+				err := tx.ConsumedCapacity(&txcc).RunWithContext(opCtx)
+				end(&txcc, err)
+				return err
+			}
+
+			// リトライで残りを削除できるよう、マイクロポストより先にいいねを削除する
+			for i := range micropostResource {
+				err = m.deleteLikes(ctx, micropostResource[i].ID())
+				if err != nil {
+					return count, errors.WithStack(err)
+				}
+			}
+
+			for {
+				err = run()
+				if err == nil {
+					break
+				}
+				retry := false
+				// 返信数の更新はユーザーの集計値の更新の後に並んでいる
+				offset := len(deletes)
+				if withStats {
+					offset++
+				}
+				// ユーザーを先に削除している場合は、集計値を更新せずに削除し直す
+				if withStats && isTransactionConditionFailed(err, len(deletes)) {
+					withStats = false
+					retry = true
+				}
+				// 返信先が既に削除されている場合は、その返信数を更新せずに削除し直す
+				for i := len(replyCounts) - 1; i >= 0; i-- {
+					if isTransactionConditionFailed(err, offset+i) {
+						replyCounts = append(replyCounts[:i], replyCounts[i+1:]...)
+						retry = true
+					}
+				}
+				if !retry {
+					return count, errors.WithStack(translateDynamoError(err))
+				}
+			}
+			count += len(micropostResource)
+
+			// 先にいいねを削除してからマイクロポストを削除するまでの間に、いいねされた分を削除する。
+			// 失敗して残ったいいねは参照されず、いいねしたユーザーの削除時に DeleteLikesByUserID で取り除かれる
+			for i := range micropostResource {
+				err = m.deleteLikes(ctx, micropostResource[i].ID())
+				if err != nil {
+					return count, errors.WithStack(err)
+				}
+			}
+		}
+
+		if startKey == nil {
+			return count, nil
+		}
+	}
+}
+
+// deleteLikes マイクロポストへのいいねを全て削除する。マイクロポストの件数は更新しない。
+// マイクロポストと同じパーティションに保存しているため、マイクロポストを削除した後に残っていても参照されることはない
+func (m *MicropostOperator) deleteLikes(ctx context.Context, micropostID uint64) error {
+	conn, err := m.Client.ConnectDB()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	table, err := m.Client.ConnectTable()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var startKey dynamo.PagingKey
+	for {
+		query := table.
+			Get(m.Mapper.PKName, m.Mapper.GetMicropostPartitionKey(micropostID)).
+			Range(m.Mapper.SKName, dynamo.BeginsWith, m.Mapper.GetLikeSortKeyPrefix()).
+			Limit(maxTransactionItems)
+		if startKey != nil {
+			query = query.StartFrom(startKey)
+		}
+
+		var likeResource []LikeResource
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.DeleteLikes")
+		startKey, err = query.ConsumedCapacity(&cc).AllWithLastEvaluatedKeyContext(opCtx, &likeResource)
+		end(&cc, err)
+		if err != nil {
+			return errors.WithStack(translateDynamoError(err))
+		}
+
+		if len(likeResource) > 0 {
+			tx := conn.WriteTx()
+			for _, like := range likeResource {
+				tx.Delete(table.
+					Delete(m.Mapper.PKName, like.ResourceSchema.PK).
+					Range(m.Mapper.SKName, like.ResourceSchema.SK))
+			}
+
+			var txcc dynamo.ConsumedCapacity
+			opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.DeleteLikes")
+			err = tx.ConsumedCapacity(&txcc).RunWithContext(opCtx)
+			end(&txcc, err)
+			if err != nil {
+				return errors.WithStack(translateDynamoError(err))
+			}
+		}
+
+		if startKey == nil {
+			return nil
+		}
+	}
+}
+
+// CreateMicropost 新規作成する。投稿者のユーザーまたは返信先のマイクロポストが存在しない場合はErrNotFoundを返す
+func (m *MicropostOperator) CreateMicropost(ctx context.Context, micropostModel *domain.MicropostModel) (*domain.MicropostModel, error) {
+	conn, err := m.Client.ConnectDB()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	micropostResource := NewMicropostResource(micropostModel, m.Mapper)
-	err := m.Mapper.PutResource(micropostResource)
+
+	creates, err := m.Mapper.BuildQueryCreate(ctx, micropostResource)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return &micropostResource.MicropostModel, nil
+
+	// 投稿者の集計値を同じトランザクション内で更新する。同時にユーザーが削除された場合は条件チェックで失敗する
+	postedAt := micropostResource.CreatedAt()
+	statsQueries, err := m.buildQueriesUpdatePostedStats(micropostModel.UserID, postedAt)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// 返信の場合は、返信先のインデックス項目の作成と返信数の更新も同じトランザクションで行う。
+	// 同時に返信先が削除された場合は条件チェックで失敗する
+	var replyPut *dynamo.Put
+	var replyCount *dynamo.Update
+	if reply := micropostResource.ReplyResource(); reply != nil {
+		replyPut, err = m.buildQueryPutReply(reply)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		replyCount, err = m.buildQueryUpdateReplyCount(reply.InReplyToID, 1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	// 公開タイムラインの開始日時がまだ記録されていなければ、同じトランザクションで記録する
+	timelineStart, err := m.Mapper.buildQueryRecordTimelineStart(postedAt)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	run := func(stats *dynamo.Update) error {
+		tx := conn.WriteTx()
+		for _, r := range creates {
+			tx.Put(r)
+		}
+		tx.Update(stats)
+		if replyPut != nil {
+			tx.Put(replyPut).Update(replyCount)
+		}
+		if timelineStart != nil {
+			tx.Update(timelineStart)
+		}
+
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := m.Mapper.startOperation(ctx, "MicropostOperator.CreateMicropost")
+		err := tx.ConsumedCapacity(&cc).RunWithContext(opCtx)
+		end(&cc, err)
+		return err
+	}
+
+	// 投稿日時の条件を満たさない場合は、次の更新で作成し直す。最後の更新でも失敗した場合はユーザーが存在しない
+	for i, stats := range statsQueries {
+		err = run(stats)
+		if err == nil {
+			break
+		}
+		if isTransactionConditionFailed(err, len(creates)+2) {
+			return nil, errors.WithStack(domain.ErrNotFound)
+		}
+		if isTransactionConditionFailed(err, len(creates)) {
+			if i == len(statsQueries)-1 {
+				return nil, errors.WithStack(domain.ErrNotFound)
+			}
+			continue
+		}
+		return nil, errors.WithStack(translateDynamoError(err))
+	}
+	if timelineStart != nil {
+		m.Mapper.markTimelineStartRecorded()
+	}
+
+	return micropostResource.Model(), nil
 }
 
-// UpdateMicropost 更新する
-func (m *MicropostOperator) UpdateMicropost(micropostModel *domain.MicropostModel) error {
-	micropostResource, err := m.getMicropostResourceByID(micropostModel.ID)
+// UpdateMicropost 更新する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ更新する
+func (m *MicropostOperator) UpdateMicropost(ctx context.Context, micropostModel *domain.MicropostModel) error {
+	micropostResource, err := m.getMicropostResourceByID(ctx, micropostModel.ID)
 	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
 		return errors.WithStack(err)
 	}
 	micropostResource.Content = micropostModel.Content
+	if micropostModel.Version != 0 {
+		micropostResource.SetVersion(micropostModel.Version)
+	}
 
-	err = m.Mapper.PutResource(micropostResource)
+	err = m.Mapper.PutResource(ctx, micropostResource)
 	if err != nil {
+		if isConditionFailed(err) {
+			return errors.WithStack(domain.ErrPreconditionFailed)
+		}
 		return errors.WithStack(err)
 	}
 
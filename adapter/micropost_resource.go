@@ -15,11 +15,18 @@ type MicropostResource struct {
 
 func NewMicropostResource(micropostModel *domain.MicropostModel, mapper *DynamoModelMapper) *MicropostResource {
 	return &MicropostResource{
-		MicropostModel: *micropostModel,
-		Mapper:         mapper,
+		DynamoResourceBase: DynamoResourceBase{Version: micropostModel.Version},
+		MicropostModel:     *micropostModel,
+		Mapper:             mapper,
 	}
 }
 
+// Model DynamoDB上のバージョンを反映したドメインモデルを返す
+func (m *MicropostResource) Model() *domain.MicropostModel {
+	m.MicropostModel.Version = m.Version()
+	return &m.MicropostModel
+}
+
 // DynamoResourceインタフェースの実装
 
 func (m *MicropostResource) EntityName() string {
@@ -73,3 +80,38 @@ func (m *MicropostResource) UpdatedAt() time.Time {
 func (m *MicropostResource) SetUpdatedAt(t time.Time) {
 	m.DynamoResourceBase.UpdatedAt = t
 }
+
+// DynamoIndexedResourceインタフェースの実装
+
+func (m *MicropostResource) SetGSI1() {
+	m.ResourceSchema.GSI1PK = m.Mapper.GetUserPartitionKey(m.UserID)
+	m.ResourceSchema.GSI1SK = m.Mapper.GetTimeSortKey(m.CreatedAt(), m.ID())
+}
+
+// DynamoTimelineResourceインタフェースの実装
+
+func (m *MicropostResource) TimelineResource() *TimelineResource {
+	return NewTimelineResource(m.ID(), m.UserID, m.CreatedAt(), m.Mapper)
+}
+
+// DynamoAggregatedResourceインタフェースの実装
+
+// 集計値の属性名。いいね・返信の作成・削除と同じトランザクションで更新する
+const (
+	micropostLikeCountAttribute  = "LikeCount"
+	micropostReplyCountAttribute = "ReplyCount"
+)
+
+func (m *MicropostResource) AggregatedAttributes() []string {
+	return []string{micropostLikeCountAttribute, micropostReplyCountAttribute}
+}
+
+// ReplyResource 返信の場合は返信先のマイクロポストのパーティションに保存するインデックス項目を返す。返信でない場合はnilを返す
+func (m *MicropostResource) ReplyResource() *ReplyResource {
+	if m.InReplyToID == 0 {
+		return nil
+	}
+	reply := NewReplyResource(m.ID(), m.InReplyToID, m.Mapper)
+	reply.SetKeys()
+	return reply
+}
@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/guregu/dynamo"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// PagingCursor DynamoDBのLastEvaluatedKeyと、クライアントに渡す署名付きカーソル文字列を相互に変換する
+type PagingCursor struct {
+	Secret []byte
+}
+
+// NewPagingCursor PagingCursor インスタンスを生成。
+// 他のインスタンスが発行したカーソルも検証できるよう全てのインスタンスで同じ鍵を使うため、secretが空の場合はエラーを返す
+func NewPagingCursor(secret string) (*PagingCursor, error) {
+	if secret == "" {
+		return nil, errors.New("paging cursor secret is empty")
+	}
+	return &PagingCursor{Secret: []byte(secret)}, nil
+}
+
+// Encode キーをカーソル文字列に変換する。scopeは別の一覧のカーソルを使い回されないようにするための値
+func (p *PagingCursor) Encode(scope string, key map[string]string) (string, error) {
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + p.sign(scope, encoded), nil
+}
+
+// Decode カーソル文字列を検証し、DynamoDBのStartFromに渡すキーに変換する
+func (p *PagingCursor) Decode(scope, cursor string) (dynamo.PagingKey, error) {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 {
+		return nil, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(p.sign(scope, parts[0]))) {
+		return nil, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	var key map[string]string
+	err = json.Unmarshal(payload, &key)
+	if err != nil {
+		return nil, errors.WithStack(domain.ErrInvalidCursor)
+	}
+
+	pagingKey := dynamo.PagingKey{}
+	for name, value := range key {
+		pagingKey[name] = &dynamodb.AttributeValue{S: aws.String(value)}
+	}
+
+	return pagingKey, nil
+}
+
+func (p *PagingCursor) sign(scope, encoded string) string {
+	mac := hmac.New(sha256.New, p.Secret)
+	mac.Write([]byte(scope + "." + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,290 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"github.com/guregu/dynamo"
+	"github.com/memememomo/nomof"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// RelationshipOperator フォロー関係を操作する構造体
+type RelationshipOperator struct {
+	Client *ResourceTableOperator
+	Mapper *DynamoModelMapper
+	Cursor *PagingCursor
+}
+
+// Follow フォローする。すでにフォローしている場合は何もしない。
+// フォローするユーザーかフォロー対象のユーザーが存在しない場合はErrNotFoundを返す
+func (r *RelationshipOperator) Follow(ctx context.Context, relationshipModel *domain.RelationshipModel) error {
+	conn, err := r.Client.ConnectDB()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	table, err := r.Client.ConnectTable()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	relationship := NewRelationshipResource(relationshipModel, r.Mapper)
+	relationship.SetKeys()
+	relationship.CreatedAt = time.Now()
+	relationship.UpdatedAt = relationship.CreatedAt
+
+	// フォローした日時が変わらないよう、すでにフォローしている場合は上書きしない
+	fb := nomof.NewBuilder()
+	fb.AttributeNotExists(r.Mapper.PKName)
+	put := table.Put(relationship).If(fb.JoinAnd(), fb.Arg...)
+
+	// 同時にユーザーが削除された場合に備えて、同じトランザクション内で両方のユーザーの存在を確認する
+	follower, err := r.Mapper.BuildQueryCheckExists(NewUserResource(&domain.UserModel{ID: relationshipModel.FollowerID}, r.Mapper))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	followed, err := r.Mapper.BuildQueryCheckExists(NewUserResource(&domain.UserModel{ID: relationshipModel.FollowedID}, r.Mapper))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := r.Mapper.startOperation(ctx, "RelationshipOperator.Follow")
+	err = conn.WriteTx().Put(put).Check(follower).Check(followed).ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
+	if err != nil {
+		if isTransactionConditionFailed(err, 1) || isTransactionConditionFailed(err, 2) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
+		if isTransactionConditionFailed(err, 0) {
+			return nil
+		}
+		return errors.WithStack(translateDynamoError(err))
+	}
+
+	return nil
+}
+
+// Unfollow フォローを解除する。フォローしていない場合はErrNotFoundを返す
+func (r *RelationshipOperator) Unfollow(ctx context.Context, relationshipModel *domain.RelationshipModel) error {
+	table, err := r.Client.ConnectTable()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	relationship := NewRelationshipResource(relationshipModel, r.Mapper)
+	relationship.SetKeys()
+
+	fb := nomof.NewBuilder()
+	fb.AttributeExists(r.Mapper.PKName)
+
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := r.Mapper.startOperation(ctx, "RelationshipOperator.Unfollow")
+	err = table.
+		Delete(r.Mapper.PKName, relationship.ResourceSchema.PK).
+		Range(r.Mapper.SKName, relationship.ResourceSchema.SK).
+		If(fb.JoinAnd(), fb.Arg...).
+		ConsumedCapacity(&cc).
+		RunWithContext(opCtx)
+	end(&cc, err)
+	if err != nil {
+		if isConditionFailed(err) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
+		return errors.WithStack(translateDynamoError(err))
+	}
+
+	return nil
+}
+
+// IsFollowing followerIDのユーザーがfollowedIDのユーザーをフォローしているかどうか
+func (r *RelationshipOperator) IsFollowing(ctx context.Context, followerID, followedID uint64) (bool, error) {
+	table, err := r.Client.ConnectTable()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	relationship := NewRelationshipResource(domain.NewRelationshipModel(followerID, followedID), r.Mapper)
+	relationship.SetKeys()
+
+	var found RelationshipResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := r.Mapper.startOperation(ctx, "RelationshipOperator.IsFollowing")
+	err = table.
+		Get(r.Mapper.PKName, relationship.ResourceSchema.PK).
+		Range(r.Mapper.SKName, dynamo.Equal, relationship.ResourceSchema.SK).
+		ConsumedCapacity(&cc).
+		OneWithContext(opCtx, &found)
+	end(&cc, err)
+	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return false, nil
+		}
+		return false, errors.WithStack(translateDynamoError(err))
+	}
+
+	return true, nil
+}
+
+// GetFollowers 指定されたユーザーのフォロワーをユーザーIDの順に取得する
+func (r *RelationshipOperator) GetFollowers(ctx context.Context, userID uint64, paging *domain.Paging) ([]*domain.RelationshipModel, string, error) {
+	table, err := r.Client.ConnectTable()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	partitionKey := r.Mapper.GetFollowersPartitionKey(userID)
+	query := table.
+		Get("GSI1PK", partitionKey).
+		Index(GSI1Name).
+		Order(dynamo.Ascending)
+
+	return r.getRelationships(ctx, "RelationshipOperator.GetFollowers", query, partitionKey, paging, func(last *RelationshipResource) map[string]string {
+		return map[string]string{
+			r.Mapper.PKName: last.ResourceSchema.PK,
+			r.Mapper.SKName: last.ResourceSchema.SK,
+			"GSI1PK":        last.ResourceSchema.GSI1PK,
+			"GSI1SK":        last.ResourceSchema.GSI1SK,
+		}
+	})
+}
+
+// GetFollowing 指定されたユーザーがフォローしているユーザーをユーザーIDの順に取得する
+func (r *RelationshipOperator) GetFollowing(ctx context.Context, userID uint64, paging *domain.Paging) ([]*domain.RelationshipModel, string, error) {
+	table, err := r.Client.ConnectTable()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	partitionKey := r.Mapper.GetFollowingPartitionKey(userID)
+	query := table.
+		Get(r.Mapper.PKName, partitionKey).
+		Order(dynamo.Ascending)
+
+	return r.getRelationships(ctx, "RelationshipOperator.GetFollowing", query, partitionKey, paging, func(last *RelationshipResource) map[string]string {
+		return map[string]string{
+			r.Mapper.PKName: last.ResourceSchema.PK,
+			r.Mapper.SKName: last.ResourceSchema.SK,
+		}
+	})
+}
+
+// getRelationships ページング条件に従ってフォロー関係を取得する。cursorKeyで次のページのカーソルに含めるキーを指定する
+func (r *RelationshipOperator) getRelationships(ctx context.Context, operation string, query *dynamo.Query, partitionKey string, paging *domain.Paging, cursorKey func(last *RelationshipResource) map[string]string) ([]*domain.RelationshipModel, string, error) {
+	if paging != nil && paging.Cursor != "" {
+		key, err := r.Cursor.Decode(partitionKey, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		query = query.StartFrom(key)
+	}
+
+	// 次のページが存在するかを判定するため、1件多く取得する
+	if paging != nil && paging.Limit > 0 {
+		query = query.Limit(int64(paging.Limit + 1))
+	}
+
+	var relationshipResource []RelationshipResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := r.Mapper.startOperation(ctx, operation)
+	err := query.ConsumedCapacity(&cc).AllWithContext(opCtx, &relationshipResource)
+	end(&cc, err)
+	if err != nil {
+		return nil, "", errors.WithStack(translateDynamoError(err))
+	}
+
+	var nextCursor string
+	if paging != nil && paging.Limit > 0 && len(relationshipResource) > paging.Limit {
+		relationshipResource = relationshipResource[:paging.Limit]
+		nextCursor, err = r.Cursor.Encode(partitionKey, cursorKey(&relationshipResource[len(relationshipResource)-1]))
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+	}
+
+	var relationships = make([]*domain.RelationshipModel, len(relationshipResource))
+	for i := range relationshipResource {
+		relationships[i] = relationshipResource[i].Model()
+	}
+
+	return relationships, nextCursor, nil
+}
+
+// DeleteRelationshipsByUserID 指定されたユーザーがフォローしている関係とフォローされている関係を全て削除し、削除した件数を返す。
+// トランザクションの上限件数ごとに分割して削除する
+func (r *RelationshipOperator) DeleteRelationshipsByUserID(ctx context.Context, userID uint64) (int, error) {
+	table, err := r.Client.ConnectTable()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	following, err := r.deleteRelationships(ctx, func() *dynamo.Query {
+		return table.Get(r.Mapper.PKName, r.Mapper.GetFollowingPartitionKey(userID))
+	})
+	if err != nil {
+		return following, errors.WithStack(err)
+	}
+
+	followers, err := r.deleteRelationships(ctx, func() *dynamo.Query {
+		return table.Get("GSI1PK", r.Mapper.GetFollowersPartitionKey(userID)).Index(GSI1Name)
+	})
+	if err != nil {
+		return following + followers, errors.WithStack(err)
+	}
+
+	return following + followers, nil
+}
+
+// deleteRelationships newQueryで取得できるフォロー関係を全て削除し、削除した件数を返す
+func (r *RelationshipOperator) deleteRelationships(ctx context.Context, newQuery func() *dynamo.Query) (int, error) {
+	conn, err := r.Client.ConnectDB()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	count := 0
+	var startKey dynamo.PagingKey
+	for {
+		query := newQuery().Limit(maxTransactionItems)
+		if startKey != nil {
+			query = query.StartFrom(startKey)
+		}
+
+		var relationshipResource []RelationshipResource
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := r.Mapper.startOperation(ctx, "RelationshipOperator.DeleteRelationshipsByUserID")
+		startKey, err = query.ConsumedCapacity(&cc).AllWithLastEvaluatedKeyContext(opCtx, &relationshipResource)
+		end(&cc, err)
+		if err != nil {
+			return count, errors.WithStack(translateDynamoError(err))
+		}
+
+		if len(relationshipResource) > 0 {
+			table, err := r.Client.ConnectTable()
+			if err != nil {
+				return count, errors.WithStack(err)
+			}
+
+			tx := conn.WriteTx()
+			for _, relationship := range relationshipResource {
+				tx.Delete(table.
+					Delete(r.Mapper.PKName, relationship.ResourceSchema.PK).
+					Range(r.Mapper.SKName, relationship.ResourceSchema.SK))
+			}
+
+			var txcc dynamo.ConsumedCapacity
+			opCtx, end := r.Mapper.startOperation(ctx, "RelationshipOperator.DeleteRelationshipsByUserID")
+			err = tx.ConsumedCapacity(&txcc).RunWithContext(opCtx)
+			end(&txcc, err)
+			if err != nil {
+				return count, errors.WithStack(translateDynamoError(err))
+			}
+			count += len(relationshipResource)
+		}
+
+		if startKey == nil {
+			return count, nil
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"fmt"
+)
+
+// RelationshipResource フォロー関係のDynamoDB上のデータ構造を表した構造体。
+// PKはフォローしているユーザーごと、GSI1PKはフォローされているユーザーごとにまとめるため、
+// フォロー中のユーザー一覧はテーブルから、フォロワー一覧はGSI1から取得できる
+type RelationshipResource struct {
+	ResourceSchema
+	DynamoCreatedUpdated
+	domain.RelationshipModel
+	Mapper *DynamoModelMapper `dynamo:"-"`
+}
+
+func NewRelationshipResource(relationshipModel *domain.RelationshipModel, mapper *DynamoModelMapper) *RelationshipResource {
+	return &RelationshipResource{
+		RelationshipModel: *relationshipModel,
+		Mapper:            mapper,
+	}
+}
+
+// Model ドメインモデルを返す
+func (r *RelationshipResource) Model() *domain.RelationshipModel {
+	return &r.RelationshipModel
+}
+
+// SetKeys テーブルとGSI1のキーを設定する
+func (r *RelationshipResource) SetKeys() {
+	r.ResourceSchema.PK = r.Mapper.GetFollowingPartitionKey(r.FollowerID)
+	r.ResourceSchema.SK = fmt.Sprintf("%011d", r.FollowedID)
+	r.ResourceSchema.GSI1PK = r.Mapper.GetFollowersPartitionKey(r.FollowedID)
+	r.ResourceSchema.GSI1SK = fmt.Sprintf("%011d", r.FollowerID)
+}
@@ -0,0 +1,29 @@
+package adapter
+
+import (
+	"fmt"
+)
+
+// ReplyResource 返信のインデックス項目のDynamoDB上のデータ構造を表した構造体。
+// PKは返信先のマイクロポストと同じにしてマイクロポストの隣に保存し、SKは返信のIDのため、SKの昇順に読むと古い順に並ぶ。
+// 返信先のマイクロポストが削除されても残るため、返信先が削除された後も返信を取得できる
+type ReplyResource struct {
+	ResourceSchema
+	MicropostID uint64             `dynamo:"MicropostID"`
+	InReplyToID uint64             `dynamo:"InReplyToID"`
+	Mapper      *DynamoModelMapper `dynamo:"-"`
+}
+
+func NewReplyResource(micropostID, inReplyToID uint64, mapper *DynamoModelMapper) *ReplyResource {
+	return &ReplyResource{
+		MicropostID: micropostID,
+		InReplyToID: inReplyToID,
+		Mapper:      mapper,
+	}
+}
+
+// SetKeys テーブルのキーを設定する
+func (r *ReplyResource) SetKeys() {
+	r.ResourceSchema.PK = r.Mapper.GetMicropostPartitionKey(r.InReplyToID)
+	r.ResourceSchema.SK = fmt.Sprintf("%s%011d", r.Mapper.GetReplySortKeyPrefix(), r.MicropostID)
+}
@@ -0,0 +1,36 @@
+package adapter_test
+
+import (
+	"clean-serverless-book-sample-v2/adapter"
+	"clean-serverless-book-sample-v2/domain/repositorytest"
+	"clean-serverless-book-sample-v2/mocks"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRepositoryConformance DynamoDBのリポジトリがリポジトリの振る舞いを満たしているかをチェック
+func TestRepositoryConformance(t *testing.T) {
+	if os.Getenv("DYNAMO_LOCAL_ENDPOINT") == "" {
+		t.Skip("DYNAMO_LOCAL_ENDPOINT is not set")
+	}
+
+	backend := os.Getenv("REPOSITORY_BACKEND")
+	os.Setenv("REPOSITORY_BACKEND", "dynamo")
+	defer os.Setenv("REPOSITORY_BACKEND", backend)
+
+	repositorytest.Run(t, func(t *testing.T) (*repositorytest.Repositories, func()) {
+		tables := mocks.SetupDB(t)
+		return &repositorytest.Repositories{
+			Users:         tables.UserOperator,
+			Microposts:    tables.MicropostOperator,
+			Relationships: tables.RelationshipOperator,
+			Likes:         tables.LikeOperator,
+			SetNow: func(now func() time.Time) {
+				mapper := tables.MicropostOperator.(*adapter.MicropostOperator).Mapper
+				mapper.Now = now
+				t.Cleanup(func() { mapper.Now = nil })
+			},
+		}, tables.Cleanup
+	})
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"io/ioutil"
+	"net/http"
+)
+
+// HTTPHandler net/httpのリクエストをコントローラーのリクエストに変換して、ルーティングする
+type HTTPHandler struct {
+	Router *Router
+}
+
+// NewHTTPHandler HTTPHandler インスタンスを生成
+func NewHTTPHandler(router *Router) *HTTPHandler {
+	return &HTTPHandler{Router: router}
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	request, err := NewRequest(req)
+	if err != nil {
+		writeResponse(w, controller.Response500(err))
+		return
+	}
+
+	writeResponse(w, h.Router.Handle(request))
+}
+
+// NewRequest net/httpのリクエストを、コントローラーのリクエストに変換する
+func NewRequest(req *http.Request) (controller.Request, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return controller.Request{}, err
+	}
+
+	headers := map[string]string{"Host": req.Host}
+	for name, values := range req.Header {
+		headers[name] = values[len(values)-1]
+	}
+
+	var query map[string]string
+	for name, values := range req.URL.Query() {
+		if query == nil {
+			query = map[string]string{}
+		}
+		query[name] = values[len(values)-1]
+	}
+
+	return controller.Request{
+		HTTPMethod:            req.Method,
+		Path:                  req.URL.Path,
+		Headers:               headers,
+		QueryStringParameters: query,
+		Body:                  string(body),
+	}.WithContext(req.Context()), nil
+}
+
+// writeResponse コントローラーのレスポンスを、net/httpのレスポンスとして書き込む
+func writeResponse(w http.ResponseWriter, res controller.Response) {
+	for name, value := range res.Headers {
+		w.Header().Set(name, value)
+	}
+
+	w.WriteHeader(res.StatusCode)
+	w.Write([]byte(res.Body))
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"strings"
+)
+
+// Route HTTPメソッドとパスのテンプレートに対応するコントローラー
+type Route struct {
+	Method string
+	// Path serverless.ymlと同じ形式のパスのテンプレート。{user_id}のようにパスパラメータを指定する
+	Path    string
+	Handler controller.HandlerFunc
+}
+
+// Routes serverless.ymlに定義しているAPIのルーティング
+func Routes() []*Route {
+	return []*Route{
+		{Method: "GET", Path: "/v1/users", Handler: controller.GetUsers},
+		{Method: "POST", Path: "/v1/users", Handler: controller.PostUsers},
+		{Method: "GET", Path: "/v1/users/{user_id}", Handler: controller.GetUser},
+		{Method: "PUT", Path: "/v1/users/{user_id}", Handler: controller.PutUser},
+		{Method: "DELETE", Path: "/v1/users/{user_id}", Handler: controller.DeleteUser},
+		{Method: "GET", Path: "/v1/microposts", Handler: controller.GetPublicTimeline},
+		{Method: "GET", Path: "/v1/microposts/{micropost_id}/replies", Handler: controller.GetReplies},
+		{Method: "POST", Path: "/v1/users/{user_id}/microposts", Handler: controller.PostMicroposts},
+		{Method: "GET", Path: "/v1/users/{user_id}/microposts", Handler: controller.GetMicroposts},
+		{Method: "GET", Path: "/v1/users/{user_id}/microposts/{micropost_id}", Handler: controller.GetMicropost},
+		{Method: "PUT", Path: "/v1/users/{user_id}/microposts/{micropost_id}", Handler: controller.PutMicropost},
+		{Method: "DELETE", Path: "/v1/users/{user_id}/microposts/{micropost_id}", Handler: controller.DeleteMicropost},
+		{Method: "PUT", Path: "/v1/users/{user_id}/microposts/{micropost_id}/likes", Handler: controller.PutLike},
+		{Method: "DELETE", Path: "/v1/users/{user_id}/microposts/{micropost_id}/likes", Handler: controller.DeleteLike},
+		{Method: "GET", Path: "/v1/users/{user_id}/microposts/{micropost_id}/likes", Handler: controller.GetLikes},
+		{Method: "PUT", Path: "/v1/users/{user_id}/following/{target_id}", Handler: controller.PutFollowing},
+		{Method: "DELETE", Path: "/v1/users/{user_id}/following/{target_id}", Handler: controller.DeleteFollowing},
+		{Method: "GET", Path: "/v1/users/{user_id}/following", Handler: controller.GetFollowing},
+		{Method: "GET", Path: "/v1/users/{user_id}/followers", Handler: controller.GetFollowers},
+	}
+}
+
+// Router HTTPメソッドとパスからコントローラーを探す
+type Router struct {
+	Routes []*Route
+}
+
+// NewRouter Router インスタンスを生成
+func NewRouter(routes []*Route) *Router {
+	return &Router{Routes: routes}
+}
+
+// Match メソッドとパスに一致するルートとパスパラメータを返す。
+// 一致するルートがない場合はnilを返し、パスだけが一致するルートがあればそれらのメソッドを返す
+func (r *Router) Match(method, path string) (*Route, map[string]string, []string) {
+	var allowed []string
+	for _, route := range r.Routes {
+		params, ok := matchPath(route.Path, path)
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(route.Method, method) {
+			return route, params, nil
+		}
+		allowed = append(allowed, route.Method)
+	}
+	return nil, nil, allowed
+}
+
+// Handle リクエストを、メソッドとパスに一致するコントローラーに振り分ける。
+// API Gatewayで関数ごとに個別のパスで受け付けた場合も、/{proxy+} でまとめて受け付けた場合も同じように振り分ける。
+// 一致するルートがない場合も含めて、リクエストIDの付与とアクセスログの出力を行う
+func (r *Router) Handle(request controller.Request) controller.Response {
+	route, params, allowed := r.matchRequest(request)
+	if route == nil {
+		// ルートが一致しなかったことが分かるよう、アクセスログのルートは空にする
+		request.Resource = ""
+		return controller.Middleware(func(controller.Request) controller.Response {
+			if len(allowed) > 0 {
+				return controller.Response405(allowed)
+			}
+			return controller.Response404()
+		})(request)
+	}
+
+	// コントローラーからは個別のパスで受け付けた場合と同じに見えるようにする
+	request.Resource = route.Path
+	request.PathParameters = params
+
+	return controller.Middleware(route.Handler)(request)
+}
+
+// matchRequest リソースのテンプレートが一致するルートを優先し、なければパスから探す
+func (r *Router) matchRequest(request controller.Request) (*Route, map[string]string, []string) {
+	for _, route := range r.Routes {
+		if route.Path == request.Resource && strings.EqualFold(route.Method, request.HTTPMethod) {
+			return route, request.PathParameters, nil
+		}
+	}
+	return r.Match(request.HTTPMethod, request.Path)
+}
+
+// matchPath パスがテンプレートに一致する場合に、パスパラメータを返す
+func matchPath(template, path string) (map[string]string, bool) {
+	templateSegments := splitPath(template)
+	pathSegments := splitPath(path)
+	if len(templateSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
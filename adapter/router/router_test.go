@@ -0,0 +1,176 @@
+package router
+
+import (
+	"clean-serverless-book-sample-v2/adapter/controller"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoHandler 受け取ったリクエストをそのままJSONで返す
+func echoHandler(request controller.Request) controller.Response {
+	b, _ := json.Marshal(request)
+	return controller.Response{
+		StatusCode: 200,
+		Headers:    map[string]string{"X-Resource": request.Resource},
+		Body:       string(b),
+	}
+}
+
+// TestRouter_Match ルートの検索
+func TestRouter_Match(t *testing.T) {
+	r := NewRouter(Routes())
+
+	cases := []struct {
+		Method   string
+		Path     string
+		Expected string
+		Params   map[string]string
+		Allowed  []string
+	}{
+		{Method: "GET", Path: "/v1/users", Expected: "/v1/users", Params: map[string]string{}},
+		{Method: "get", Path: "/v1/users/", Expected: "/v1/users", Params: map[string]string{}},
+		{Method: "PUT", Path: "/v1/users/1", Expected: "/v1/users/{user_id}", Params: map[string]string{"user_id": "1"}},
+		{
+			Method:   "DELETE",
+			Path:     "/v1/users/1/microposts/2",
+			Expected: "/v1/users/{user_id}/microposts/{micropost_id}",
+			Params:   map[string]string{"user_id": "1", "micropost_id": "2"},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/v1/users/1/following/2",
+			Expected: "/v1/users/{user_id}/following/{target_id}",
+			Params:   map[string]string{"user_id": "1", "target_id": "2"},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/v1/users/1/microposts/2/likes",
+			Expected: "/v1/users/{user_id}/microposts/{micropost_id}/likes",
+			Params:   map[string]string{"user_id": "1", "micropost_id": "2"},
+		},
+		{Method: "GET", Path: "/v1/microposts", Expected: "/v1/microposts", Params: map[string]string{}},
+		{
+			Method:   "GET",
+			Path:     "/v1/microposts/2/replies",
+			Expected: "/v1/microposts/{micropost_id}/replies",
+			Params:   map[string]string{"micropost_id": "2"},
+		},
+		{Method: "GET", Path: "/v1/users/1/followers", Expected: "/v1/users/{user_id}/followers", Params: map[string]string{"user_id": "1"}},
+		{Method: "PATCH", Path: "/v1/users/1", Allowed: []string{"GET", "PUT", "DELETE"}},
+		{Method: "GET", Path: "/v1/unknown"},
+		{Method: "GET", Path: "/v1/users/1/microposts/2/3"},
+	}
+
+	for _, c := range cases {
+		route, params, allowed := r.Match(c.Method, c.Path)
+		if c.Expected == "" {
+			assert.Nil(t, route, c.Path)
+			assert.Equal(t, c.Allowed, allowed, c.Path)
+			continue
+		}
+		if assert.NotNil(t, route, c.Path) {
+			assert.Equal(t, c.Expected, route.Path)
+			assert.Equal(t, c.Params, params)
+		}
+	}
+}
+
+// TestHTTPHandler net/httpのリクエストの変換
+func TestHTTPHandler(t *testing.T) {
+	handler := NewHTTPHandler(NewRouter([]*Route{
+		{Method: "POST", Path: "/v1/users/{user_id}/microposts", Handler: echoHandler},
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/users/10/microposts?limit=5&cursor=abc", strings.NewReader(`{"content":"hello"}`))
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "/v1/users/{user_id}/microposts", rec.Header().Get("X-Resource"))
+
+	var request controller.Request
+	err := json.Unmarshal(rec.Body.Bytes(), &request)
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", request.HTTPMethod)
+	assert.Equal(t, "/v1/users/10/microposts", request.Path)
+	assert.Equal(t, map[string]string{"user_id": "10"}, request.PathParameters)
+	assert.Equal(t, map[string]string{"limit": "5", "cursor": "abc"}, request.QueryStringParameters)
+	assert.Equal(t, "Bearer token", request.Headers["Authorization"])
+	assert.Equal(t, `{"content":"hello"}`, request.Body)
+}
+
+// TestHTTPHandler_NotFound 存在しないルートの場合
+func TestHTTPHandler_NotFound(t *testing.T) {
+	handler := NewHTTPHandler(NewRouter([]*Route{
+		{Method: "GET", Path: "/v1/users", Handler: echoHandler},
+		{Method: "POST", Path: "/v1/users", Handler: echoHandler},
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("DELETE", "/v1/users", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+}
+
+// TestRouter_Handle API Gatewayのリクエストの振り分け
+func TestRouter_Handle(t *testing.T) {
+	r := NewRouter([]*Route{
+		{Method: "GET", Path: "/v1/users/{user_id}", Handler: echoHandler},
+		{Method: "PUT", Path: "/v1/users/{user_id}", Handler: echoHandler},
+	})
+
+	cases := []struct {
+		Msg     string
+		Request controller.Request
+	}{
+		{
+			Msg: "関数ごとに個別のパスで受け付けた場合",
+			Request: controller.Request{
+				HTTPMethod:     "GET",
+				Resource:       "/v1/users/{user_id}",
+				Path:           "/v1/users/1",
+				PathParameters: map[string]string{"user_id": "1"},
+			},
+		},
+		{
+			Msg: "/{proxy+} でまとめて受け付けた場合",
+			Request: controller.Request{
+				HTTPMethod:     "GET",
+				Resource:       "/{proxy+}",
+				Path:           "/v1/users/1",
+				PathParameters: map[string]string{"proxy": "v1/users/1"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		res := r.Handle(c.Request)
+		assert.Equal(t, 200, res.StatusCode, c.Msg)
+
+		var request controller.Request
+		err := json.Unmarshal([]byte(res.Body), &request)
+		assert.NoError(t, err)
+		assert.Equal(t, "/v1/users/{user_id}", request.Resource, c.Msg)
+		assert.Equal(t, map[string]string{"user_id": "1"}, request.PathParameters, c.Msg)
+		assert.NotEmpty(t, res.Headers[controller.RequestIDHeader], c.Msg)
+	}
+
+	// 存在しないルートでもリクエストIDを返す
+	res := r.Handle(controller.Request{RequestID: "req-1", HTTPMethod: "GET", Resource: "/{proxy+}", Path: "/v1/unknown"})
+	assert.Equal(t, 404, res.StatusCode)
+	assert.Equal(t, "req-1", res.Headers[controller.RequestIDHeader])
+
+	// パスは一致するがメソッドが一致しないルート
+	res = r.Handle(controller.Request{HTTPMethod: "DELETE", Resource: "/{proxy+}", Path: "/v1/users/1"})
+	assert.Equal(t, 405, res.StatusCode)
+	assert.Equal(t, "GET, PUT", res.Headers["Allow"])
+}
@@ -0,0 +1,33 @@
+package adapter
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimelineResource 公開タイムラインのインデックス項目のDynamoDB上のデータ構造を表した構造体。
+// PKは投稿日ごとに、書き込みが集中しないようIDで複数のパーティションに分ける。
+// SKはマイクロポストのIDのため、日付を遡りながら各パーティションをSKの降順に読み、IDの降順にまとめると新しい順に並ぶ。
+// マイクロポストの本文は持たず、マイクロポストの項目から取得する
+type TimelineResource struct {
+	ResourceSchema
+	MicropostID uint64             `dynamo:"MicropostID"`
+	UserID      uint64             `dynamo:"UserID"`
+	CreatedAt   time.Time          `dynamo:"CreatedAt"`
+	Mapper      *DynamoModelMapper `dynamo:"-"`
+}
+
+func NewTimelineResource(micropostID, userID uint64, createdAt time.Time, mapper *DynamoModelMapper) *TimelineResource {
+	return &TimelineResource{
+		MicropostID: micropostID,
+		UserID:      userID,
+		CreatedAt:   createdAt,
+		Mapper:      mapper,
+	}
+}
+
+// SetKeys テーブルのキーを設定する
+func (t *TimelineResource) SetKeys() {
+	t.ResourceSchema.PK = t.Mapper.GetTimelinePartitionKey(t.CreatedAt, t.Mapper.GetTimelineShard(t.MicropostID))
+	t.ResourceSchema.SK = fmt.Sprintf("%011d", t.MicropostID)
+}
@@ -0,0 +1,90 @@
+// Package tracing OpenTelemetryのトレースを、環境変数で指定したエクスポーターに書き出す
+package tracing
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+)
+
+// InstrumentationName スパンを作成するTracerの名前
+const InstrumentationName = "clean-serverless-book-sample-v2"
+
+const (
+	// ExporterNone トレースを記録しない
+	ExporterNone = ""
+	// ExporterStdout スパンをJSON形式で標準出力に書き出す
+	ExporterStdout = "stdout"
+	// ExporterOTLP スパンをOTLP/HTTPでコレクターに送信する
+	ExporterOTLP = "otlp"
+)
+
+// DefaultOTLPEndpoint OTLP/HTTPのエンドポイントが指定されていない場合の送信先。
+// Lambdaの場合はADOT Collectorのレイヤーを追加して、localhostで受け付けるようにする
+const DefaultOTLPEndpoint = "http://localhost:4318"
+
+// NewProvider exporterで指定したエクスポーターにスパンを書き出すTracerProviderを生成する。
+// exporterが空の場合はトレースを記録しないため、nilを返す
+func NewProvider(exporter string, out io.Writer, otlpEndpoint string) (*sdktrace.TracerProvider, error) {
+	switch exporter {
+	case ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Lambdaは呼び出しの合間に停止するため、バッファリングせずに書き出す
+		return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)), nil
+	case ExporterOTLP:
+		exp, err := newOTLPExporter(otlpEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		// 送信はまとめて行い、呼び出しの終わりに ForceFlush で送信しきる
+		return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp)), nil
+	default:
+		return nil, errors.Errorf("unknown tracing exporter: %s", exporter)
+	}
+}
+
+// newOTLPExporter endpointにOTLP/HTTPで送信するエクスポーターを生成する。endpointが空の場合は DefaultOTLPEndpoint に送信する
+func newOTLPExporter(endpoint string, opts ...otlptracehttp.Option) (*otlptrace.Exporter, error) {
+	if endpoint == "" {
+		endpoint = DefaultOTLPEndpoint
+	}
+	opts = append([]otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}, opts...)
+	exp, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return exp, nil
+}
+
+// ForceFlush グローバルなTracerProviderにバッファリングされているスパンを全て書き出す。
+// トレースを記録していない場合は何もしない
+func ForceFlush(ctx context.Context) error {
+	flusher, ok := otel.GetTracerProvider().(interface {
+		ForceFlush(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	return errors.WithStack(flusher.ForceFlush(ctx))
+}
+
+// End errがnilでなければスパンにエラーとして記録してから、スパンを終了する
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestNewProvider 指定したエクスポーターごとのTracerProvider
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(ExporterNone, nil, "")
+	assert.NoError(t, err)
+	assert.Nil(t, provider)
+
+	_, err = NewProvider("zipkin", nil, "")
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	provider, err = NewProvider(ExporterStdout, &buf, "")
+	require.NoError(t, err)
+	_, span := provider.Tracer("test").Start(context.Background(), "stdout-span")
+	span.End()
+
+	// バッファリングせずに書き出される
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &written))
+	assert.Equal(t, "stdout-span", written["Name"])
+}
+
+// TestNewProvider_OTLP スパンがOTLP/HTTPでコレクターに送信されること
+func TestNewProvider_OTLP(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		received, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(ExporterOTLP, nil, server.URL)
+	require.NoError(t, err)
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent",
+		trace.WithSpanKind(trace.SpanKindServer))
+	_, child := provider.Tracer("test").Start(ctx, "child")
+	End(child, errors.New("failed"))
+	parent.End()
+
+	// 呼び出しの終わりと同じく、ForceFlushで送信しきる
+	require.NoError(t, provider.ForceFlush(context.Background()))
+
+	var req coltracepb.ExportTraceServiceRequest
+	require.NoError(t, proto.Unmarshal(received, &req))
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	assert.Equal(t, "test", req.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, spans[1].SpanId, spans[0].ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, spans[0].Status.Code)
+	assert.Equal(t, "failed", spans[0].Status.Message)
+	assert.Equal(t, "parent", spans[1].Name)
+	assert.Equal(t, tracepb.Span_SPAN_KIND_SERVER, spans[1].Kind)
+}
+
+// TestNewOTLPExporter_Error 送信先がエラーを返した場合はエラーになること
+func TestNewOTLPExporter_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter, err := newOTLPExporter(server.URL, otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}))
+	require.NoError(t, err)
+	recorder := &spanCollector{}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "span")
+	span.End()
+
+	assert.Error(t, exporter.ExportSpans(ctx, recorder.spans))
+}
+
+// spanCollector 終了したスパンを保持する
+type spanCollector struct {
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (c *spanCollector) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	c.spans = append(c.spans, spans...)
+	return nil
+}
+
+func (c *spanCollector) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"github.com/guregu/dynamo"
 	"github.com/memememomo/nomof"
 	"github.com/pkg/errors"
@@ -39,6 +40,29 @@ func (u *UserEmailUniqGenerator) NewUserEmailUniqByUser(user *UserResource) *Use
 	}
 }
 
+// GetByEmail メールアドレスから重複チェック用のレコードを取得する
+func (u *UserEmailUniqGenerator) GetByEmail(ctx context.Context, email string) (*UserEmailUniq, error) {
+	table, err := u.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var uniq UserEmailUniq
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserEmailUniqGenerator.GetByEmail")
+	err = table.
+		Get(u.PKName, email).
+		Range(u.SKName, dynamo.Equal, u.Mapper.GetEntityNameFromStruct(UserResource{})).
+		ConsumedCapacity(&cc).
+		OneWithContext(opCtx, &uniq)
+	end(&cc, err)
+	if err != nil {
+		return nil, errors.WithStack(translateDynamoError(err))
+	}
+
+	return &uniq, nil
+}
+
 func (u *UserEmailUniqGenerator) BuildQueryCreateByUser(user *UserResource) (*dynamo.Put, error) {
 	table, err := u.Client.ConnectTable()
 	if err != nil {
@@ -2,9 +2,11 @@ package adapter
 
 import (
 	"clean-serverless-book-sample-v2/domain"
+	"context"
 	"github.com/guregu/dynamo"
 	"github.com/memememomo/nomof"
 	"github.com/pkg/errors"
+	"time"
 )
 
 // UserOperator ユーザーを操作する構造体
@@ -12,11 +14,15 @@ type UserOperator struct {
 	Client                 *ResourceTableOperator
 	Mapper                 *DynamoModelMapper
 	UserEmailUniqGenerator *UserEmailUniqGenerator
+	Cursor                 *PagingCursor
 }
 
-func (u *UserOperator) getUserResourceByID(id uint64) (*UserResource, error) {
+// userListCursorScope ユーザー一覧のカーソルを署名する際のスコープ
+const userListCursorScope = "users"
+
+func (u *UserOperator) getUserResourceByID(ctx context.Context, id uint64) (*UserResource, error) {
 	var user UserResource
-	_, err := u.Mapper.GetEntityByID(id, &UserResource{}, &user)
+	_, err := u.Mapper.GetEntityByID(ctx, id, &UserResource{}, &user)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -24,67 +30,131 @@ func (u *UserOperator) getUserResourceByID(id uint64) (*UserResource, error) {
 }
 
 // GetUserByEmail メールアドレスからユーザー情報を取得する
-func (u *UserOperator) GetUserByEmail(email string) (*domain.UserModel, error) {
-	table, err := u.Client.ConnectTable()
+func (u *UserOperator) GetUserByEmail(ctx context.Context, email string) (*domain.UserModel, error) {
+	uniq, err := u.UserEmailUniqGenerator.GetByEmail(ctx, email)
 	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return nil, errors.WithStack(domain.ErrNotFound)
+		}
 		return nil, errors.WithStack(err)
 	}
 
-	fb := nomof.NewBuilder()
-	fb.Equal("Email", email)
-	fb.BeginsWith("PK", u.Mapper.GetEntityNameFromStruct(UserResource{}))
+	return u.GetUserByID(ctx, uniq.UserID)
+}
 
-	var usersDynamo []UserResource
-	err = table.Scan().Filter(fb.JoinAnd(), fb.Arg...).All(&usersDynamo)
+// Execute IDからユーザー情報を取得する
+func (u *UserOperator) GetUserByID(ctx context.Context, id uint64) (*domain.UserModel, error) {
+	userResource, err := u.getUserResourceByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return nil, errors.WithStack(domain.ErrNotFound)
+		}
 		return nil, errors.WithStack(err)
 	}
+	return userResource.Model(), nil
+}
 
-	if len(usersDynamo) == 0 {
-		return nil, errors.WithStack(domain.ErrNotFound)
+// GetUsersByIDs 指定したIDの順にユーザーをまとめて取得する。存在しないユーザーは結果に含めない
+func (u *UserOperator) GetUsersByIDs(ctx context.Context, ids []uint64) ([]*domain.UserModel, error) {
+	users := make([]*domain.UserModel, 0, len(ids))
+	if len(ids) == 0 {
+		return users, nil
 	}
 
-	return &usersDynamo[0].UserModel, nil
-}
-
-// Execute IDからユーザー情報を取得する
-func (u *UserOperator) GetUserByID(id uint64) (*domain.UserModel, error) {
-	userResource, err := u.getUserResourceByID(id)
+	table, err := u.Client.ConnectTable()
 	if err != nil {
-		if err.Error() == dynamo.ErrNotFound.Error() {
-			return nil, errors.WithStack(domain.ErrNotFound)
-		}
 		return nil, errors.WithStack(err)
 	}
-	return &userResource.UserModel, nil
+
+	keys := make([]dynamo.Keyed, len(ids))
+	for i, id := range ids {
+		key := NewUserResource(&domain.UserModel{ID: id}, u.Mapper)
+		keys[i] = dynamo.Keys{key.PK(), key.SK()}
+	}
+
+	var userResource []UserResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.GetUsersByIDs")
+	err = table.
+		Batch(u.Mapper.PKName, u.Mapper.SKName).
+		Get(keys...).
+		ConsumedCapacity(&cc).
+		AllWithContext(opCtx, &userResource)
+	end(&cc, err)
+	if err != nil && !errors.Is(err, dynamo.ErrNotFound) {
+		return nil, errors.WithStack(translateDynamoError(err))
+	}
+
+	found := make(map[uint64]*UserResource, len(userResource))
+	for i := range userResource {
+		found[userResource[i].ID()] = &userResource[i]
+	}
+	for _, id := range ids {
+		if r, ok := found[id]; ok {
+			users = append(users, r.Model())
+		}
+	}
+
+	return users, nil
 }
 
-// Execute ユーザー一覧を取得する
-func (u *UserOperator) GetUsers() ([]*domain.UserModel, error) {
+// GetUsers ユーザー一覧を取得する
+func (u *UserOperator) GetUsers(ctx context.Context, paging *domain.Paging) ([]*domain.UserModel, string, error) {
 	table, err := u.Client.ConnectTable()
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, "", errors.WithStack(err)
 	}
 
 	fb := nomof.NewBuilder()
 	fb.BeginsWith("PK", u.Mapper.GetEntityNameFromStruct(UserResource{}))
 
+	scan := table.Scan().Filter(fb.JoinAnd(), fb.Arg...)
+
+	if paging != nil && paging.Cursor != "" {
+		key, err := u.Cursor.Decode(userListCursorScope, paging.Cursor)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		scan = scan.StartFrom(key)
+	}
+
+	// 次のページが存在するかを判定するため、1件多く取得する
+	if paging != nil && paging.Limit > 0 {
+		scan = scan.Limit(int64(paging.Limit + 1))
+	}
+
 	var userDynamo []UserResource
-	err = table.Scan().Filter(fb.JoinAnd(), fb.Arg...).All(&userDynamo)
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.GetUsers")
+	err = scan.ConsumedCapacity(&cc).AllWithContext(opCtx, &userDynamo)
+	end(&cc, err)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, "", errors.WithStack(translateDynamoError(err))
+	}
+
+	var nextCursor string
+	if paging != nil && paging.Limit > 0 && len(userDynamo) > paging.Limit {
+		userDynamo = userDynamo[:paging.Limit]
+		last := userDynamo[len(userDynamo)-1]
+		nextCursor, err = u.Cursor.Encode(userListCursorScope, map[string]string{
+			u.Mapper.PKName: last.ResourceSchema.PK,
+			u.Mapper.SKName: last.ResourceSchema.SK,
+		})
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
 	}
 
 	var users = make([]*domain.UserModel, len(userDynamo))
 	for i := range userDynamo {
-		users[i] = &userDynamo[i].UserModel
+		users[i] = userDynamo[i].Model()
 	}
 
-	return users, nil
+	return users, nextCursor, nil
 }
 
 // CreateUser ユーザーを新規作成する
-func (u *UserOperator) CreateUser(userModel *domain.UserModel) (*domain.UserModel, error) {
+func (u *UserOperator) CreateUser(ctx context.Context, userModel *domain.UserModel) (*domain.UserModel, error) {
 	conn, err := u.Client.ConnectDB()
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -94,39 +164,55 @@ func (u *UserOperator) CreateUser(userModel *domain.UserModel) (*domain.UserMode
 
 	tx := conn.WriteTx()
 
-	r, err := u.Mapper.BuildQueryCreate(userResource)
+	creates, err := u.Mapper.BuildQueryCreate(ctx, userResource)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	for _, r := range creates {
+		tx.Put(r)
+	}
 
 	uniq, err := u.UserEmailUniqGenerator.BuildQueryCreateByUser(userResource)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	err = tx.Put(r).Put(uniq).Run()
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.CreateUser")
+	err = tx.Put(uniq).ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		// 同じメールアドレスのユーザーが同時に作成された場合
+		if isTransactionConditionFailed(err, len(creates)) {
+			return nil, errors.WithStack(domain.ErrConflict.Wrap(err))
+		}
+		return nil, errors.WithStack(translateDynamoError(err))
 	}
 
-	return &userResource.UserModel, nil
+	return userResource.Model(), nil
 }
 
-// UpdateUser ユーザーを更新する
-func (u *UserOperator) UpdateUser(newUserModel *domain.UserModel) error {
+// UpdateUser ユーザーを更新する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ更新する
+func (u *UserOperator) UpdateUser(ctx context.Context, newUserModel *domain.UserModel) error {
 	conn, err := u.Client.ConnectDB()
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	oldUserResource, err := u.getUserResourceByID(newUserModel.ID)
+	oldUserResource, err := u.getUserResourceByID(ctx, newUserModel.ID)
 	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return errors.WithStack(domain.ErrNotFound)
+		}
 		return errors.WithStack(err)
 	}
 
 	newUserResource := *oldUserResource
 	newUserResource.Email = newUserModel.Email
 	newUserResource.Name = newUserModel.Name
+	if newUserModel.Version != 0 {
+		newUserResource.SetVersion(newUserModel.Version)
+	}
 
 	tx := conn.WriteTx()
 
@@ -135,7 +221,7 @@ func (u *UserOperator) UpdateUser(newUserModel *domain.UserModel) error {
 		return errors.WithStack(err)
 	}
 
-	query := tx.Put(r)
+	query := tx.Update(r)
 
 	if oldUserResource.Email != newUserResource.Email {
 		uniqDelete, err := u.UserEmailUniqGenerator.BuildQueryDeleteByUser(oldUserResource)
@@ -153,17 +239,27 @@ func (u *UserOperator) UpdateUser(newUserModel *domain.UserModel) error {
 			Delete(uniqDelete)
 	}
 
-	err = query.Run()
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.UpdateUser")
+	err = query.ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
 
 	if err != nil {
-		return errors.WithStack(err)
+		if isTransactionConditionFailed(err, 0) {
+			return errors.WithStack(domain.ErrPreconditionFailed)
+		}
+		// 変更後のメールアドレスが同時に他のユーザーに登録された場合
+		if isTransactionConditionFailed(err, 1) {
+			return errors.WithStack(domain.ErrConflict.Wrap(err))
+		}
+		return errors.WithStack(translateDynamoError(err))
 	}
 
 	return nil
 }
 
-// DeleteUser ユーザー情報を削除する
-func (u *UserOperator) DeleteUser(userModel *domain.UserModel) error {
+// DeleteUser ユーザー情報を削除する。バージョンが指定されている場合は、保存されているバージョンと一致する場合のみ削除する
+func (u *UserOperator) DeleteUser(ctx context.Context, userModel *domain.UserModel) error {
 	conn, err := u.Client.ConnectDB()
 	if err != nil {
 		return errors.WithStack(err)
@@ -173,7 +269,7 @@ func (u *UserOperator) DeleteUser(userModel *domain.UserModel) error {
 
 	userResource := NewUserResource(userModel, u.Mapper)
 
-	r, err := u.Mapper.BuildQueryDelete(userResource)
+	r, err := u.Mapper.BuildQueryDeleteWithVersion(userResource)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -183,10 +279,144 @@ func (u *UserOperator) DeleteUser(userModel *domain.UserModel) error {
 		return errors.WithStack(err)
 	}
 
-	err = tx.Delete(r).Delete(uniq).Run()
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.DeleteUser")
+	err = tx.Delete(r).Delete(uniq).ConsumedCapacity(&cc).RunWithContext(opCtx)
+	end(&cc, err)
 	if err != nil {
-		return errors.WithStack(err)
+		if isTransactionConditionFailed(err, 0) {
+			return errors.WithStack(domain.ErrPreconditionFailed)
+		}
+		return errors.WithStack(translateDynamoError(err))
 	}
 
 	return nil
 }
+
+// maxRepairUserStatsAttempts 集計中にマイクロポストが作成・削除された場合に集計をやり直す回数
+const maxRepairUserStatsAttempts = 3
+
+// RepairUserStats マイクロポストの件数と投稿日時を、保存されているマイクロポストから計算し直して保存する。
+// 投稿日時は削除では戻さないため、保存されている値より最初の投稿日時を遅く、最後の投稿日時を早くはしない。
+// 集計中にマイクロポストが作成・削除された場合は集計をやり直す
+func (u *UserOperator) RepairUserStats(ctx context.Context, id uint64) (*domain.UserModel, error) {
+	table, err := u.Client.ConnectTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for attempt := 0; attempt < maxRepairUserStatsAttempts; attempt++ {
+		userResource, err := u.getUserResourceByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, dynamo.ErrNotFound) {
+				return nil, errors.WithStack(domain.ErrNotFound)
+			}
+			return nil, errors.WithStack(err)
+		}
+		userResource.Mapper = u.Mapper
+
+		count, err := u.countMicroposts(ctx, table, id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		// GSI1は作成日時順に並んでいるため、両端の1件ずつが最初と最後の投稿になる
+		first, err := u.getPostedAt(ctx, table, id, dynamo.Ascending)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		last, err := u.getPostedAt(ctx, table, id, dynamo.Descending)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		oldCount := userResource.MicropostsCount
+		oldLastPostedAt := userResource.LastPostedAt
+		userResource.MicropostsCount = count
+		if first != nil && (userResource.FirstPostedAt == nil || first.Before(*userResource.FirstPostedAt)) {
+			userResource.FirstPostedAt = first
+		}
+		if last != nil && (userResource.LastPostedAt == nil || last.After(*userResource.LastPostedAt)) {
+			userResource.LastPostedAt = last
+		}
+
+		// 集計を始めてからマイクロポストの件数と最後の投稿日時が変わっていない場合のみ保存する
+		cond := "attribute_exists($) AND (attribute_not_exists($) OR $ = ?)"
+		args := []interface{}{u.Mapper.PKName, userMicropostsCountAttribute, userMicropostsCountAttribute, oldCount}
+		if oldLastPostedAt == nil {
+			cond += " AND attribute_not_exists($)"
+			args = append(args, userLastPostedAtAttribute)
+		} else {
+			// formatPostedAt より前に保存された値は、time.Timeの既定の形式で保存されている
+			cond += " AND $ IN (?, ?)"
+			args = append(args, userLastPostedAtAttribute, formatPostedAt(*oldLastPostedAt), *oldLastPostedAt)
+		}
+
+		query := table.
+			Update(u.Mapper.PKName, userResource.PK()).
+			Range(u.Mapper.SKName, userResource.SK()).
+			Set(userMicropostsCountAttribute, userResource.MicropostsCount).
+			If(cond, args...)
+		// 投稿日時は戻さないため、投稿していない場合は保存されている値もない
+		if userResource.FirstPostedAt != nil {
+			query.Set(userFirstPostedAtAttribute, formatPostedAt(*userResource.FirstPostedAt))
+		}
+		if userResource.LastPostedAt != nil {
+			query.Set(userLastPostedAtAttribute, formatPostedAt(*userResource.LastPostedAt))
+		}
+
+		var cc dynamo.ConsumedCapacity
+		opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.RepairUserStats")
+		err = query.ConsumedCapacity(&cc).RunWithContext(opCtx)
+		end(&cc, err)
+		if err != nil {
+			if isConditionFailed(err) {
+				continue
+			}
+			return nil, errors.WithStack(translateDynamoError(err))
+		}
+
+		return userResource.Model(), nil
+	}
+
+	return nil, errors.WithStack(domain.ErrConflict)
+}
+
+// countMicroposts ユーザーのマイクロポストの件数を、項目を読み込まずに数える
+func (u *UserOperator) countMicroposts(ctx context.Context, table *dynamo.Table, id uint64) (int, error) {
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.RepairUserStats")
+	count, err := table.
+		Get("GSI1PK", u.Mapper.GetUserPartitionKey(id)).
+		Index(GSI1Name).
+		ConsumedCapacity(&cc).
+		CountWithContext(opCtx)
+	end(&cc, err)
+	if err != nil {
+		return 0, errors.WithStack(translateDynamoError(err))
+	}
+	return int(count), nil
+}
+
+// getPostedAt ユーザーのマイクロポストを作成日時順に並べ、orderの先頭の1件の作成日時を取得する。投稿していない場合はnilを返す
+func (u *UserOperator) getPostedAt(ctx context.Context, table *dynamo.Table, id uint64, order dynamo.Order) (*time.Time, error) {
+	var micropostResource MicropostResource
+	var cc dynamo.ConsumedCapacity
+	opCtx, end := u.Mapper.startOperation(ctx, "UserOperator.RepairUserStats")
+	err := table.
+		Get("GSI1PK", u.Mapper.GetUserPartitionKey(id)).
+		Index(GSI1Name).
+		Order(order).
+		Limit(1).
+		ConsumedCapacity(&cc).
+		OneWithContext(opCtx, &micropostResource)
+	end(&cc, err)
+	if err != nil {
+		if errors.Is(err, dynamo.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(translateDynamoError(err))
+	}
+	postedAt := micropostResource.CreatedAt()
+	return &postedAt, nil
+}
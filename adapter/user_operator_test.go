@@ -0,0 +1,59 @@
+package adapter_test
+
+import (
+	"clean-serverless-book-sample-v2/adapter"
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/mocks"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+// setupDynamoUserOperator DynamoDB Local上にテーブルを作成し、ユーザーを操作するインスタンスを返す
+func setupDynamoUserOperator(t *testing.T) (*mocks.DynamoTableOperator, *adapter.UserOperator) {
+	t.Helper()
+	if os.Getenv("DYNAMO_LOCAL_ENDPOINT") == "" {
+		t.Skip("DYNAMO_LOCAL_ENDPOINT is not set")
+	}
+
+	backend := os.Getenv("REPOSITORY_BACKEND")
+	os.Setenv("REPOSITORY_BACKEND", "dynamo")
+	t.Cleanup(func() { os.Setenv("REPOSITORY_BACKEND", backend) })
+
+	tables := mocks.SetupDB(t)
+	t.Cleanup(tables.Cleanup)
+
+	return tables, tables.UserOperator.(*adapter.UserOperator)
+}
+
+// TestUserOperator_GetUserByEmail_Missing 重複チェック用のレコードが存在しないメールアドレスの場合はErrNotFoundを返すこと
+func TestUserOperator_GetUserByEmail_Missing(t *testing.T) {
+	tables, users := setupDynamoUserOperator(t)
+	tables.CreateUserMock(t, 1)
+
+	_, err := users.GetUserByEmail(context.Background(), "missing@example.com")
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+// TestUserOperator_GetUserByEmail_DeletedUser 重複チェック用のレコードだけが残り、ユーザーが削除されている場合はErrNotFoundを返すこと
+func TestUserOperator_GetUserByEmail_DeletedUser(t *testing.T) {
+	tables, users := setupDynamoUserOperator(t)
+	user := tables.CreateUserMock(t, 1)
+
+	table, err := tables.Operator.ConnectTable()
+	require.NoError(t, err)
+
+	// ユーザー本体だけを削除し、重複チェック用のレコードを残す
+	resource := adapter.NewUserResource(user, users.Mapper)
+	require.NoError(t, table.Delete(users.Mapper.PKName, resource.PK()).Range(users.Mapper.SKName, resource.SK()).Run())
+
+	uniq, err := users.UserEmailUniqGenerator.GetByEmail(context.Background(), user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, uniq.UserID)
+
+	_, err = users.GetUserByEmail(context.Background(), user.Email)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
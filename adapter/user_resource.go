@@ -15,11 +15,18 @@ type UserResource struct {
 
 func NewUserResource(userModel *domain.UserModel, mapper *DynamoModelMapper) *UserResource {
 	return &UserResource{
-		UserModel: *userModel,
-		Mapper:    mapper,
+		DynamoResourceBase: DynamoResourceBase{Version: userModel.Version},
+		UserModel:          *userModel,
+		Mapper:             mapper,
 	}
 }
 
+// Model DynamoDB上のバージョンを反映したドメインモデルを返す
+func (u *UserResource) Model() *domain.UserModel {
+	u.UserModel.Version = u.Version()
+	return &u.UserModel
+}
+
 // DynamoResourceインタフェースの実装
 
 func (u *UserResource) EntityName() string {
@@ -73,3 +80,21 @@ func (u *UserResource) UpdatedAt() time.Time {
 func (u *UserResource) SetUpdatedAt(t time.Time) {
 	u.DynamoResourceBase.UpdatedAt = t
 }
+
+// DynamoAggregatedResourceインタフェースの実装
+
+// 集計値の属性名。マイクロポストの作成・削除と同じトランザクションで更新する
+const (
+	userMicropostsCountAttribute = "MicropostsCount"
+	userFirstPostedAtAttribute   = "FirstPostedAt"
+	userLastPostedAtAttribute    = "LastPostedAt"
+)
+
+func (u *UserResource) AggregatedAttributes() []string {
+	return []string{userMicropostsCountAttribute, userFirstPostedAtAttribute, userLastPostedAtAttribute}
+}
+
+// formatPostedAt 投稿日時を保存する形式。条件式で文字列のまま前後を比較できるよう、UTCの固定長にする
+func formatPostedAt(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
@@ -0,0 +1,39 @@
+// localserver API Gatewayを使わずに、全てのAPIをローカルのHTTPサーバーで提供する。
+//
+//	DYNAMO_LOCAL_ENDPOINT=http://localhost:8000 DYNAMO_TABLE_NAME=ResourceTable \
+//	DYNAMO_PK_NAME=PK DYNAMO_SK_NAME=SK DISABLE_ENV_DECRYPT=1 JWT_HS256_SECRET=secret \
+//	PAGING_CURSOR_SECRET=secret \
+//	go run ./cmd/localserver -create-table
+//
+// REPOSITORY_BACKEND=memory を指定すると、DynamoDB Localも使わずにメモリ上にデータを保存する
+package main
+
+import (
+	"clean-serverless-book-sample-v2/adapter/router"
+	"clean-serverless-book-sample-v2/registry"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	createTable := flag.Bool("create-table", false, "create the DynamoDB table before serving")
+	flag.Parse()
+
+	f := registry.GetFactory()
+	if err := f.Envs.LoadSecrets(); err != nil {
+		log.Fatalf("failed to load secrets: %+v", err)
+	}
+	if *createTable && !f.Envs.UseMemoryRepository() {
+		// 既にテーブルがある場合もエラーになるため、警告だけ出して起動を続ける
+		if err := f.BuildResourceTableOperator().CreateTableForTest(); err != nil {
+			log.Printf("failed to create table %q: %v", f.Envs.DynamoTableName(), err)
+		}
+	}
+
+	handler := router.NewHTTPHandler(router.NewRouter(router.Routes()))
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -0,0 +1,86 @@
+// repair_user_stats ユーザーのマイクロポスト件数と投稿日時を、保存されているマイクロポストから計算し直す。
+//
+//	DYNAMO_TABLE_NAME=ResourceTable DYNAMO_PK_NAME=PK DYNAMO_SK_NAME=SK PAGING_CURSOR_SECRET=secret \
+//	go run ./cmd/repair_user_stats [-user-id 1]
+//
+// -user-id を指定しない場合は全てのユーザーを対象にする
+package main
+
+import (
+	"clean-serverless-book-sample-v2/registry"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+)
+
+// pageSize 全てのユーザーを対象にする場合に、一度に取得するユーザー数
+const pageSize = 100
+
+func main() {
+	userID := flag.Uint64("user-id", 0, "repair only the user with this ID")
+	flag.Parse()
+
+	ctx := context.Background()
+	f := registry.GetFactory()
+	if err := f.Envs.LoadSecrets(); err != nil {
+		log.Fatalf("failed to load secrets: %+v", err)
+	}
+
+	if *userID != 0 {
+		if !repair(ctx, f, *userID) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	failed := 0
+	cursor := ""
+	for {
+		res, err := f.BuildGetUserList().Execute(ctx, &usecase.GetUserListRequest{
+			Limit:  pageSize,
+			Cursor: cursor,
+		})
+		if err != nil {
+			log.Fatalf("failed to list users: %+v", err)
+		}
+
+		for _, u := range res.Users {
+			if !repair(ctx, f, u.ID) {
+				failed++
+			}
+		}
+
+		if res.NextCursor == "" {
+			break
+		}
+		cursor = res.NextCursor
+	}
+
+	if failed > 0 {
+		log.Printf("failed to repair %d users", failed)
+		os.Exit(1)
+	}
+}
+
+// repair 1ユーザー分の集計値を計算し直し、成功したかどうかを返す
+func repair(ctx context.Context, f *registry.Factory, userID uint64) bool {
+	res, err := f.BuildRepairUserStats().Execute(ctx, &usecase.RepairUserStatsRequest{UserID: userID})
+	if err != nil {
+		log.Printf("user %d: %+v", userID, err)
+		return false
+	}
+
+	log.Printf("user %d: microposts_count=%d first_posted_at=%s last_posted_at=%s",
+		userID, res.User.MicropostsCount, formatTime(res.User.FirstPostedAt), formatTime(res.User.LastPostedAt))
+	return true
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+)
+
+// AccessPolicy リクエスト送信者がリソースを操作できるかどうかを判定する
+type AccessPolicy interface {
+	AuthorizeOwner(principal *Principal, ownerID uint64) error
+}
+
+// OwnerPolicy リソースの所有者本人と管理者のみに操作を許可する
+type OwnerPolicy struct {
+}
+
+func NewOwnerPolicy() *OwnerPolicy {
+	return &OwnerPolicy{}
+}
+
+// AuthorizeOwner 所有者本人または管理者でなければErrForbiddenを返す
+func (o *OwnerPolicy) AuthorizeOwner(principal *Principal, ownerID uint64) error {
+	if principal == nil {
+		return errors.WithStack(ErrForbidden)
+	}
+
+	if principal.HasRole(RoleAdmin) {
+		return nil
+	}
+
+	userID, ok := principal.UserID()
+	if !ok || userID != ownerID {
+		return errors.WithStack(ErrForbidden)
+	}
+
+	return nil
+}
@@ -1,7 +1,111 @@
 package domain
 
-import "github.com/pkg/errors"
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+// ErrorKind エラーの種類。コントローラーでレスポンスのステータスコードを決めるために使う
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindPreconditionFailed
+	KindUnavailable
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "NotFound"
+	case KindConflict:
+		return "Conflict"
+	case KindValidation:
+		return "Validation"
+	case KindUnauthorized:
+		return "Unauthorized"
+	case KindForbidden:
+		return "Forbidden"
+	case KindPreconditionFailed:
+		return "PreconditionFailed"
+	case KindUnavailable:
+		return "Unavailable"
+	default:
+		return "Internal"
+	}
+}
+
+// Error 種類を持ったエラー
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	// Field 入力値によるエラーの場合に、原因となった項目名
+	Field string
+	// Err 原因となったエラー
+	Err error
+}
+
+// NewError Error インスタンスを生成
+func NewError(kind ErrorKind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// NewValidationError 項目名を指定して、入力値によるエラーを生成
+func NewValidationError(field, message string) *Error {
+	return &Error{Kind: KindValidation, Message: message, Field: field}
+}
+
+// Wrap 原因となったエラーを保持したコピーを返す。errors.Isでは元のエラーと同じものとして扱われる
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is Wrapで生成したエラーを元のエラーと同じものとして扱う
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t.Err != nil {
+		return false
+	}
+	return t.Kind == e.Kind && t.Message == e.Message && t.Field == e.Field
+}
+
+// KindOf エラーの種類を返す。コンテキストのキャンセルや期限切れは KindUnavailable、
+// それ以外で Error を含まないエラーの場合は KindInternal を返す
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return KindUnavailable
+	}
+	return KindInternal
+}
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound           = NewError(KindNotFound, "not found")
+	ErrConflict           = NewError(KindConflict, "conflict")
+	ErrInvalidCursor      = NewValidationError("cursor", "invalid cursor")
+	ErrUnauthorized       = NewError(KindUnauthorized, "unauthorized")
+	ErrForbidden          = NewError(KindForbidden, "forbidden")
+	ErrPreconditionFailed = NewError(KindPreconditionFailed, "precondition failed")
+	ErrUnavailable        = NewError(KindUnavailable, "service unavailable")
 )
@@ -0,0 +1,13 @@
+package domain
+
+// LikeModel マイクロポストへのいいねのモデル
+type LikeModel struct {
+	// MicropostID いいねされたマイクロポストのID
+	MicropostID uint64
+	// UserID いいねしたユーザーのID
+	UserID uint64
+}
+
+func NewLikeModel(micropostID, userID uint64) *LikeModel {
+	return &LikeModel{MicropostID: micropostID, UserID: userID}
+}
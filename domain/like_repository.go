@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+// LikeRepository いいねのリポジトリ
+type LikeRepository interface {
+	// Like いいねする。すでにいいねしている場合は何もしない。
+	// マイクロポストかいいねするユーザーが存在しない場合はErrNotFoundを返す
+	Like(ctx context.Context, like *LikeModel) error
+	// Unlike いいねを取り消す。いいねしていない場合は何もしない。マイクロポストが存在しない場合はErrNotFoundを返す
+	Unlike(ctx context.Context, like *LikeModel) error
+	// GetLikes 指定されたマイクロポストへのいいねを、いいねしたユーザーのIDの順に取得する
+	GetLikes(ctx context.Context, micropostID uint64, paging *Paging) ([]*LikeModel, string, error)
+	// DeleteLikesByUserID 指定されたユーザーのいいねを全て取り消し、取り消した件数を返す
+	DeleteLikesByUserID(ctx context.Context, userID uint64) (int, error)
+}
@@ -5,8 +5,21 @@ type MicropostModel struct {
 	ID      uint64
 	Content string
 	UserID  uint64
+	// InReplyToID 返信先のマイクロポストのID。返信でない場合は0。返信先が削除されても返信は削除しない
+	InReplyToID uint64
+	// LikeCount いいねされた件数。いいねと同じトランザクションで更新する
+	LikeCount int
+	// ReplyCount 返信された件数。返信の作成・削除に合わせて更新する
+	ReplyCount int
+	// Version 楽観的排他制御に使うバージョン。0の場合はバージョンを指定しない
+	Version int
 }
 
 func NewMicropostModel(content string, userID uint64) *MicropostModel {
 	return &MicropostModel{Content: content, UserID: userID}
 }
+
+// NewReplyModel inReplyToIDのマイクロポストへの返信を生成する
+func NewReplyModel(content string, userID, inReplyToID uint64) *MicropostModel {
+	return &MicropostModel{Content: content, UserID: userID, InReplyToID: inReplyToID}
+}
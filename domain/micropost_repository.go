@@ -1,10 +1,18 @@
 package domain
 
+import "context"
+
 // MicropostRepository Micropostモデルのリポジトリ
 type MicropostRepository interface {
-	CreateMicropost(newMicropost *MicropostModel) (*MicropostModel, error)
-	UpdateMicropost(newMicropost *MicropostModel) error
-	GetMicropostByID(id uint64) (*MicropostModel, error)
-	GetMicropostsByUserID(userID uint64) ([]*MicropostModel, error)
-	DeleteMicropost(id uint64) error
+	CreateMicropost(ctx context.Context, newMicropost *MicropostModel) (*MicropostModel, error)
+	UpdateMicropost(ctx context.Context, newMicropost *MicropostModel) error
+	GetMicropostByID(ctx context.Context, id uint64) (*MicropostModel, error)
+	GetMicropostsByUserID(ctx context.Context, userID uint64, paging *Paging) ([]*MicropostModel, string, error)
+	// GetPublicTimeline 全てのユーザーのマイクロポストを新しい順に取得する。
+	// sinceIDより大きくmaxID以下のIDのものを対象とし、0の場合はその条件を指定しない
+	GetPublicTimeline(ctx context.Context, paging *Paging, sinceID, maxID uint64) ([]*MicropostModel, string, error)
+	// GetReplies 指定されたマイクロポストへの返信を古い順に取得する。返信先が削除されていても返信は取得できる
+	GetReplies(ctx context.Context, micropostID uint64, paging *Paging) ([]*MicropostModel, string, error)
+	DeleteMicropost(ctx context.Context, targetMicropost *MicropostModel) error
+	DeleteMicropostsByUserID(ctx context.Context, userID uint64) (int, error)
 }
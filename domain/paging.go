@@ -0,0 +1,7 @@
+package domain
+
+// Paging 一覧取得時のページング条件。Limitが0の場合は全件を対象とする
+type Paging struct {
+	Limit  int
+	Cursor string
+}
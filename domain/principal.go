@@ -0,0 +1,35 @@
+package domain
+
+import "strconv"
+
+// RoleAdmin 全てのリソースを操作できる管理者ロール
+const RoleAdmin = "admin"
+
+// Principal 認証済みのリクエスト送信者
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+func NewPrincipal(subject string, roles []string) *Principal {
+	return &Principal{Subject: subject, Roles: roles}
+}
+
+// UserID subjectをユーザーIDとして解釈する。数値でない場合はfalseを返す
+func (p *Principal) UserID() (uint64, bool) {
+	id, err := strconv.ParseUint(p.Subject, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// HasRole 指定されたロールを持っているかどうか
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package domain
+
+// RelationshipModel ユーザー間のフォロー関係のモデル
+type RelationshipModel struct {
+	// FollowerID フォローしているユーザーのID
+	FollowerID uint64
+	// FollowedID フォローされているユーザーのID
+	FollowedID uint64
+}
+
+func NewRelationshipModel(followerID, followedID uint64) *RelationshipModel {
+	return &RelationshipModel{FollowerID: followerID, FollowedID: followedID}
+}
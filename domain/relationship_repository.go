@@ -0,0 +1,13 @@
+package domain
+
+import "context"
+
+// RelationshipRepository フォロー関係のリポジトリ
+type RelationshipRepository interface {
+	Follow(ctx context.Context, relationship *RelationshipModel) error
+	Unfollow(ctx context.Context, relationship *RelationshipModel) error
+	IsFollowing(ctx context.Context, followerID, followedID uint64) (bool, error)
+	GetFollowers(ctx context.Context, userID uint64, paging *Paging) ([]*RelationshipModel, string, error)
+	GetFollowing(ctx context.Context, userID uint64, paging *Paging) ([]*RelationshipModel, string, error)
+	DeleteRelationshipsByUserID(ctx context.Context, userID uint64) (int, error)
+}
@@ -0,0 +1,1330 @@
+// Package repositorytest domain.UserRepository・domain.MicropostRepository・domain.RelationshipRepository・domain.LikeRepository の実装が満たすべき振る舞いをテストする。
+// 保存先ごとのテストから Run を呼び出して使う
+package repositorytest
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Repositories テスト対象のリポジトリ。同じ保存先を共有している必要がある
+type Repositories struct {
+	Users         domain.UserRepository
+	Microposts    domain.MicropostRepository
+	Relationships domain.RelationshipRepository
+	Likes         domain.LikeRepository
+	// SetNow 作成日時に使う現在時刻を差し替える。nilの場合は現在時刻に依存するテストをスキップする
+	SetNow func(now func() time.Time)
+}
+
+// Factory 空のリポジトリを生成する。戻り値の関数はテスト終了時に呼ばれる
+type Factory func(t *testing.T) (*Repositories, func())
+
+// Run 全てのテストケースを実行する
+func Run(t *testing.T, factory Factory) {
+	cases := []struct {
+		Name string
+		Test func(t *testing.T, repos *Repositories)
+	}{
+		{"User/CreateAndGet", testUserCreateAndGet},
+		{"User/NotFound", testUserNotFound},
+		{"User/GetByIDs", testUserGetByIDs},
+		{"User/Update", testUserUpdate},
+		{"User/UpdateVersionConflict", testUserUpdateVersionConflict},
+		{"User/EmailUniqueness", testUserEmailUniqueness},
+		{"User/EmailUniquenessRace", testUserEmailUniquenessRace},
+		{"User/Delete", testUserDelete},
+		{"User/DeleteVersionConflict", testUserDeleteVersionConflict},
+		{"User/Paging", testUserPaging},
+		{"User/MicropostStats", testUserMicropostStats},
+		{"User/MicropostStatsOutOfOrder", testUserMicropostStatsOutOfOrder},
+		{"User/RepairStats", testUserRepairStats},
+		{"Micropost/CreateAndGet", testMicropostCreateAndGet},
+		{"Micropost/CreateWithoutUser", testMicropostCreateWithoutUser},
+		{"Micropost/NotFound", testMicropostNotFound},
+		{"Micropost/Update", testMicropostUpdate},
+		{"Micropost/VersionConflict", testMicropostVersionConflict},
+		{"Micropost/ListByUser", testMicropostListByUser},
+		{"Micropost/Delete", testMicropostDelete},
+		{"Micropost/DeleteByUserID", testMicropostDeleteByUserID},
+		{"Micropost/DeleteByUserIDAfterUserDeleted", testMicropostDeleteByUserIDAfterUserDeleted},
+		{"Micropost/PublicTimeline", testMicropostPublicTimeline},
+		{"Micropost/PublicTimelineRange", testMicropostPublicTimelineRange},
+		{"Micropost/PublicTimelineSinceAcrossMidnight", testMicropostPublicTimelineSinceAcrossMidnight},
+		{"Relationship/FollowAndUnfollow", testRelationshipFollowAndUnfollow},
+		{"Relationship/FollowWithoutUser", testRelationshipFollowWithoutUser},
+		{"Relationship/List", testRelationshipList},
+		{"Relationship/DeleteByUserID", testRelationshipDeleteByUserID},
+		{"Like/LikeAndUnlike", testLikeAndUnlike},
+		{"Like/WithoutMicropost", testLikeWithoutMicropost},
+		{"Like/List", testLikeList},
+		{"Like/DeleteWithMicropost", testLikeDeleteWithMicropost},
+		{"Like/DeleteByUserID", testLikeDeleteByUserID},
+		{"Like/UpdateMicropost", testLikeUpdateMicropost},
+		{"Reply/CreateAndList", testReplyCreateAndList},
+		{"Reply/WithoutInReplyTo", testReplyWithoutInReplyTo},
+		{"Reply/Delete", testReplyDelete},
+		{"Reply/DeleteInReplyTo", testReplyDeleteInReplyTo},
+		{"Reply/DeleteByUserID", testReplyDeleteByUserID},
+		{"Reply/UpdateMicropost", testReplyUpdateMicropost},
+		{"CanceledContext", testCanceledContext},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			repos, cleanup := factory(t)
+			defer cleanup()
+			c.Test(t, repos)
+		})
+	}
+}
+
+func createUser(t *testing.T, repos *Repositories, n int) *domain.UserModel {
+	t.Helper()
+	user, err := repos.Users.CreateUser(context.Background(), domain.NewUserModel(fmt.Sprintf("Name_%d", n), fmt.Sprintf("test%d@example.com", n)))
+	require.NoError(t, err)
+	return user
+}
+
+func createMicropost(t *testing.T, repos *Repositories, userID uint64, n int) *domain.MicropostModel {
+	t.Helper()
+	micropost, err := repos.Microposts.CreateMicropost(context.Background(), domain.NewMicropostModel(fmt.Sprintf("Content_%d", n), userID))
+	require.NoError(t, err)
+	return micropost
+}
+
+func testUserCreateAndGet(t *testing.T, repos *Repositories) {
+	user1 := createUser(t, repos, 1)
+	user2 := createUser(t, repos, 2)
+
+	// IDは自動で採番され、バージョンは1から始まる
+	assert.NotZero(t, user1.ID)
+	assert.NotEqual(t, user1.ID, user2.ID)
+	assert.Equal(t, 1, user1.Version)
+
+	got, err := repos.Users.GetUserByID(context.Background(), user1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user1, got)
+
+	got, err = repos.Users.GetUserByEmail(context.Background(), user2.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user2, got)
+}
+
+func testUserNotFound(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	_, err := repos.Users.GetUserByID(context.Background(), user.ID+1000)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	_, err = repos.Users.GetUserByEmail(context.Background(), "missing@example.com")
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	// 存在しないユーザーは更新できず、作成もされない
+	missing := &domain.UserModel{ID: user.ID + 1000, Name: "Name_missing", Email: "missing@example.com"}
+	err = repos.Users.UpdateUser(context.Background(), missing)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	missing.Version = 1
+	err = repos.Users.UpdateUser(context.Background(), missing)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	_, err = repos.Users.GetUserByEmail(context.Background(), "missing@example.com")
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testUserGetByIDs(t *testing.T, repos *Repositories) {
+	user1 := createUser(t, repos, 1)
+	user2 := createUser(t, repos, 2)
+	user3 := createUser(t, repos, 3)
+	require.NoError(t, repos.Users.DeleteUser(context.Background(), user2))
+
+	// 指定した順に取得し、削除されたユーザーと存在しないユーザーは含めない
+	got, err := repos.Users.GetUsersByIDs(context.Background(), []uint64{user3.ID, user2.ID, user3.ID + 1000, user1.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.UserModel{user3, user1}, got)
+
+	got, err = repos.Users.GetUsersByIDs(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testUserUpdate(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	oldEmail := user.Email
+
+	user.Name = "Name_update"
+	user.Email = "test_update@example.com"
+	require.NoError(t, repos.Users.UpdateUser(context.Background(), user))
+
+	got, err := repos.Users.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Name_update", got.Name)
+	assert.Equal(t, "test_update@example.com", got.Email)
+	assert.Equal(t, user.Version+1, got.Version)
+
+	// 変更後のメールアドレスで取得でき、変更前のメールアドレスは解放される
+	got, err = repos.Users.GetUserByEmail(context.Background(), "test_update@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+
+	_, err = repos.Users.GetUserByEmail(context.Background(), oldEmail)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	_, err = repos.Users.CreateUser(context.Background(), domain.NewUserModel("Name_2", oldEmail))
+	assert.NoError(t, err)
+}
+
+func testUserUpdateVersionConflict(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	// 最新のバージョンを指定した更新は成功する
+	updated := *user
+	updated.Name = "Name_update1"
+	require.NoError(t, repos.Users.UpdateUser(context.Background(), &updated))
+
+	// 古いバージョンを指定した更新は失敗する
+	stale := *user
+	stale.Name = "Name_update2"
+	err := repos.Users.UpdateUser(context.Background(), &stale)
+	assert.True(t, errors.Is(err, domain.ErrPreconditionFailed), "%+v", err)
+
+	got, err := repos.Users.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Name_update1", got.Name)
+	assert.Equal(t, 2, got.Version)
+}
+
+func testUserEmailUniqueness(t *testing.T, repos *Repositories) {
+	user1 := createUser(t, repos, 1)
+	user2 := createUser(t, repos, 2)
+
+	// 登録済みのメールアドレスでは作成できない
+	_, err := repos.Users.CreateUser(context.Background(), domain.NewUserModel("Name_3", user1.Email))
+	assert.True(t, errors.Is(err, domain.ErrConflict), "%+v", err)
+
+	// 他のユーザーのメールアドレスには変更できない
+	user2.Email = user1.Email
+	err = repos.Users.UpdateUser(context.Background(), user2)
+	assert.True(t, errors.Is(err, domain.ErrConflict), "%+v", err)
+
+	got, err := repos.Users.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user1.ID, got.ID)
+}
+
+func testUserEmailUniquenessRace(t *testing.T, repos *Repositories) {
+	const n = 5
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = repos.Users.CreateUser(context.Background(), domain.NewUserModel(fmt.Sprintf("Name_%d", i), "race@example.com"))
+		}(i)
+	}
+	wg.Wait()
+
+	// 同時に作成しても、成功するのは1件だけ
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.Is(err, domain.ErrConflict), "%+v", err)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	users, _, err := repos.Users.GetUsers(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func testUserDelete(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	require.NoError(t, repos.Users.DeleteUser(context.Background(), user))
+
+	_, err := repos.Users.GetUserByID(context.Background(), user.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	_, err = repos.Users.GetUserByEmail(context.Background(), user.Email)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	// 削除したユーザーのメールアドレスは再度使える
+	_, err = repos.Users.CreateUser(context.Background(), domain.NewUserModel("Name_2", user.Email))
+	assert.NoError(t, err)
+}
+
+func testUserDeleteVersionConflict(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	updated := *user
+	updated.Name = "Name_update"
+	require.NoError(t, repos.Users.UpdateUser(context.Background(), &updated))
+
+	// 古いバージョンを指定した削除は失敗する
+	err := repos.Users.DeleteUser(context.Background(), user)
+	assert.True(t, errors.Is(err, domain.ErrPreconditionFailed), "%+v", err)
+
+	_, err = repos.Users.GetUserByID(context.Background(), user.ID)
+	assert.NoError(t, err)
+}
+
+func testUserPaging(t *testing.T, repos *Repositories) {
+	const n = 5
+	expected := map[uint64]bool{}
+	for i := 0; i < n; i++ {
+		expected[createUser(t, repos, i).ID] = true
+	}
+
+	// ページングなしの場合は全件取得する
+	users, nextCursor, err := repos.Users.GetUsers(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, users, n)
+	assert.Empty(t, nextCursor)
+
+	// 2件ずつ取得して、全件を重複なく取得できる
+	actual := map[uint64]bool{}
+	cursor := ""
+	for page := 0; page < n; page++ {
+		users, nextCursor, err := repos.Users.GetUsers(context.Background(), &domain.Paging{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		assert.True(t, len(users) <= 2)
+		for _, u := range users {
+			assert.False(t, actual[u.ID], "重複して取得されている: %d", u.ID)
+			actual[u.ID] = true
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	assert.Equal(t, expected, actual)
+
+	_, _, err = repos.Users.GetUsers(context.Background(), &domain.Paging{Limit: 2, Cursor: "invalid"})
+	assert.True(t, errors.Is(err, domain.ErrInvalidCursor), "%+v", err)
+}
+
+func testUserMicropostStats(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+
+	// 投稿していないユーザーは件数が0で、投稿日時は設定されない
+	got, err := repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.MicropostsCount)
+	assert.Nil(t, got.FirstPostedAt)
+	assert.Nil(t, got.LastPostedAt)
+
+	var microposts []*domain.MicropostModel
+	for i := 0; i < 3; i++ {
+		microposts = append(microposts, createMicropost(t, repos, user.ID, i))
+	}
+
+	got, err = repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.MicropostsCount)
+	require.NotNil(t, got.FirstPostedAt)
+	require.NotNil(t, got.LastPostedAt)
+	assert.False(t, got.LastPostedAt.Before(*got.FirstPostedAt))
+	firstPostedAt, lastPostedAt := *got.FirstPostedAt, *got.LastPostedAt
+
+	// プロフィールを更新しても集計値は変わらない
+	got.Name = "Name_update"
+	require.NoError(t, repos.Users.UpdateUser(ctx, got))
+	got, err = repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.MicropostsCount)
+
+	// 削除すると件数は減るが、投稿日時は変わらない
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, microposts[2]))
+	got, err = repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.MicropostsCount)
+	require.NotNil(t, got.FirstPostedAt)
+	require.NotNil(t, got.LastPostedAt)
+	assert.True(t, firstPostedAt.Equal(*got.FirstPostedAt))
+	assert.True(t, lastPostedAt.Equal(*got.LastPostedAt))
+
+	_, err = repos.Microposts.DeleteMicropostsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	got, err = repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.MicropostsCount)
+	require.NotNil(t, got.FirstPostedAt)
+	require.NotNil(t, got.LastPostedAt)
+	assert.True(t, firstPostedAt.Equal(*got.FirstPostedAt))
+	assert.True(t, lastPostedAt.Equal(*got.LastPostedAt))
+}
+
+func testUserMicropostStatsOutOfOrder(t *testing.T, repos *Repositories) {
+	if repos.SetNow == nil {
+		t.Skip("SetNow is not supported")
+	}
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+
+	// 同時に作成されたマイクロポストが、投稿日時と逆の順に保存された場合
+	middle := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	newer := middle.Add(time.Second)
+	older := middle.Add(-time.Second)
+	for _, postedAt := range []time.Time{middle, newer, older, middle} {
+		postedAt := postedAt
+		repos.SetNow(func() time.Time { return postedAt })
+		createMicropost(t, repos, user.ID, 1)
+	}
+
+	// 最後の投稿日時は戻らず、最初の投稿日時は古い方になる
+	got, err := repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, got.MicropostsCount)
+	require.NotNil(t, got.FirstPostedAt)
+	require.NotNil(t, got.LastPostedAt)
+	assert.True(t, older.Equal(*got.FirstPostedAt), "%v", got.FirstPostedAt)
+	assert.True(t, newer.Equal(*got.LastPostedAt), "%v", got.LastPostedAt)
+}
+
+func testUserRepairStats(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	other := createUser(t, repos, 2)
+
+	var microposts []*domain.MicropostModel
+	for i := 0; i < 3; i++ {
+		microposts = append(microposts, createMicropost(t, repos, user.ID, i))
+	}
+	createMicropost(t, repos, other.ID, 1)
+	before, err := repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, microposts[2]))
+
+	// 計算し直すと件数は残っているマイクロポストの件数になり、投稿日時は削除と同じく変わらない
+	repaired, err := repos.Users.RepairUserStats(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repaired.MicropostsCount)
+	require.NotNil(t, repaired.FirstPostedAt)
+	require.NotNil(t, repaired.LastPostedAt)
+	assert.True(t, before.FirstPostedAt.Equal(*repaired.FirstPostedAt))
+	assert.True(t, before.LastPostedAt.Equal(*repaired.LastPostedAt))
+
+	got, err := repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.MicropostsCount)
+	require.NotNil(t, got.FirstPostedAt)
+	require.NotNil(t, got.LastPostedAt)
+	assert.True(t, repaired.FirstPostedAt.Equal(*got.FirstPostedAt))
+	assert.True(t, repaired.LastPostedAt.Equal(*got.LastPostedAt))
+
+	// 全て削除した後に計算し直しても、投稿日時は消えない
+	_, err = repos.Microposts.DeleteMicropostsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	repaired, err = repos.Users.RepairUserStats(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, repaired.MicropostsCount)
+	require.NotNil(t, repaired.FirstPostedAt)
+	require.NotNil(t, repaired.LastPostedAt)
+	assert.True(t, before.FirstPostedAt.Equal(*repaired.FirstPostedAt))
+	assert.True(t, before.LastPostedAt.Equal(*repaired.LastPostedAt))
+
+	// 投稿していないユーザーは、計算し直しても投稿日時は設定されない
+	empty := createUser(t, repos, 3)
+	repaired, err = repos.Users.RepairUserStats(ctx, empty.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, repaired.MicropostsCount)
+	assert.Nil(t, repaired.FirstPostedAt)
+	assert.Nil(t, repaired.LastPostedAt)
+
+	// 他のユーザーの集計値は変わらない
+	got, err = repos.Users.GetUserByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.MicropostsCount)
+
+	_, err = repos.Users.RepairUserStats(ctx, other.ID+1000)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testMicropostCreateAndGet(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	micropost1 := createMicropost(t, repos, user.ID, 1)
+	micropost2 := createMicropost(t, repos, user.ID, 2)
+
+	assert.NotZero(t, micropost1.ID)
+	assert.NotEqual(t, micropost1.ID, micropost2.ID)
+	assert.Equal(t, 1, micropost1.Version)
+
+	got, err := repos.Microposts.GetMicropostByID(context.Background(), micropost1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, micropost1, got)
+}
+
+func testMicropostCreateWithoutUser(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	_, err := repos.Microposts.CreateMicropost(context.Background(), domain.NewMicropostModel("Content", user.ID+1000))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	microposts, _, err := repos.Microposts.GetMicropostsByUserID(context.Background(), user.ID+1000, nil)
+	require.NoError(t, err)
+	assert.Empty(t, microposts)
+}
+
+func testMicropostNotFound(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+	missing := &domain.MicropostModel{ID: micropost.ID + 1000, UserID: user.ID, Content: "Content"}
+
+	_, err := repos.Microposts.GetMicropostByID(context.Background(), missing.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Microposts.UpdateMicropost(context.Background(), missing)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Microposts.DeleteMicropost(context.Background(), missing)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	// バージョンを指定した場合も、存在しないことを優先して返す
+	missing.Version = 1
+	err = repos.Microposts.UpdateMicropost(context.Background(), missing)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Microposts.DeleteMicropost(context.Background(), missing)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	// 削除済みのマイクロポストも同様
+	require.NoError(t, repos.Microposts.DeleteMicropost(context.Background(), micropost))
+	err = repos.Microposts.UpdateMicropost(context.Background(), micropost)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Microposts.DeleteMicropost(context.Background(), micropost)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testMicropostUpdate(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+
+	micropost.Content = "Content_update"
+	require.NoError(t, repos.Microposts.UpdateMicropost(context.Background(), micropost))
+
+	got, err := repos.Microposts.GetMicropostByID(context.Background(), micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Content_update", got.Content)
+	assert.Equal(t, user.ID, got.UserID)
+	assert.Equal(t, micropost.Version+1, got.Version)
+}
+
+func testMicropostVersionConflict(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+
+	updated := *micropost
+	updated.Content = "Content_update1"
+	require.NoError(t, repos.Microposts.UpdateMicropost(context.Background(), &updated))
+
+	// 古いバージョンを指定した更新・削除は失敗する
+	stale := *micropost
+	stale.Content = "Content_update2"
+	err := repos.Microposts.UpdateMicropost(context.Background(), &stale)
+	assert.True(t, errors.Is(err, domain.ErrPreconditionFailed), "%+v", err)
+
+	err = repos.Microposts.DeleteMicropost(context.Background(), &stale)
+	assert.True(t, errors.Is(err, domain.ErrPreconditionFailed), "%+v", err)
+
+	got, err := repos.Microposts.GetMicropostByID(context.Background(), micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Content_update1", got.Content)
+}
+
+func testMicropostListByUser(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	other := createUser(t, repos, 2)
+
+	const n = 5
+	var expected []uint64
+	for i := 0; i < n; i++ {
+		expected = append(expected, createMicropost(t, repos, user.ID, i).ID)
+		createMicropost(t, repos, other.ID, i)
+	}
+
+	// 他のユーザーのマイクロポストを含まず、作成順に取得する
+	microposts, nextCursor, err := repos.Microposts.GetMicropostsByUserID(context.Background(), user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, nextCursor)
+	var actual []uint64
+	for _, m := range microposts {
+		assert.Equal(t, user.ID, m.UserID)
+		actual = append(actual, m.ID)
+	}
+	assert.Equal(t, expected, actual)
+
+	// ページングしても同じ順序で取得できる
+	actual = nil
+	cursor := ""
+	for page := 0; page < n; page++ {
+		microposts, nextCursor, err := repos.Microposts.GetMicropostsByUserID(context.Background(), user.ID, &domain.Paging{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		for _, m := range microposts {
+			actual = append(actual, m.ID)
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	assert.Equal(t, expected, actual)
+
+	// 他のユーザーの一覧のカーソルは使えない
+	_, next, err := repos.Microposts.GetMicropostsByUserID(context.Background(), user.ID, &domain.Paging{Limit: 2})
+	require.NoError(t, err)
+	_, _, err = repos.Microposts.GetMicropostsByUserID(context.Background(), other.ID, &domain.Paging{Limit: 2, Cursor: next})
+	assert.True(t, errors.Is(err, domain.ErrInvalidCursor), "%+v", err)
+}
+
+func testMicropostDelete(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+
+	require.NoError(t, repos.Microposts.DeleteMicropost(context.Background(), micropost))
+
+	_, err := repos.Microposts.GetMicropostByID(context.Background(), micropost.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testMicropostDeleteByUserID(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+	other := createUser(t, repos, 2)
+
+	// 一度に削除できる件数を超える数を作成する
+	const n = 30
+	for i := 0; i < n; i++ {
+		createMicropost(t, repos, user.ID, i)
+	}
+	otherMicropost := createMicropost(t, repos, other.ID, 1)
+
+	count, err := repos.Microposts.DeleteMicropostsByUserID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, n, count)
+
+	microposts, _, err := repos.Microposts.GetMicropostsByUserID(context.Background(), user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, microposts)
+
+	// 他のユーザーのマイクロポストは削除されない
+	_, err = repos.Microposts.GetMicropostByID(context.Background(), otherMicropost.ID)
+	assert.NoError(t, err)
+}
+
+func testMicropostDeleteByUserIDAfterUserDeleted(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	for i := 0; i < 3; i++ {
+		createMicropost(t, repos, user.ID, i)
+	}
+
+	// ユーザーを先に削除しても、紐づくマイクロポストを削除できる
+	require.NoError(t, repos.Users.DeleteUser(ctx, user))
+	count, err := repos.Microposts.DeleteMicropostsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	microposts, _, err := repos.Microposts.GetMicropostsByUserID(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, microposts)
+
+	// 削除したユーザーとしては作成できない
+	_, err = repos.Microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content", user.ID))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testMicropostPublicTimeline(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+
+	// まだ何も投稿されていない場合
+	microposts, nextCursor, err := repos.Microposts.GetPublicTimeline(ctx, &domain.Paging{Limit: 2}, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, microposts)
+	assert.Empty(t, nextCursor)
+
+	user := createUser(t, repos, 1)
+	other := createUser(t, repos, 2)
+
+	// 全てのユーザーのマイクロポストを新しい順に取得する
+	const n = 5
+	var expected []uint64
+	for i := 0; i < n; i++ {
+		expected = append([]uint64{createMicropost(t, repos, user.ID, i).ID}, expected...)
+		expected = append([]uint64{createMicropost(t, repos, other.ID, i).ID}, expected...)
+	}
+
+	microposts, nextCursor, err = repos.Microposts.GetPublicTimeline(ctx, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, nextCursor)
+	var actual []uint64
+	for _, m := range microposts {
+		actual = append(actual, m.ID)
+	}
+	assert.Equal(t, expected, actual)
+
+	// 削除したマイクロポストは含まない
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, &domain.MicropostModel{ID: expected[0]}))
+	expected = expected[1:]
+
+	// ページングしても同じ順序で取得できる
+	actual = nil
+	cursor := ""
+	for page := 0; page < n*2; page++ {
+		microposts, nextCursor, err := repos.Microposts.GetPublicTimeline(ctx, &domain.Paging{Limit: 3, Cursor: cursor}, 0, 0)
+		require.NoError(t, err)
+		for _, m := range microposts {
+			actual = append(actual, m.ID)
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	assert.Equal(t, expected, actual)
+
+	// ユーザーごとの一覧のカーソルは使えない
+	_, next, err := repos.Microposts.GetMicropostsByUserID(ctx, user.ID, &domain.Paging{Limit: 2})
+	require.NoError(t, err)
+	_, _, err = repos.Microposts.GetPublicTimeline(ctx, &domain.Paging{Limit: 2, Cursor: next}, 0, 0)
+	assert.True(t, errors.Is(err, domain.ErrInvalidCursor), "%+v", err)
+
+	// ユーザーを削除した場合
+	_, err = repos.Microposts.DeleteMicropostsByUserID(ctx, other.ID)
+	require.NoError(t, err)
+	microposts, _, err = repos.Microposts.GetPublicTimeline(ctx, nil, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, microposts, n)
+	for _, m := range microposts {
+		assert.Equal(t, user.ID, m.UserID)
+	}
+}
+
+func testMicropostPublicTimelineRange(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+
+	var ids []uint64
+	for i := 0; i < 6; i++ {
+		ids = append(ids, createMicropost(t, repos, user.ID, i).ID)
+	}
+
+	list := func(paging *domain.Paging, sinceID, maxID uint64) ([]uint64, string) {
+		t.Helper()
+		microposts, nextCursor, err := repos.Microposts.GetPublicTimeline(ctx, paging, sinceID, maxID)
+		require.NoError(t, err)
+		var actual []uint64
+		for _, m := range microposts {
+			actual = append(actual, m.ID)
+		}
+		return actual, nextCursor
+	}
+
+	// sinceIDより新しいもの
+	actual, _ := list(nil, ids[3], 0)
+	assert.Equal(t, []uint64{ids[5], ids[4]}, actual)
+
+	// maxID以前のもの
+	actual, _ = list(nil, 0, ids[2])
+	assert.Equal(t, []uint64{ids[2], ids[1], ids[0]}, actual)
+
+	// 両方を指定してページングする
+	actual, nextCursor := list(&domain.Paging{Limit: 2}, ids[0], ids[4])
+	assert.Equal(t, []uint64{ids[4], ids[3]}, actual)
+	require.NotEmpty(t, nextCursor)
+	actual, nextCursor = list(&domain.Paging{Limit: 2, Cursor: nextCursor}, ids[0], ids[4])
+	assert.Equal(t, []uint64{ids[2], ids[1]}, actual)
+	if nextCursor != "" {
+		actual, _ = list(&domain.Paging{Limit: 2, Cursor: nextCursor}, ids[0], ids[4])
+		assert.Empty(t, actual)
+	}
+}
+
+func testMicropostPublicTimelineSinceAcrossMidnight(t *testing.T, repos *Repositories) {
+	if repos.SetNow == nil {
+		t.Skip("SetNow is not supported")
+	}
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+
+	// 時計が進んだ環境で日付が変わった直後に投稿した後、時計が遅れた環境で前日の日時として投稿した場合
+	midnight := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+	var ids []uint64
+	for _, postedAt := range []time.Time{midnight.Add(-2 * time.Second), midnight.Add(time.Second), midnight.Add(-time.Second)} {
+		postedAt := postedAt
+		repos.SetNow(func() time.Time { return postedAt })
+		ids = append(ids, createMicropost(t, repos, user.ID, len(ids)).ID)
+	}
+	repos.SetNow(func() time.Time { return midnight.Add(2 * time.Second) })
+
+	// 日付をまたいでも、sinceIDより後に採番されたマイクロポストを取得できる
+	microposts, _, err := repos.Microposts.GetPublicTimeline(ctx, nil, ids[1], 0)
+	require.NoError(t, err)
+	var actual []uint64
+	for _, m := range microposts {
+		actual = append(actual, m.ID)
+	}
+	assert.Equal(t, []uint64{ids[2]}, actual)
+}
+
+func testRelationshipFollowAndUnfollow(t *testing.T, repos *Repositories) {
+	user1 := createUser(t, repos, 1)
+	user2 := createUser(t, repos, 2)
+	ctx := context.Background()
+
+	require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(user1.ID, user2.ID)))
+
+	// フォローは一方向のみ
+	following, err := repos.Relationships.IsFollowing(ctx, user1.ID, user2.ID)
+	require.NoError(t, err)
+	assert.True(t, following)
+	following, err = repos.Relationships.IsFollowing(ctx, user2.ID, user1.ID)
+	require.NoError(t, err)
+	assert.False(t, following)
+
+	// すでにフォローしている場合も成功する
+	require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(user1.ID, user2.ID)))
+	followers, _, err := repos.Relationships.GetFollowers(ctx, user2.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, followers, 1)
+
+	require.NoError(t, repos.Relationships.Unfollow(ctx, domain.NewRelationshipModel(user1.ID, user2.ID)))
+	following, err = repos.Relationships.IsFollowing(ctx, user1.ID, user2.ID)
+	require.NoError(t, err)
+	assert.False(t, following)
+
+	// フォローしていない場合は解除できない
+	err = repos.Relationships.Unfollow(ctx, domain.NewRelationshipModel(user1.ID, user2.ID))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testRelationshipFollowWithoutUser(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	err := repos.Relationships.Follow(context.Background(), domain.NewRelationshipModel(user.ID, 9999))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Relationships.Follow(context.Background(), domain.NewRelationshipModel(9999, user.ID))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	following, err := repos.Relationships.IsFollowing(context.Background(), user.ID, 9999)
+	require.NoError(t, err)
+	assert.False(t, following)
+}
+
+func testRelationshipList(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 0)
+	ctx := context.Background()
+
+	// userが全員をフォローし、全員からフォローされる
+	const n = 5
+	var expected []uint64
+	for i := 1; i <= n; i++ {
+		other := createUser(t, repos, i)
+		expected = append(expected, other.ID)
+		require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(user.ID, other.ID)))
+		require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(other.ID, user.ID)))
+	}
+
+	// ユーザーIDの順に取得する
+	following, nextCursor, err := repos.Relationships.GetFollowing(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, nextCursor)
+	var actual []uint64
+	for _, r := range following {
+		assert.Equal(t, user.ID, r.FollowerID)
+		actual = append(actual, r.FollowedID)
+	}
+	assert.Equal(t, expected, actual)
+
+	followers, _, err := repos.Relationships.GetFollowers(ctx, user.ID, nil)
+	require.NoError(t, err)
+	actual = nil
+	for _, r := range followers {
+		assert.Equal(t, user.ID, r.FollowedID)
+		actual = append(actual, r.FollowerID)
+	}
+	assert.Equal(t, expected, actual)
+
+	// ページングしても同じ順序で取得できる
+	for _, list := range []func(paging *domain.Paging) ([]uint64, string, error){
+		func(paging *domain.Paging) ([]uint64, string, error) {
+			relationships, next, err := repos.Relationships.GetFollowing(ctx, user.ID, paging)
+			var ids []uint64
+			for _, r := range relationships {
+				ids = append(ids, r.FollowedID)
+			}
+			return ids, next, err
+		},
+		func(paging *domain.Paging) ([]uint64, string, error) {
+			relationships, next, err := repos.Relationships.GetFollowers(ctx, user.ID, paging)
+			var ids []uint64
+			for _, r := range relationships {
+				ids = append(ids, r.FollowerID)
+			}
+			return ids, next, err
+		},
+	} {
+		actual = nil
+		cursor := ""
+		for page := 0; page < n; page++ {
+			ids, next, err := list(&domain.Paging{Limit: 2, Cursor: cursor})
+			require.NoError(t, err)
+			actual = append(actual, ids...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		assert.Equal(t, expected, actual)
+	}
+
+	// フォロー中の一覧のカーソルはフォロワーの一覧には使えない
+	_, next, err := repos.Relationships.GetFollowing(ctx, user.ID, &domain.Paging{Limit: 2})
+	require.NoError(t, err)
+	_, _, err = repos.Relationships.GetFollowers(ctx, user.ID, &domain.Paging{Limit: 2, Cursor: next})
+	assert.True(t, errors.Is(err, domain.ErrInvalidCursor), "%+v", err)
+}
+
+func testRelationshipDeleteByUserID(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 0)
+	ctx := context.Background()
+
+	// 一度に削除できる件数を超える数を作成する
+	const n = 15
+	var others []*domain.UserModel
+	for i := 1; i <= n; i++ {
+		other := createUser(t, repos, i)
+		others = append(others, other)
+		require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(user.ID, other.ID)))
+		require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(other.ID, user.ID)))
+	}
+	require.NoError(t, repos.Relationships.Follow(ctx, domain.NewRelationshipModel(others[0].ID, others[1].ID)))
+
+	count, err := repos.Relationships.DeleteRelationshipsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, n*2, count)
+
+	following, _, err := repos.Relationships.GetFollowing(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, following)
+	followers, _, err := repos.Relationships.GetFollowers(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, followers)
+	following, _, err = repos.Relationships.GetFollowing(ctx, others[2].ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, following)
+
+	// 他のユーザー同士のフォロー関係は削除されない
+	isFollowing, err := repos.Relationships.IsFollowing(ctx, others[0].ID, others[1].ID)
+	require.NoError(t, err)
+	assert.True(t, isFollowing)
+}
+
+func testLikeAndUnlike(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	author := createUser(t, repos, 1)
+	liker := createUser(t, repos, 2)
+	micropost := createMicropost(t, repos, author.ID, 1)
+	assert.Equal(t, 0, micropost.LikeCount)
+
+	// 同じユーザーが何度いいねしても件数は1件だけ増える
+	like := domain.NewLikeModel(micropost.ID, liker.ID)
+	require.NoError(t, repos.Likes.Like(ctx, like))
+	require.NoError(t, repos.Likes.Like(ctx, like))
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.LikeCount)
+
+	require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, author.ID)))
+	got, err = repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.LikeCount)
+
+	// 何度取り消しても件数は1件だけ減る
+	require.NoError(t, repos.Likes.Unlike(ctx, like))
+	require.NoError(t, repos.Likes.Unlike(ctx, like))
+	got, err = repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.LikeCount)
+
+	likes, _, err := repos.Likes.GetLikes(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, likes, 1)
+	assert.Equal(t, author.ID, likes[0].UserID)
+	assert.Equal(t, micropost.ID, likes[0].MicropostID)
+}
+
+func testLikeWithoutMicropost(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+
+	err := repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID+1000, user.ID))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, user.ID+1000))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	err = repos.Likes.Unlike(ctx, domain.NewLikeModel(micropost.ID+1000, user.ID))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	// 失敗したいいねは件数に含まれない
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.LikeCount)
+}
+
+func testLikeList(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	author := createUser(t, repos, 0)
+	micropost := createMicropost(t, repos, author.ID, 1)
+	other := createMicropost(t, repos, author.ID, 2)
+
+	const n = 5
+	var ids []uint64
+	for i := 1; i <= n; i++ {
+		liker := createUser(t, repos, i)
+		require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, liker.ID)))
+		ids = append(ids, liker.ID)
+	}
+	require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(other.ID, author.ID)))
+
+	// 他のマイクロポストへのいいねは含まれない
+	likes, nextCursor, err := repos.Likes.GetLikes(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, nextCursor)
+	require.Len(t, likes, n)
+	for i, like := range likes {
+		assert.Equal(t, ids[i], like.UserID)
+	}
+
+	// ページングしても全件を順に取得できる
+	var got []uint64
+	paging := &domain.Paging{Limit: 2}
+	for {
+		likes, next, err := repos.Likes.GetLikes(ctx, micropost.ID, paging)
+		require.NoError(t, err)
+		for _, like := range likes {
+			got = append(got, like.UserID)
+		}
+		if next == "" {
+			break
+		}
+		paging = &domain.Paging{Limit: 2, Cursor: next}
+	}
+	assert.Equal(t, ids, got)
+
+	// 他のマイクロポストのカーソルは使えない
+	_, next, err := repos.Likes.GetLikes(ctx, micropost.ID, &domain.Paging{Limit: 2})
+	require.NoError(t, err)
+	_, _, err = repos.Likes.GetLikes(ctx, other.ID, &domain.Paging{Limit: 2, Cursor: next})
+	assert.True(t, errors.Is(err, domain.ErrInvalidCursor), "%+v", err)
+}
+
+func testLikeDeleteWithMicropost(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+	require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, user.ID)))
+
+	// マイクロポストを削除するといいねも削除される
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, micropost))
+	likes, _, err := repos.Likes.GetLikes(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, likes)
+
+	micropost = createMicropost(t, repos, user.ID, 2)
+	require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, user.ID)))
+	_, err = repos.Microposts.DeleteMicropostsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	likes, _, err = repos.Likes.GetLikes(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, likes)
+}
+
+func testLikeDeleteByUserID(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	other := createUser(t, repos, 2)
+
+	// 一度に取得する件数を超える数にいいねする
+	const n = 30
+	var microposts []*domain.MicropostModel
+	for i := 0; i < n; i++ {
+		micropost := createMicropost(t, repos, other.ID, i)
+		require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, user.ID)))
+		microposts = append(microposts, micropost)
+	}
+	require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(microposts[0].ID, other.ID)))
+
+	// 削除されたマイクロポストへのいいねは、マイクロポストと一緒に削除されている
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, microposts[n-1]))
+
+	count, err := repos.Likes.DeleteLikesByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, n-1, count)
+
+	// 他のユーザーのいいねは残り、件数は取り消した分だけ減る
+	got, err := repos.Microposts.GetMicropostByID(ctx, microposts[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.LikeCount)
+	got, err = repos.Microposts.GetMicropostByID(ctx, microposts[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.LikeCount)
+
+	likes, _, err := repos.Likes.GetLikes(ctx, microposts[0].ID, nil)
+	require.NoError(t, err)
+	require.Len(t, likes, 1)
+	assert.Equal(t, other.ID, likes[0].UserID)
+}
+
+func testLikeUpdateMicropost(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+	require.NoError(t, repos.Likes.Like(ctx, domain.NewLikeModel(micropost.ID, user.ID)))
+
+	// いいねされる前に読み込んだマイクロポストで更新しても、件数は上書きされない
+	micropost.Content = "Content_update"
+	require.NoError(t, repos.Microposts.UpdateMicropost(ctx, micropost))
+
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Content_update", got.Content)
+	assert.Equal(t, 1, got.LikeCount)
+}
+
+func createReply(t *testing.T, repos *Repositories, userID, inReplyToID uint64, n int) *domain.MicropostModel {
+	t.Helper()
+	reply, err := repos.Microposts.CreateMicropost(context.Background(), domain.NewReplyModel(fmt.Sprintf("Reply_%d", n), userID, inReplyToID))
+	require.NoError(t, err)
+	return reply
+}
+
+func testReplyCreateAndList(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	author := createUser(t, repos, 1)
+	replier := createUser(t, repos, 2)
+	micropost := createMicropost(t, repos, author.ID, 1)
+	other := createMicropost(t, repos, author.ID, 2)
+	assert.Equal(t, 0, micropost.ReplyCount)
+
+	var replies []*domain.MicropostModel
+	for i := 1; i <= 5; i++ {
+		replies = append(replies, createReply(t, repos, replier.ID, micropost.ID, i))
+	}
+	createReply(t, repos, replier.ID, other.ID, 6)
+	assert.Equal(t, micropost.ID, replies[0].InReplyToID)
+
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, got.ReplyCount)
+
+	got, err = repos.Microposts.GetMicropostByID(ctx, replies[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, micropost.ID, got.InReplyToID)
+
+	// 全件取得すると古い順に並ぶ
+	all, nextCursor, err := repos.Microposts.GetReplies(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, nextCursor)
+	require.Len(t, all, 5)
+	for i, reply := range replies {
+		assert.Equal(t, reply.ID, all[i].ID)
+		assert.Equal(t, "Reply_"+fmt.Sprint(i+1), all[i].Content)
+	}
+
+	// カーソルで続きを取得すると、重複も欠落もなく全件を取得できる
+	var ids []uint64
+	paging := &domain.Paging{Limit: 2}
+	for {
+		page, next, err := repos.Microposts.GetReplies(ctx, micropost.ID, paging)
+		require.NoError(t, err)
+		for _, reply := range page {
+			ids = append(ids, reply.ID)
+		}
+		if next == "" {
+			break
+		}
+		paging = &domain.Paging{Limit: 2, Cursor: next}
+	}
+	require.Len(t, ids, 5)
+	for i, reply := range replies {
+		assert.Equal(t, reply.ID, ids[i])
+	}
+
+	// 他のマイクロポストへの返信一覧のカーソルは使えない
+	_, next, err := repos.Microposts.GetReplies(ctx, micropost.ID, &domain.Paging{Limit: 2})
+	require.NoError(t, err)
+	_, _, err = repos.Microposts.GetReplies(ctx, other.ID, &domain.Paging{Limit: 2, Cursor: next})
+	assert.True(t, errors.Is(err, domain.ErrInvalidCursor), "%+v", err)
+}
+
+func testReplyWithoutInReplyTo(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+
+	_, err := repos.Microposts.CreateMicropost(ctx, domain.NewReplyModel("Reply_1", user.ID, micropost.ID+1000))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	// 失敗した返信は作成されず、投稿者の件数も変わらない
+	got, err := repos.Users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.MicropostsCount)
+
+	microposts, _, err := repos.Microposts.GetMicropostsByUserID(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, microposts, 1)
+}
+
+func testReplyDelete(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+	reply := createReply(t, repos, user.ID, micropost.ID, 1)
+	createReply(t, repos, user.ID, micropost.ID, 2)
+
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, reply))
+
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.ReplyCount)
+
+	replies, _, err := repos.Microposts.GetReplies(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, replies, 1)
+	assert.Equal(t, "Reply_2", replies[0].Content)
+}
+
+func testReplyDeleteInReplyTo(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	author := createUser(t, repos, 1)
+	replier := createUser(t, repos, 2)
+	micropost := createMicropost(t, repos, author.ID, 1)
+	reply := createReply(t, repos, replier.ID, micropost.ID, 1)
+
+	// 返信先を削除しても返信は残り、返信一覧も取得できる
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, micropost))
+
+	_, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	got, err := repos.Microposts.GetMicropostByID(ctx, reply.ID)
+	require.NoError(t, err)
+	assert.Equal(t, micropost.ID, got.InReplyToID)
+
+	replies, _, err := repos.Microposts.GetReplies(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, replies, 1)
+	assert.Equal(t, reply.ID, replies[0].ID)
+
+	// 返信先が削除された後でも返信を削除できる
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, reply))
+	replies, _, err = repos.Microposts.GetReplies(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, replies)
+
+	// 削除された返信先には返信できない
+	_, err = repos.Microposts.CreateMicropost(ctx, domain.NewReplyModel("Reply_2", replier.ID, micropost.ID))
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testReplyDeleteByUserID(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	author := createUser(t, repos, 1)
+	replier := createUser(t, repos, 2)
+	micropost := createMicropost(t, repos, author.ID, 1)
+	deleted := createMicropost(t, repos, author.ID, 2)
+	for i := 1; i <= 3; i++ {
+		createReply(t, repos, replier.ID, micropost.ID, i)
+	}
+	createReply(t, repos, replier.ID, deleted.ID, 4)
+	createReply(t, repos, author.ID, micropost.ID, 5)
+	require.NoError(t, repos.Microposts.DeleteMicropost(ctx, deleted))
+
+	// 返信先が削除済みの返信も含めて全て削除する
+	count, err := repos.Microposts.DeleteMicropostsByUserID(ctx, replier.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.ReplyCount)
+
+	replies, _, err := repos.Microposts.GetReplies(ctx, micropost.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, replies, 1)
+	assert.Equal(t, author.ID, replies[0].UserID)
+
+	replies, _, err = repos.Microposts.GetReplies(ctx, deleted.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, replies)
+
+	// 自分のマイクロポストへの返信も、返信先と一緒に削除できる
+	count, err = repos.Microposts.DeleteMicropostsByUserID(ctx, author.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+}
+
+func testReplyUpdateMicropost(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	user := createUser(t, repos, 1)
+	micropost := createMicropost(t, repos, user.ID, 1)
+	reply := createReply(t, repos, user.ID, micropost.ID, 1)
+
+	// 返信される前に読み込んだマイクロポストで更新しても、件数は上書きされない
+	micropost.Content = "Content_update"
+	require.NoError(t, repos.Microposts.UpdateMicropost(ctx, micropost))
+
+	got, err := repos.Microposts.GetMicropostByID(ctx, micropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Content_update", got.Content)
+	assert.Equal(t, 1, got.ReplyCount)
+
+	// 返信を更新しても返信先は変わらない
+	reply.Content = "Reply_update"
+	require.NoError(t, repos.Microposts.UpdateMicropost(ctx, reply))
+	got, err = repos.Microposts.GetMicropostByID(ctx, reply.ID)
+	require.NoError(t, err)
+	assert.Equal(t, micropost.ID, got.InReplyToID)
+}
+
+func testCanceledContext(t *testing.T, repos *Repositories) {
+	user := createUser(t, repos, 1)
+
+	// キャンセル済みのコンテキストでは処理を行わずにエラーを返す
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repos.Users.CreateUser(ctx, domain.NewUserModel("Name_2", "test2@example.com"))
+	assert.Error(t, err)
+
+	_, err = repos.Users.GetUserByID(ctx, user.ID)
+	assert.Error(t, err)
+
+	_, err = repos.Microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content_1", user.ID))
+	assert.Error(t, err)
+
+	users, _, err := repos.Users.GetUsers(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+}
@@ -0,0 +1,6 @@
+package domain
+
+// TokenVerifier 認証トークンを検証し、リクエスト送信者を特定する
+type TokenVerifier interface {
+	Verify(token string) (*Principal, error)
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -14,10 +15,10 @@ func NewUserEmailUniqChecker(repos UserRepository) *UserEmailUniqChecker {
 }
 
 // IsUniqueEmail メールアドレスがユニークかどうかをチェックする。自身のメールアドレスは対象としないようにする
-func (u *UserEmailUniqChecker) IsUniqueEmail(newUser *UserModel) (bool, error) {
-	user, err := u.Repos.GetUserByEmail(newUser.Email)
+func (u *UserEmailUniqChecker) IsUniqueEmail(ctx context.Context, newUser *UserModel) (bool, error) {
+	user, err := u.Repos.GetUserByEmail(ctx, newUser.Email)
 	if err != nil {
-		if ErrNotFound.Error() == err.Error() {
+		if errors.Is(err, ErrNotFound) {
 			return true, nil
 		}
 		return false, errors.WithStack(err)
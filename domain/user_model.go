@@ -1,10 +1,22 @@
 package domain
 
+import "time"
+
 // UserModel ユーザーモデル
 type UserModel struct {
 	ID    uint64
 	Name  string
 	Email string
+	// Version 楽観的排他制御に使うバージョン。0の場合はバージョンを指定しない
+	Version int
+	// MicropostsCount 投稿しているマイクロポストの件数
+	MicropostsCount int
+	// FirstPostedAt 最初にマイクロポストを投稿した日時。投稿していない場合はnil。
+	// 投稿の履歴を表すため、マイクロポストを削除しても変わらない
+	FirstPostedAt *time.Time
+	// LastPostedAt 最後にマイクロポストを投稿した日時。投稿していない場合はnil。
+	// 投稿の履歴を表すため、マイクロポストを削除しても変わらない
+	LastPostedAt *time.Time
 }
 
 func NewUserModel(name, email string) *UserModel {
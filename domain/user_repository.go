@@ -1,11 +1,18 @@
 package domain
 
+import "context"
+
 // UserRepository ユーザーモデルのリポジトリ
 type UserRepository interface {
-	GetUsers() ([]*UserModel, error)
-	GetUserByID(id uint64) (*UserModel, error)
-	GetUserByEmail(email string) (*UserModel, error)
-	CreateUser(newUser *UserModel) (*UserModel, error)
-	UpdateUser(newUser *UserModel) error
-	DeleteUser(targetUser *UserModel) error
+	GetUsers(ctx context.Context, paging *Paging) ([]*UserModel, string, error)
+	GetUserByID(ctx context.Context, id uint64) (*UserModel, error)
+	// GetUsersByIDs 指定したIDの順にユーザーをまとめて取得する。存在しないユーザーは結果に含めない
+	GetUsersByIDs(ctx context.Context, ids []uint64) ([]*UserModel, error)
+	GetUserByEmail(ctx context.Context, email string) (*UserModel, error)
+	CreateUser(ctx context.Context, newUser *UserModel) (*UserModel, error)
+	UpdateUser(ctx context.Context, newUser *UserModel) error
+	DeleteUser(ctx context.Context, targetUser *UserModel) error
+	// RepairUserStats マイクロポストの件数と投稿日時を、保存されているマイクロポストから計算し直して保存する。
+	// 投稿日時は削除では戻さないため、保存されている値より最初の投稿日時を遅く、最後の投稿日時を早くはしない
+	RepairUserStats(ctx context.Context, id uint64) (*UserModel, error)
 }
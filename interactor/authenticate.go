@@ -0,0 +1,33 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// Authenticate 認証
+type Authenticate struct {
+	TokenVerifier domain.TokenVerifier
+}
+
+func NewAuthenticate(verifier domain.TokenVerifier) *Authenticate {
+	return &Authenticate{
+		TokenVerifier: verifier,
+	}
+}
+
+// Execute トークンを検証してリクエスト送信者を取得
+func (a *Authenticate) Execute(ctx context.Context, req *usecase.AuthenticateRequest) (*usecase.AuthenticateResponse, error) {
+	if req.Token == "" {
+		return nil, errors.WithStack(domain.ErrUnauthorized)
+	}
+
+	principal, err := a.TokenVerifier.Verify(req.Token)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &usecase.AuthenticateResponse{Principal: principal}, nil
+}
@@ -3,24 +3,55 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
+var (
+	ErrReplyTargetNotFound = domain.NewValidationError("in_reply_to_id", "返信先のマイクロポストが存在しません。")
+)
+
 // CreateMicropost マイクロポスト作成
 type CreateMicropost struct {
 	MicropostRepository domain.MicropostRepository
+	UserRepository      domain.UserRepository
+	AccessPolicy        domain.AccessPolicy
 }
 
-func NewCreateMicropost(repos domain.MicropostRepository) *CreateMicropost {
+func NewCreateMicropost(repos domain.MicropostRepository, userRepos domain.UserRepository, policy domain.AccessPolicy) *CreateMicropost {
 	return &CreateMicropost{
 		MicropostRepository: repos,
+		UserRepository:      userRepos,
+		AccessPolicy:        policy,
 	}
 }
 
-// Execute マイクロポストを新規作成
-func (m *CreateMicropost) Execute(req *usecase.CreateMicropostRequest) (*usecase.CreateMicropostResponse, error) {
+// Execute マイクロポストを新規作成。投稿者のユーザーが存在しない場合はErrNotFoundを、
+// 返信先のマイクロポストが存在しない場合はErrReplyTargetNotFoundを返す
+func (m *CreateMicropost) Execute(ctx context.Context, req *usecase.CreateMicropostRequest) (*usecase.CreateMicropostResponse, error) {
+	err := m.AccessPolicy.AuthorizeOwner(req.Principal, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	_, err = m.UserRepository.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	newMicropost := domain.NewMicropostModel(req.Content, req.UserID)
-	micropost, err := m.MicropostRepository.CreateMicropost(newMicropost)
+	if req.InReplyToID != 0 {
+		_, err = m.MicropostRepository.GetMicropostByID(ctx, req.InReplyToID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, errors.WithStack(ErrReplyTargetNotFound)
+			}
+			return nil, errors.WithStack(err)
+		}
+		newMicropost = domain.NewReplyModel(req.Content, req.UserID, req.InReplyToID)
+	}
+
+	micropost, err := m.MicropostRepository.CreateMicropost(ctx, newMicropost)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
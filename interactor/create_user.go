@@ -3,11 +3,12 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
 var (
-	ErrUniqEmail = errors.New("unique email error")
+	ErrUniqEmail = domain.NewValidationError("email", "unique email error")
 )
 
 // UserCreator ユーザー新規作成
@@ -24,8 +25,8 @@ func NewCreateUser(repos domain.UserRepository, checker *domain.UserEmailUniqChe
 }
 
 // Execute ユーザーを新規作成
-func (u *UserCreator) Execute(req *usecase.CreateUserRequest) (*usecase.CreateUserResponse, error) {
-	isUniq, err := u.UniqChecker.IsUniqueEmail(req.ToUserModel())
+func (u *UserCreator) Execute(ctx context.Context, req *usecase.CreateUserRequest) (*usecase.CreateUserResponse, error) {
+	isUniq, err := u.UniqChecker.IsUniqueEmail(ctx, req.ToUserModel())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -33,7 +34,7 @@ func (u *UserCreator) Execute(req *usecase.CreateUserRequest) (*usecase.CreateUs
 		return nil, errors.WithStack(ErrUniqEmail)
 	}
 
-	user, err := u.UserRepository.CreateUser(req.ToUserModel())
+	user, err := u.UserRepository.CreateUser(ctx, req.ToUserModel())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -3,6 +3,7 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -10,18 +11,25 @@ import (
 type DeleteMicropost struct {
 	Getter              usecase.IGetMicropostByID
 	MicropostRepository domain.MicropostRepository
+	AccessPolicy        domain.AccessPolicy
 }
 
-func NewDeleteMicropost(getter usecase.IGetMicropostByID, repos domain.MicropostRepository) *DeleteMicropost {
+func NewDeleteMicropost(getter usecase.IGetMicropostByID, repos domain.MicropostRepository, policy domain.AccessPolicy) *DeleteMicropost {
 	return &DeleteMicropost{
 		Getter:              getter,
 		MicropostRepository: repos,
+		AccessPolicy:        policy,
 	}
 }
 
 // Execute マイクロポストを削除
-func (m *DeleteMicropost) Execute(req *usecase.DeleteMicropostRequest) (*usecase.DeleteMicropostResponse, error) {
-	res, err := m.Getter.Execute(&usecase.GetMicropostByIDRequest{
+func (m *DeleteMicropost) Execute(ctx context.Context, req *usecase.DeleteMicropostRequest) (*usecase.DeleteMicropostResponse, error) {
+	err := m.AccessPolicy.AuthorizeOwner(req.Principal, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := m.Getter.Execute(ctx, &usecase.GetMicropostByIDRequest{
 		MicropostID: req.MicropostID,
 		UserID:      req.UserID,
 	})
@@ -29,7 +37,12 @@ func (m *DeleteMicropost) Execute(req *usecase.DeleteMicropostRequest) (*usecase
 		return nil, errors.WithStack(err)
 	}
 
-	err = m.MicropostRepository.DeleteMicropost(res.Micropost.ID)
+	micropost := res.Micropost
+	if req.Version != 0 {
+		micropost.Version = req.Version
+	}
+
+	err = m.MicropostRepository.DeleteMicropost(ctx, micropost)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -3,33 +3,86 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
 // UserDeleter ユーザー削除
 type UserDeleter struct {
-	UserRepository domain.UserRepository
-	UserGetter     usecase.IGetUserByID
+	UserRepository         domain.UserRepository
+	MicropostRepository    domain.MicropostRepository
+	RelationshipRepository domain.RelationshipRepository
+	LikeRepository         domain.LikeRepository
+	UserGetter             usecase.IGetUserByID
+	AccessPolicy           domain.AccessPolicy
 }
 
-func NewUserDeleter(repos domain.UserRepository, getter usecase.IGetUserByID) *UserDeleter {
+func NewUserDeleter(repos domain.UserRepository, micropostRepos domain.MicropostRepository, relationshipRepos domain.RelationshipRepository, likeRepos domain.LikeRepository, getter usecase.IGetUserByID, policy domain.AccessPolicy) *UserDeleter {
 	return &UserDeleter{
-		UserRepository: repos,
-		UserGetter:     getter,
+		UserRepository:         repos,
+		MicropostRepository:    micropostRepos,
+		RelationshipRepository: relationshipRepos,
+		LikeRepository:         likeRepos,
+		UserGetter:             getter,
+		AccessPolicy:           policy,
 	}
 }
 
-// Execute ユーザーを削除
-func (u *UserDeleter) Execute(req *usecase.DeleteUserRequest) (*usecase.DeleteUserResponse, error) {
-	user, err := u.UserGetter.Execute(&usecase.GetUserByIDRequest{UserID: req.UserID})
+// Execute ユーザーを削除。ユーザーに紐づくマイクロポスト・フォロー関係・いいねも合わせて削除する
+func (u *UserDeleter) Execute(ctx context.Context, req *usecase.DeleteUserRequest) (*usecase.DeleteUserResponse, error) {
+	err := u.AccessPolicy.AuthorizeOwner(req.Principal, req.UserID)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	err = u.UserRepository.DeleteUser(user.User)
+	user, err := u.UserGetter.Execute(ctx, &usecase.GetUserByIDRequest{UserID: req.UserID})
 	if err != nil {
+		// ユーザーを削除した後に中断された場合でもリトライで削除しきれるように、紐づくデータを削除し直す
+		if errors.Is(err, domain.ErrNotFound) {
+			if _, err := u.deleteUserData(ctx, req.UserID); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return nil, errors.WithStack(domain.ErrNotFound)
+		}
 		return nil, errors.WithStack(err)
 	}
 
-	return &usecase.DeleteUserResponse{}, nil
+	target := *user.User
+	if req.Version != 0 {
+		target.Version = req.Version
+	}
+
+	// マイクロポストの作成・フォロー・いいねは同じトランザクション内でユーザーの存在を確認するため、
+	// 先にユーザーを削除して、紐づくデータを削除している間に新しいデータが作成されないようにする
+	err = u.UserRepository.DeleteUser(ctx, &target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	count, err := u.deleteUserData(ctx, target.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &usecase.DeleteUserResponse{DeletedMicropostCount: count}, nil
+}
+
+// deleteUserData ユーザーに紐づくマイクロポスト・フォロー関係・いいねを削除し、削除したマイクロポストの件数を返す
+func (u *UserDeleter) deleteUserData(ctx context.Context, userID uint64) (int, error) {
+	count, err := u.MicropostRepository.DeleteMicropostsByUserID(ctx, userID)
+	if err != nil {
+		return count, errors.WithStack(err)
+	}
+
+	_, err = u.RelationshipRepository.DeleteRelationshipsByUserID(ctx, userID)
+	if err != nil {
+		return count, errors.WithStack(err)
+	}
+
+	_, err = u.LikeRepository.DeleteLikesByUserID(ctx, userID)
+	if err != nil {
+		return count, errors.WithStack(err)
+	}
+
+	return count, nil
 }
@@ -0,0 +1,138 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/adapter/memory"
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// interleavedMicropostRepository マイクロポストの削除を始める直前に、指定した処理を割り込ませる
+type interleavedMicropostRepository struct {
+	domain.MicropostRepository
+	beforeDelete func()
+}
+
+func (r *interleavedMicropostRepository) DeleteMicropostsByUserID(ctx context.Context, userID uint64) (int, error) {
+	if r.beforeDelete != nil {
+		r.beforeDelete()
+		r.beforeDelete = nil
+	}
+	return r.MicropostRepository.DeleteMicropostsByUserID(ctx, userID)
+}
+
+// TestUserDeleter_ConcurrentCreate ユーザーの削除中に作成されたマイクロポスト・フォロー関係・いいねが残らないこと
+func TestUserDeleter_ConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserOperator(store)
+	microposts := memory.NewMicropostOperator(store)
+	relationships := memory.NewRelationshipOperator(store)
+	likes := memory.NewLikeOperator(store)
+
+	user, err := users.CreateUser(ctx, domain.NewUserModel("Name_1", "test1@example.com"))
+	require.NoError(t, err)
+	other, err := users.CreateUser(ctx, domain.NewUserModel("Name_2", "test2@example.com"))
+	require.NoError(t, err)
+	otherMicropost, err := microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content_other", other.ID))
+	require.NoError(t, err)
+	_, err = microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content_1", user.ID))
+	require.NoError(t, err)
+
+	// 削除対象のユーザーの存在を確認した後、紐づくデータの削除を始める前に作成を試みる
+	var createErr, followErr, likeErr error
+	interleaved := &interleavedMicropostRepository{
+		MicropostRepository: microposts,
+		beforeDelete: func() {
+			_, createErr = microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content_2", user.ID))
+			followErr = relationships.Follow(ctx, domain.NewRelationshipModel(user.ID, other.ID))
+			likeErr = likes.Like(ctx, domain.NewLikeModel(otherMicropost.ID, user.ID))
+		},
+	}
+
+	deleter := NewUserDeleter(users, interleaved, relationships, likes, NewGetUserByID(users), domain.NewOwnerPolicy())
+	res, err := deleter.Execute(ctx, &usecase.DeleteUserRequest{
+		UserID:    user.ID,
+		Principal: domain.NewPrincipal(fmt.Sprint(user.ID), nil),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.DeletedMicropostCount)
+
+	// 削除中のユーザーとしては作成できない
+	assert.True(t, errors.Is(createErr, domain.ErrNotFound), "%+v", createErr)
+	assert.True(t, errors.Is(followErr, domain.ErrNotFound), "%+v", followErr)
+	assert.True(t, errors.Is(likeErr, domain.ErrNotFound), "%+v", likeErr)
+
+	posts, _, err := microposts.GetMicropostsByUserID(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+
+	followers, _, err := relationships.GetFollowers(ctx, other.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, followers)
+
+	got, err := microposts.GetMicropostByID(ctx, otherMicropost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.LikeCount)
+}
+
+// TestUserDeleter_RetryAfterUserDeleted ユーザーの削除後に中断された場合でも、リトライで紐づくデータを削除できること
+func TestUserDeleter_RetryAfterUserDeleted(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserOperator(store)
+	microposts := memory.NewMicropostOperator(store)
+	relationships := memory.NewRelationshipOperator(store)
+	likes := memory.NewLikeOperator(store)
+
+	user, err := users.CreateUser(ctx, domain.NewUserModel("Name_1", "test1@example.com"))
+	require.NoError(t, err)
+	_, err = microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content_1", user.ID))
+	require.NoError(t, err)
+
+	// ユーザーだけが削除された状態
+	require.NoError(t, users.DeleteUser(ctx, user))
+
+	deleter := NewUserDeleter(users, microposts, relationships, likes, NewGetUserByID(users), domain.NewOwnerPolicy())
+	_, err = deleter.Execute(ctx, &usecase.DeleteUserRequest{
+		UserID:    user.ID,
+		Principal: domain.NewPrincipal(fmt.Sprint(user.ID), nil),
+	})
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "%+v", err)
+
+	posts, _, err := microposts.GetMicropostsByUserID(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+// TestUserDeleter_VersionConflict バージョンが一致しない場合は、ユーザーにも紐づくデータにも触れないこと
+func TestUserDeleter_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserOperator(store)
+	microposts := memory.NewMicropostOperator(store)
+
+	user, err := users.CreateUser(ctx, domain.NewUserModel("Name_1", "test1@example.com"))
+	require.NoError(t, err)
+	_, err = microposts.CreateMicropost(ctx, domain.NewMicropostModel("Content_1", user.ID))
+	require.NoError(t, err)
+
+	deleter := NewUserDeleter(users, microposts, memory.NewRelationshipOperator(store), memory.NewLikeOperator(store), NewGetUserByID(users), domain.NewOwnerPolicy())
+	_, err = deleter.Execute(ctx, &usecase.DeleteUserRequest{
+		UserID:    user.ID,
+		Version:   user.Version + 1,
+		Principal: domain.NewPrincipal(fmt.Sprint(user.ID), nil),
+	})
+	assert.True(t, errors.Is(err, domain.ErrPreconditionFailed), "%+v", err)
+
+	_, err = users.GetUserByID(ctx, user.ID)
+	assert.NoError(t, err)
+	posts, _, err := microposts.GetMicropostsByUserID(ctx, user.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, posts, 1)
+}
@@ -0,0 +1,59 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrFollowSelf = domain.NewValidationError("target_id", "自分自身をフォローすることはできません。")
+)
+
+// FollowUser フォロー
+type FollowUser struct {
+	RelationshipRepository domain.RelationshipRepository
+	UserRepository         domain.UserRepository
+	AccessPolicy           domain.AccessPolicy
+}
+
+func NewFollowUser(repos domain.RelationshipRepository, userRepos domain.UserRepository, policy domain.AccessPolicy) *FollowUser {
+	return &FollowUser{
+		RelationshipRepository: repos,
+		UserRepository:         userRepos,
+		AccessPolicy:           policy,
+	}
+}
+
+// Execute ユーザーをフォローする。すでにフォローしている場合は何もしない。
+// フォローするユーザーかフォロー対象のユーザーが存在しない場合はErrNotFoundを返す
+func (f *FollowUser) Execute(ctx context.Context, req *usecase.FollowUserRequest) (*usecase.FollowUserResponse, error) {
+	err := f.AccessPolicy.AuthorizeOwner(req.Principal, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if req.UserID == req.TargetID {
+		return nil, errors.WithStack(ErrFollowSelf)
+	}
+
+	_, err = f.UserRepository.GetUserByID(ctx, req.TargetID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	following, err := f.RelationshipRepository.IsFollowing(ctx, req.UserID, req.TargetID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if following {
+		return &usecase.FollowUserResponse{}, nil
+	}
+
+	err = f.RelationshipRepository.Follow(ctx, domain.NewRelationshipModel(req.UserID, req.TargetID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.FollowUserResponse{}, nil
+}
@@ -0,0 +1,43 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// GetFollowers フォロワー一覧取得
+type GetFollowers struct {
+	RelationshipRepository domain.RelationshipRepository
+	UserRepository         domain.UserRepository
+}
+
+func NewGetFollowers(repos domain.RelationshipRepository, userRepos domain.UserRepository) *GetFollowers {
+	return &GetFollowers{
+		RelationshipRepository: repos,
+		UserRepository:         userRepos,
+	}
+}
+
+// Execute フォロワー一覧取得
+func (g *GetFollowers) Execute(ctx context.Context, req *usecase.GetFollowersRequest) (*usecase.GetFollowersResponse, error) {
+	relationships, nextCursor, err := g.RelationshipRepository.GetFollowers(ctx, req.UserID, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ids := make([]uint64, len(relationships))
+	for i, r := range relationships {
+		ids[i] = r.FollowerID
+	}
+
+	users, err := g.UserRepository.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.GetFollowersResponse{Users: users, NextCursor: nextCursor}, nil
+}
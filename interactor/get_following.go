@@ -0,0 +1,43 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// GetFollowing フォロー中のユーザー一覧取得
+type GetFollowing struct {
+	RelationshipRepository domain.RelationshipRepository
+	UserRepository         domain.UserRepository
+}
+
+func NewGetFollowing(repos domain.RelationshipRepository, userRepos domain.UserRepository) *GetFollowing {
+	return &GetFollowing{
+		RelationshipRepository: repos,
+		UserRepository:         userRepos,
+	}
+}
+
+// Execute フォロー中のユーザー一覧取得
+func (g *GetFollowing) Execute(ctx context.Context, req *usecase.GetFollowingRequest) (*usecase.GetFollowingResponse, error) {
+	relationships, nextCursor, err := g.RelationshipRepository.GetFollowing(ctx, req.UserID, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ids := make([]uint64, len(relationships))
+	for i, r := range relationships {
+		ids[i] = r.FollowedID
+	}
+
+	users, err := g.UserRepository.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.GetFollowingResponse{Users: users, NextCursor: nextCursor}, nil
+}
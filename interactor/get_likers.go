@@ -0,0 +1,53 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// GetLikers いいねしたユーザー一覧取得
+type GetLikers struct {
+	Getter         usecase.IGetMicropostByID
+	LikeRepository domain.LikeRepository
+	UserRepository domain.UserRepository
+}
+
+func NewGetLikers(getter usecase.IGetMicropostByID, repos domain.LikeRepository, userRepos domain.UserRepository) *GetLikers {
+	return &GetLikers{
+		Getter:         getter,
+		LikeRepository: repos,
+		UserRepository: userRepos,
+	}
+}
+
+// Execute マイクロポストにいいねしたユーザー一覧取得
+func (g *GetLikers) Execute(ctx context.Context, req *usecase.GetLikersRequest) (*usecase.GetLikersResponse, error) {
+	_, err := g.Getter.Execute(ctx, &usecase.GetMicropostByIDRequest{
+		MicropostID: req.MicropostID,
+		UserID:      req.UserID,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	likes, nextCursor, err := g.LikeRepository.GetLikes(ctx, req.MicropostID, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ids := make([]uint64, len(likes))
+	for i, l := range likes {
+		ids[i] = l.UserID
+	}
+
+	users, err := g.UserRepository.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.GetLikersResponse{Users: users, NextCursor: nextCursor}, nil
+}
@@ -3,6 +3,7 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -15,8 +16,8 @@ func NewGetMicropostByID(repos domain.MicropostRepository) *GetMicropostByID {
 }
 
 // GetMicropostByID マイクロポスト取得
-func (m *GetMicropostByID) Execute(req *usecase.GetMicropostByIDRequest) (*usecase.GetMicropostByIDResponse, error) {
-	micropost, err := m.MicropostRepository.GetMicropostByID(req.MicropostID)
+func (m *GetMicropostByID) Execute(ctx context.Context, req *usecase.GetMicropostByIDRequest) (*usecase.GetMicropostByIDResponse, error) {
+	micropost, err := m.MicropostRepository.GetMicropostByID(ctx, req.MicropostID)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
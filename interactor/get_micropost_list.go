@@ -3,6 +3,7 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -18,10 +19,13 @@ func NewGetMicropostList(repos domain.MicropostRepository) *GetMicropostList {
 }
 
 // Execute マイクロポスト一覧取得
-func (m *GetMicropostList) Execute(req *usecase.GetMicropostListRequest) (*usecase.GetMicropostListResponse, error) {
-	microposts, err := m.MicropostRepository.GetMicropostsByUserID(req.UserID)
+func (m *GetMicropostList) Execute(ctx context.Context, req *usecase.GetMicropostListRequest) (*usecase.GetMicropostListResponse, error) {
+	microposts, nextCursor, err := m.MicropostRepository.GetMicropostsByUserID(ctx, req.UserID, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return &usecase.GetMicropostListResponse{Microposts: microposts}, nil
+	return &usecase.GetMicropostListResponse{Microposts: microposts, NextCursor: nextCursor}, nil
 }
@@ -0,0 +1,31 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// GetPublicTimeline 公開タイムライン取得
+type GetPublicTimeline struct {
+	MicropostRepository domain.MicropostRepository
+}
+
+func NewGetPublicTimeline(repos domain.MicropostRepository) *GetPublicTimeline {
+	return &GetPublicTimeline{
+		MicropostRepository: repos,
+	}
+}
+
+// Execute 全てのユーザーのマイクロポストを新しい順に取得
+func (g *GetPublicTimeline) Execute(ctx context.Context, req *usecase.GetPublicTimelineRequest) (*usecase.GetPublicTimelineResponse, error) {
+	microposts, nextCursor, err := g.MicropostRepository.GetPublicTimeline(ctx, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	}, req.SinceID, req.MaxID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.GetPublicTimelineResponse{Microposts: microposts, NextCursor: nextCursor}, nil
+}
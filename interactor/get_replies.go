@@ -0,0 +1,49 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// GetReplies 返信一覧取得
+type GetReplies struct {
+	MicropostRepository domain.MicropostRepository
+}
+
+func NewGetReplies(repos domain.MicropostRepository) *GetReplies {
+	return &GetReplies{
+		MicropostRepository: repos,
+	}
+}
+
+// Execute マイクロポストへの返信一覧取得。返信先が削除されていても返信が残っていれば取得できる。
+// 返信先も返信も存在しない場合はErrNotFoundを返す
+func (g *GetReplies) Execute(ctx context.Context, req *usecase.GetRepliesRequest) (*usecase.GetRepliesResponse, error) {
+	inReplyTo, err := g.MicropostRepository.GetMicropostByID(ctx, req.MicropostID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.WithStack(err)
+		}
+		inReplyTo = nil
+	}
+
+	microposts, nextCursor, err := g.MicropostRepository.GetReplies(ctx, req.MicropostID, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if inReplyTo == nil && len(microposts) == 0 && req.Cursor == "" {
+		return nil, errors.WithStack(domain.ErrNotFound)
+	}
+
+	return &usecase.GetRepliesResponse{
+		InReplyTo:  inReplyTo,
+		Microposts: microposts,
+		NextCursor: nextCursor,
+	}, nil
+}
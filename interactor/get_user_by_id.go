@@ -3,6 +3,7 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -17,8 +18,8 @@ func NewGetUserByID(repos domain.UserRepository) *GetUserByID {
 }
 
 // Execute ユーザーを取得
-func (u *GetUserByID) Execute(req *usecase.GetUserByIDRequest) (*usecase.GetUserByIDResponse, error) {
-	user, err := u.UserRepository.GetUserByID(req.UserID)
+func (u *GetUserByID) Execute(ctx context.Context, req *usecase.GetUserByIDRequest) (*usecase.GetUserByIDResponse, error) {
+	user, err := u.UserRepository.GetUserByID(ctx, req.UserID)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
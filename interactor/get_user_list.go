@@ -3,6 +3,7 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -18,10 +19,13 @@ func NewGetUserList(repos domain.UserRepository) *GetUserList {
 }
 
 // Execute ユーザー一覧を取得
-func (u *GetUserList) Execute(req *usecase.GetUserListRequest) (*usecase.GetUserListResponse, error) {
-	users, err := u.UserRepository.GetUsers()
+func (u *GetUserList) Execute(ctx context.Context, req *usecase.GetUserListRequest) (*usecase.GetUserListResponse, error) {
+	users, nextCursor, err := u.UserRepository.GetUsers(ctx, &domain.Paging{
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return &usecase.GetUserListResponse{Users: users}, nil
+	return &usecase.GetUserListResponse{Users: users, NextCursor: nextCursor}, nil
 }
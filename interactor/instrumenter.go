@@ -0,0 +1,399 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Instrumenter UseCaseをラップして、Executeごとに処理時間と結果をメトリクスに記録し、スパンを作成する
+type Instrumenter struct {
+	Metrics usecase.Metrics
+	Tracer  trace.Tracer
+}
+
+func NewInstrumenter(metrics usecase.Metrics, tracer trace.Tracer) *Instrumenter {
+	return &Instrumenter{Metrics: metrics, Tracer: tracer}
+}
+
+// start UseCaseのスパンを開始する。返り値の関数で、処理時間と結果の記録とスパンの終了を行う。
+// 利用者の入力によるエラーはスパンの属性に記録するだけで、サーバー側のエラーの場合のみスパンをエラーにする
+func (i *Instrumenter) start(ctx context.Context, name string) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := i.Tracer.Start(ctx, name, trace.WithAttributes(attribute.String("usecase.name", name)))
+
+	return ctx, func(err error) {
+		i.Metrics.ObserveUseCase(ctx, name, time.Since(start), err)
+
+		if err != nil {
+			kind := domain.KindOf(err)
+			span.SetAttributes(attribute.String("error.kind", kind.String()))
+			if kind == domain.KindInternal || kind == domain.KindUnavailable {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+		}
+		span.End()
+	}
+}
+
+// Authenticate 認証UseCaseをラップする
+func (i *Instrumenter) Authenticate(u usecase.IAuthenticate) usecase.IAuthenticate {
+	return &instrumentedAuthenticate{next: u, i: i}
+}
+
+type instrumentedAuthenticate struct {
+	next usecase.IAuthenticate
+	i    *Instrumenter
+}
+
+func (u *instrumentedAuthenticate) Execute(ctx context.Context, req *usecase.AuthenticateRequest) (*usecase.AuthenticateResponse, error) {
+	ctx, end := u.i.start(ctx, "Authenticate")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// CreateUser ユーザー作成UseCaseをラップする
+func (i *Instrumenter) CreateUser(u usecase.ICreateUser) usecase.ICreateUser {
+	return &instrumentedCreateUser{next: u, i: i}
+}
+
+type instrumentedCreateUser struct {
+	next usecase.ICreateUser
+	i    *Instrumenter
+}
+
+func (u *instrumentedCreateUser) Execute(ctx context.Context, req *usecase.CreateUserRequest) (*usecase.CreateUserResponse, error) {
+	ctx, end := u.i.start(ctx, "CreateUser")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// UpdateUser ユーザー更新UseCaseをラップする
+func (i *Instrumenter) UpdateUser(u usecase.IUpdateUser) usecase.IUpdateUser {
+	return &instrumentedUpdateUser{next: u, i: i}
+}
+
+type instrumentedUpdateUser struct {
+	next usecase.IUpdateUser
+	i    *Instrumenter
+}
+
+func (u *instrumentedUpdateUser) Execute(ctx context.Context, req *usecase.UpdateUserRequest) (*usecase.UpdateUserResponse, error) {
+	ctx, end := u.i.start(ctx, "UpdateUser")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetUserList ユーザー一覧取得UseCaseをラップする
+func (i *Instrumenter) GetUserList(u usecase.IGetUserList) usecase.IGetUserList {
+	return &instrumentedGetUserList{next: u, i: i}
+}
+
+type instrumentedGetUserList struct {
+	next usecase.IGetUserList
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetUserList) Execute(ctx context.Context, req *usecase.GetUserListRequest) (*usecase.GetUserListResponse, error) {
+	ctx, end := u.i.start(ctx, "GetUserList")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetUserByID ユーザー取得UseCaseをラップする
+func (i *Instrumenter) GetUserByID(u usecase.IGetUserByID) usecase.IGetUserByID {
+	return &instrumentedGetUserByID{next: u, i: i}
+}
+
+type instrumentedGetUserByID struct {
+	next usecase.IGetUserByID
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetUserByID) Execute(ctx context.Context, req *usecase.GetUserByIDRequest) (*usecase.GetUserByIDResponse, error) {
+	ctx, end := u.i.start(ctx, "GetUserByID")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// RepairUserStats ユーザー集計値修復UseCaseをラップする
+func (i *Instrumenter) RepairUserStats(u usecase.IRepairUserStats) usecase.IRepairUserStats {
+	return &instrumentedRepairUserStats{next: u, i: i}
+}
+
+type instrumentedRepairUserStats struct {
+	next usecase.IRepairUserStats
+	i    *Instrumenter
+}
+
+func (u *instrumentedRepairUserStats) Execute(ctx context.Context, req *usecase.RepairUserStatsRequest) (*usecase.RepairUserStatsResponse, error) {
+	ctx, end := u.i.start(ctx, "RepairUserStats")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// DeleteUser ユーザー削除UseCaseをラップする
+func (i *Instrumenter) DeleteUser(u usecase.IDeleteUser) usecase.IDeleteUser {
+	return &instrumentedDeleteUser{next: u, i: i}
+}
+
+type instrumentedDeleteUser struct {
+	next usecase.IDeleteUser
+	i    *Instrumenter
+}
+
+func (u *instrumentedDeleteUser) Execute(ctx context.Context, req *usecase.DeleteUserRequest) (*usecase.DeleteUserResponse, error) {
+	ctx, end := u.i.start(ctx, "DeleteUser")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// CreateMicropost マイクロポスト作成UseCaseをラップする
+func (i *Instrumenter) CreateMicropost(u usecase.ICreateMicropost) usecase.ICreateMicropost {
+	return &instrumentedCreateMicropost{next: u, i: i}
+}
+
+type instrumentedCreateMicropost struct {
+	next usecase.ICreateMicropost
+	i    *Instrumenter
+}
+
+func (u *instrumentedCreateMicropost) Execute(ctx context.Context, req *usecase.CreateMicropostRequest) (*usecase.CreateMicropostResponse, error) {
+	ctx, end := u.i.start(ctx, "CreateMicropost")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetMicropostList マイクロポスト一覧取得UseCaseをラップする
+func (i *Instrumenter) GetMicropostList(u usecase.IGetMicropostList) usecase.IGetMicropostList {
+	return &instrumentedGetMicropostList{next: u, i: i}
+}
+
+type instrumentedGetMicropostList struct {
+	next usecase.IGetMicropostList
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetMicropostList) Execute(ctx context.Context, req *usecase.GetMicropostListRequest) (*usecase.GetMicropostListResponse, error) {
+	ctx, end := u.i.start(ctx, "GetMicropostList")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetPublicTimeline 公開タイムライン取得UseCaseをラップする
+func (i *Instrumenter) GetPublicTimeline(u usecase.IGetPublicTimeline) usecase.IGetPublicTimeline {
+	return &instrumentedGetPublicTimeline{next: u, i: i}
+}
+
+type instrumentedGetPublicTimeline struct {
+	next usecase.IGetPublicTimeline
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetPublicTimeline) Execute(ctx context.Context, req *usecase.GetPublicTimelineRequest) (*usecase.GetPublicTimelineResponse, error) {
+	ctx, end := u.i.start(ctx, "GetPublicTimeline")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetMicropostByID マイクロポスト取得UseCaseをラップする
+func (i *Instrumenter) GetMicropostByID(u usecase.IGetMicropostByID) usecase.IGetMicropostByID {
+	return &instrumentedGetMicropostByID{next: u, i: i}
+}
+
+type instrumentedGetMicropostByID struct {
+	next usecase.IGetMicropostByID
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetMicropostByID) Execute(ctx context.Context, req *usecase.GetMicropostByIDRequest) (*usecase.GetMicropostByIDResponse, error) {
+	ctx, end := u.i.start(ctx, "GetMicropostByID")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// UpdateMicropost マイクロポスト更新UseCaseをラップする
+func (i *Instrumenter) UpdateMicropost(u usecase.IUpdateMicropost) usecase.IUpdateMicropost {
+	return &instrumentedUpdateMicropost{next: u, i: i}
+}
+
+type instrumentedUpdateMicropost struct {
+	next usecase.IUpdateMicropost
+	i    *Instrumenter
+}
+
+func (u *instrumentedUpdateMicropost) Execute(ctx context.Context, req *usecase.UpdateMicropostRequest) (*usecase.UpdateMicropostResponse, error) {
+	ctx, end := u.i.start(ctx, "UpdateMicropost")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// DeleteMicropost マイクロポスト削除UseCaseをラップする
+func (i *Instrumenter) DeleteMicropost(u usecase.IDeleteMicropost) usecase.IDeleteMicropost {
+	return &instrumentedDeleteMicropost{next: u, i: i}
+}
+
+type instrumentedDeleteMicropost struct {
+	next usecase.IDeleteMicropost
+	i    *Instrumenter
+}
+
+func (u *instrumentedDeleteMicropost) Execute(ctx context.Context, req *usecase.DeleteMicropostRequest) (*usecase.DeleteMicropostResponse, error) {
+	ctx, end := u.i.start(ctx, "DeleteMicropost")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// FollowUser フォローUseCaseをラップする
+func (i *Instrumenter) FollowUser(u usecase.IFollowUser) usecase.IFollowUser {
+	return &instrumentedFollowUser{next: u, i: i}
+}
+
+type instrumentedFollowUser struct {
+	next usecase.IFollowUser
+	i    *Instrumenter
+}
+
+func (u *instrumentedFollowUser) Execute(ctx context.Context, req *usecase.FollowUserRequest) (*usecase.FollowUserResponse, error) {
+	ctx, end := u.i.start(ctx, "FollowUser")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// UnfollowUser フォロー解除UseCaseをラップする
+func (i *Instrumenter) UnfollowUser(u usecase.IUnfollowUser) usecase.IUnfollowUser {
+	return &instrumentedUnfollowUser{next: u, i: i}
+}
+
+type instrumentedUnfollowUser struct {
+	next usecase.IUnfollowUser
+	i    *Instrumenter
+}
+
+func (u *instrumentedUnfollowUser) Execute(ctx context.Context, req *usecase.UnfollowUserRequest) (*usecase.UnfollowUserResponse, error) {
+	ctx, end := u.i.start(ctx, "UnfollowUser")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetFollowers フォロワー一覧取得UseCaseをラップする
+func (i *Instrumenter) GetFollowers(u usecase.IGetFollowers) usecase.IGetFollowers {
+	return &instrumentedGetFollowers{next: u, i: i}
+}
+
+type instrumentedGetFollowers struct {
+	next usecase.IGetFollowers
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetFollowers) Execute(ctx context.Context, req *usecase.GetFollowersRequest) (*usecase.GetFollowersResponse, error) {
+	ctx, end := u.i.start(ctx, "GetFollowers")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetFollowing フォロー中のユーザー一覧取得UseCaseをラップする
+func (i *Instrumenter) GetFollowing(u usecase.IGetFollowing) usecase.IGetFollowing {
+	return &instrumentedGetFollowing{next: u, i: i}
+}
+
+type instrumentedGetFollowing struct {
+	next usecase.IGetFollowing
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetFollowing) Execute(ctx context.Context, req *usecase.GetFollowingRequest) (*usecase.GetFollowingResponse, error) {
+	ctx, end := u.i.start(ctx, "GetFollowing")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// LikeMicropost いいねUseCaseをラップする
+func (i *Instrumenter) LikeMicropost(u usecase.ILikeMicropost) usecase.ILikeMicropost {
+	return &instrumentedLikeMicropost{next: u, i: i}
+}
+
+type instrumentedLikeMicropost struct {
+	next usecase.ILikeMicropost
+	i    *Instrumenter
+}
+
+func (u *instrumentedLikeMicropost) Execute(ctx context.Context, req *usecase.LikeMicropostRequest) (*usecase.LikeMicropostResponse, error) {
+	ctx, end := u.i.start(ctx, "LikeMicropost")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// UnlikeMicropost いいね取り消しUseCaseをラップする
+func (i *Instrumenter) UnlikeMicropost(u usecase.IUnlikeMicropost) usecase.IUnlikeMicropost {
+	return &instrumentedUnlikeMicropost{next: u, i: i}
+}
+
+type instrumentedUnlikeMicropost struct {
+	next usecase.IUnlikeMicropost
+	i    *Instrumenter
+}
+
+func (u *instrumentedUnlikeMicropost) Execute(ctx context.Context, req *usecase.UnlikeMicropostRequest) (*usecase.UnlikeMicropostResponse, error) {
+	ctx, end := u.i.start(ctx, "UnlikeMicropost")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetLikers いいねしたユーザー一覧取得UseCaseをラップする
+func (i *Instrumenter) GetLikers(u usecase.IGetLikers) usecase.IGetLikers {
+	return &instrumentedGetLikers{next: u, i: i}
+}
+
+type instrumentedGetLikers struct {
+	next usecase.IGetLikers
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetLikers) Execute(ctx context.Context, req *usecase.GetLikersRequest) (*usecase.GetLikersResponse, error) {
+	ctx, end := u.i.start(ctx, "GetLikers")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
+
+// GetReplies 返信一覧取得UseCaseをラップする
+func (i *Instrumenter) GetReplies(u usecase.IGetReplies) usecase.IGetReplies {
+	return &instrumentedGetReplies{next: u, i: i}
+}
+
+type instrumentedGetReplies struct {
+	next usecase.IGetReplies
+	i    *Instrumenter
+}
+
+func (u *instrumentedGetReplies) Execute(ctx context.Context, req *usecase.GetRepliesRequest) (*usecase.GetRepliesResponse, error) {
+	ctx, end := u.i.start(ctx, "GetReplies")
+	res, err := u.next.Execute(ctx, req)
+	end(err)
+	return res, err
+}
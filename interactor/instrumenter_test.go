@@ -0,0 +1,92 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"testing"
+	"time"
+)
+
+// observation 記録されたメトリクス
+type observation struct {
+	Name string
+	Err  error
+}
+
+// fakeMetrics 記録されたメトリクスを保持する
+type fakeMetrics struct {
+	observations []observation
+}
+
+func (m *fakeMetrics) ObserveUseCase(ctx context.Context, name string, duration time.Duration, err error) {
+	m.observations = append(m.observations, observation{Name: name, Err: err})
+}
+
+// stubGetUserByID 指定した結果を返すUseCase
+type stubGetUserByID struct {
+	res *usecase.GetUserByIDResponse
+	err error
+}
+
+func (s *stubGetUserByID) Execute(ctx context.Context, req *usecase.GetUserByIDRequest) (*usecase.GetUserByIDResponse, error) {
+	return s.res, s.err
+}
+
+// TestInstrumenter UseCaseの実行ごとに、UseCase名と結果が記録されること
+func TestInstrumenter(t *testing.T) {
+	metrics := &fakeMetrics{}
+	instrumenter := NewInstrumenter(metrics, noop.NewTracerProvider().Tracer("test"))
+
+	expected := &usecase.GetUserByIDResponse{User: &domain.UserModel{ID: 1}}
+	res, err := instrumenter.GetUserByID(&stubGetUserByID{res: expected}).
+		Execute(context.Background(), &usecase.GetUserByIDRequest{UserID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res)
+
+	notFound := errors.WithStack(domain.ErrNotFound)
+	_, err = instrumenter.GetUserByID(&stubGetUserByID{err: notFound}).
+		Execute(context.Background(), &usecase.GetUserByIDRequest{UserID: 2})
+	assert.Equal(t, notFound, err)
+
+	require.Len(t, metrics.observations, 2)
+	assert.Equal(t, observation{Name: "GetUserByID"}, metrics.observations[0])
+	assert.Equal(t, observation{Name: "GetUserByID", Err: notFound}, metrics.observations[1])
+}
+
+// TestInstrumenter_Span UseCaseの実行ごとにスパンが作成され、サーバー側のエラーの場合のみスパンがエラーになること
+func TestInstrumenter_Span(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	instrumenter := NewInstrumenter(&fakeMetrics{}, provider.Tracer("test"))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	_, err := instrumenter.GetUserByID(&stubGetUserByID{err: errors.WithStack(domain.ErrNotFound)}).
+		Execute(ctx, &usecase.GetUserByIDRequest{UserID: 1})
+	assert.Error(t, err)
+	_, err = instrumenter.GetUserByID(&stubGetUserByID{err: errors.New("internal")}).
+		Execute(ctx, &usecase.GetUserByIDRequest{UserID: 1})
+	assert.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	notFound := spans[0]
+	assert.Equal(t, "GetUserByID", notFound.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), notFound.Parent().SpanID())
+	assert.Equal(t, codes.Unset, notFound.Status().Code)
+	assert.Contains(t, notFound.Attributes(), attribute.String("error.kind", "NotFound"))
+
+	internal := spans[1]
+	assert.Equal(t, codes.Error, internal.Status().Code)
+	assert.Contains(t, internal.Attributes(), attribute.String("error.kind", "Internal"))
+}
@@ -0,0 +1,55 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// LikeMicropost いいね
+type LikeMicropost struct {
+	Getter         usecase.IGetMicropostByID
+	LikeRepository domain.LikeRepository
+}
+
+func NewLikeMicropost(getter usecase.IGetMicropostByID, repos domain.LikeRepository) *LikeMicropost {
+	return &LikeMicropost{
+		Getter:         getter,
+		LikeRepository: repos,
+	}
+}
+
+// Execute マイクロポストにいいねする。すでにいいねしている場合は何もしない
+func (l *LikeMicropost) Execute(ctx context.Context, req *usecase.LikeMicropostRequest) (*usecase.LikeMicropostResponse, error) {
+	likerID, err := principalUserID(req.Principal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	_, err = l.Getter.Execute(ctx, &usecase.GetMicropostByIDRequest{
+		MicropostID: req.MicropostID,
+		UserID:      req.UserID,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = l.LikeRepository.Like(ctx, domain.NewLikeModel(req.MicropostID, likerID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.LikeMicropostResponse{}, nil
+}
+
+// principalUserID リクエスト送信者のユーザーID。ユーザーとして認証されていない場合はErrForbiddenを返す
+func principalUserID(principal *domain.Principal) (uint64, error) {
+	if principal == nil {
+		return 0, errors.WithStack(domain.ErrForbidden)
+	}
+	id, ok := principal.UserID()
+	if !ok {
+		return 0, errors.WithStack(domain.ErrForbidden)
+	}
+	return id, nil
+}
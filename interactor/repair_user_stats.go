@@ -0,0 +1,27 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+type RepairUserStats struct {
+	UserRepository domain.UserRepository
+}
+
+func NewRepairUserStats(repos domain.UserRepository) *RepairUserStats {
+	return &RepairUserStats{
+		UserRepository: repos,
+	}
+}
+
+// Execute ユーザーのマイクロポスト件数と投稿日時を計算し直す
+func (r *RepairUserStats) Execute(ctx context.Context, req *usecase.RepairUserStatsRequest) (*usecase.RepairUserStatsResponse, error) {
+	user, err := r.UserRepository.RepairUserStats(ctx, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.RepairUserStatsResponse{User: user}, nil
+}
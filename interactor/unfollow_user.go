@@ -0,0 +1,35 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// UnfollowUser フォロー解除
+type UnfollowUser struct {
+	RelationshipRepository domain.RelationshipRepository
+	AccessPolicy           domain.AccessPolicy
+}
+
+func NewUnfollowUser(repos domain.RelationshipRepository, policy domain.AccessPolicy) *UnfollowUser {
+	return &UnfollowUser{
+		RelationshipRepository: repos,
+		AccessPolicy:           policy,
+	}
+}
+
+// Execute フォローを解除する。フォローしていない場合はErrNotFoundを返す
+func (u *UnfollowUser) Execute(ctx context.Context, req *usecase.UnfollowUserRequest) (*usecase.UnfollowUserResponse, error) {
+	err := u.AccessPolicy.AuthorizeOwner(req.Principal, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = u.RelationshipRepository.Unfollow(ctx, domain.NewRelationshipModel(req.UserID, req.TargetID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.UnfollowUserResponse{}, nil
+}
@@ -0,0 +1,43 @@
+package interactor
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"clean-serverless-book-sample-v2/usecase"
+	"context"
+	"github.com/pkg/errors"
+)
+
+// UnlikeMicropost いいね取り消し
+type UnlikeMicropost struct {
+	Getter         usecase.IGetMicropostByID
+	LikeRepository domain.LikeRepository
+}
+
+func NewUnlikeMicropost(getter usecase.IGetMicropostByID, repos domain.LikeRepository) *UnlikeMicropost {
+	return &UnlikeMicropost{
+		Getter:         getter,
+		LikeRepository: repos,
+	}
+}
+
+// Execute マイクロポストへのいいねを取り消す。いいねしていない場合は何もしない
+func (u *UnlikeMicropost) Execute(ctx context.Context, req *usecase.UnlikeMicropostRequest) (*usecase.UnlikeMicropostResponse, error) {
+	likerID, err := principalUserID(req.Principal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	_, err = u.Getter.Execute(ctx, &usecase.GetMicropostByIDRequest{
+		MicropostID: req.MicropostID,
+		UserID:      req.UserID,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = u.LikeRepository.Unlike(ctx, domain.NewLikeModel(req.MicropostID, likerID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &usecase.UnlikeMicropostResponse{}, nil
+}
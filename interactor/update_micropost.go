@@ -3,25 +3,47 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
 // UpdateMicropost
 type UpdateMicropost struct {
+	Getter              usecase.IGetMicropostByID
 	MicropostRepository domain.MicropostRepository
+	AccessPolicy        domain.AccessPolicy
 }
 
-func NewUpdateMicropost(repos domain.MicropostRepository) *UpdateMicropost {
+func NewUpdateMicropost(getter usecase.IGetMicropostByID, repos domain.MicropostRepository, policy domain.AccessPolicy) *UpdateMicropost {
 	return &UpdateMicropost{
+		Getter:              getter,
 		MicropostRepository: repos,
+		AccessPolicy:        policy,
 	}
 }
 
 // Execute 更新
-func (m *UpdateMicropost) Execute(req *usecase.UpdateMicropostRequest) (*usecase.UpdateMicropostResponse, error) {
-	newMicropost := domain.NewMicropostModel(req.Content, req.UserID)
-	newMicropost.ID = req.MicropostID
-	err := m.MicropostRepository.UpdateMicropost(newMicropost)
+func (m *UpdateMicropost) Execute(ctx context.Context, req *usecase.UpdateMicropostRequest) (*usecase.UpdateMicropostResponse, error) {
+	err := m.AccessPolicy.AuthorizeOwner(req.Principal, req.UserID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// 存在しない、または他のユーザーのマイクロポストの場合はErrNotFoundになる
+	res, err := m.Getter.Execute(ctx, &usecase.GetMicropostByIDRequest{
+		MicropostID: req.MicropostID,
+		UserID:      req.UserID,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	micropost := res.Micropost
+	micropost.Content = req.Content
+	if req.Version != 0 {
+		micropost.Version = req.Version
+	}
+	err = m.MicropostRepository.UpdateMicropost(ctx, micropost)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
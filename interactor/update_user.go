@@ -3,6 +3,7 @@ package interactor
 import (
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/usecase"
+	"context"
 	"github.com/pkg/errors"
 )
 
@@ -10,18 +11,25 @@ import (
 type UpdateUser struct {
 	UserRepository domain.UserRepository
 	UniqChecker    *domain.UserEmailUniqChecker
+	AccessPolicy   domain.AccessPolicy
 }
 
-func NewUpdateUser(repos domain.UserRepository, checker *domain.UserEmailUniqChecker) *UpdateUser {
+func NewUpdateUser(repos domain.UserRepository, checker *domain.UserEmailUniqChecker, policy domain.AccessPolicy) *UpdateUser {
 	return &UpdateUser{
 		UserRepository: repos,
 		UniqChecker:    checker,
+		AccessPolicy:   policy,
 	}
 }
 
 // Execute ユーザーを更新
-func (u *UpdateUser) Execute(req *usecase.UpdateUserRequest) (*usecase.UpdateUserResponse, error) {
-	isUniq, err := u.UniqChecker.IsUniqueEmail(req.ToUserModel())
+func (u *UpdateUser) Execute(ctx context.Context, req *usecase.UpdateUserRequest) (*usecase.UpdateUserResponse, error) {
+	err := u.AccessPolicy.AuthorizeOwner(req.Principal, req.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	isUniq, err := u.UniqChecker.IsUniqueEmail(ctx, req.ToUserModel())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -29,7 +37,7 @@ func (u *UpdateUser) Execute(req *usecase.UpdateUserRequest) (*usecase.UpdateUse
 		return nil, errors.WithStack(ErrUniqEmail)
 	}
 
-	err = u.UserRepository.UpdateUser(req.ToUserModel())
+	err = u.UserRepository.UpdateUser(ctx, req.ToUserModel())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
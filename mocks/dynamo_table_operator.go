@@ -4,37 +4,61 @@ import (
 	"clean-serverless-book-sample-v2/adapter"
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/registry"
+	"context"
 	"crypto/rand"
 	"fmt"
 	"os"
 	"testing"
 )
 
+// testPagingCursorSecret テスト用のカーソル署名鍵
+const testPagingCursorSecret = "test-paging-cursor-secret"
+
 type DynamoTableOperator struct {
-	Operator          *adapter.ResourceTableOperator
-	UserOperator      domain.UserRepository
-	MicropostOperator domain.MicropostRepository
+	Operator             *adapter.ResourceTableOperator
+	UserOperator         domain.UserRepository
+	MicropostOperator    domain.MicropostRepository
+	RelationshipOperator domain.RelationshipRepository
+	LikeOperator         domain.LikeRepository
 }
 
+// SetupDB テスト用のリポジトリを準備する。DynamoDB Localにテーブルを作成して使う。
+// REPOSITORY_BACKEND=memory の場合はメモリ上のリポジトリを使い、
+// どちらも使えない(DYNAMO_LOCAL_ENDPOINTが設定されていない)場合はテストをスキップする
 func SetupDB(t *testing.T) *DynamoTableOperator {
 	t.Helper()
 
+	if os.Getenv("REPOSITORY_BACKEND") != "memory" && os.Getenv("DYNAMO_LOCAL_ENDPOINT") == "" {
+		t.Skip("DYNAMO_LOCAL_ENDPOINT is not set. Set REPOSITORY_BACKEND=memory to run against the in-memory repositories")
+	}
+
 	os.Setenv("DYNAMO_TABLE_NAME", generateRandomTableName(t))
+	os.Setenv("DISABLE_ENV_DECRYPT", "1")
+	os.Setenv("JWT_HS256_SECRET", TestJWTSecret)
+	os.Setenv("PAGING_CURSOR_SECRET", testPagingCursorSecret)
 
 	registry.ClearFactory()
 	f := registry.GetFactory()
 	operator := &DynamoTableOperator{}
-	operator.Operator = f.BuildResourceTableOperator()
 	operator.UserOperator = f.BuildUserOperator()
 	operator.MicropostOperator = f.BuildMicropostOperator()
+	operator.RelationshipOperator = f.BuildRelationshipOperator()
+	operator.LikeOperator = f.BuildLikeOperator()
 
-	operator.Operator.CreateTableForTest()
+	if f.Envs.UseMemoryRepository() {
+		t.Log("using the in-memory repositories (REPOSITORY_BACKEND=memory)")
+	} else {
+		operator.Operator = f.BuildResourceTableOperator()
+		operator.Operator.CreateTableForTest()
+	}
 
 	return operator
 }
 
 func (d *DynamoTableOperator) Cleanup() {
-	d.Operator.DropTable()
+	if d.Operator != nil {
+		d.Operator.DropTable()
+	}
 }
 
 func generateRandomTableName(t *testing.T) string {
@@ -49,3 +73,16 @@ func generateRandomTableName(t *testing.T) string {
 	}
 	return fmt.Sprintf("%x", buf[0:l])
 }
+
+// CreateUserMock テスト用のユーザーを作成する
+func (d *DynamoTableOperator) CreateUserMock(t *testing.T, n int) *domain.UserModel {
+	t.Helper()
+	user, err := d.UserOperator.CreateUser(context.Background(), &domain.UserModel{
+		Name:  fmt.Sprintf("Name_%d", n),
+		Email: fmt.Sprintf("test%d@example.com", n),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
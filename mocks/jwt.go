@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestJWTSecret テスト用のJWT署名鍵
+const TestJWTSecret = "test-jwt-secret"
+
+// SignJWT HS256でJWTを署名する
+func SignJWT(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return header + "." + payload + "." + signature
+}
+
+// AuthHeaders 指定したユーザーとして認証済みのAuthorizationヘッダーを生成する
+func AuthHeaders(t *testing.T, userID uint64, roles ...string) map[string]string {
+	t.Helper()
+
+	claims := map[string]interface{}{
+		"sub": fmt.Sprintf("%d", userID),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if len(roles) > 0 {
+		claims["roles"] = roles
+	}
+
+	return map[string]string{
+		"Authorization": "Bearer " + SignJWT(t, TestJWTSecret, claims),
+	}
+}
@@ -2,7 +2,7 @@ package registry
 
 import (
 	"clean-serverless-book-sample-v2/adapter"
-	"github.com/golang/glog"
+	"github.com/pkg/errors"
 	"os"
 )
 
@@ -30,40 +30,84 @@ func Env() *Envs {
 	return envs
 }
 
-func (c *Envs) decrypt(key string) string {
+// secretKeys KMSで暗号化した値を設定する環境変数
+var secretKeys = []string{"PAGING_CURSOR_SECRET", "JWT_HS256_SECRET"}
+
+// LoadSecrets 暗号化された環境変数を全て復号する。
+// 復号できない値で起動を続けると全てのリクエストが失敗するため、起動時に呼び出してエラーの場合は起動を中止する
+func (c *Envs) LoadSecrets() error {
+	for _, key := range secretKeys {
+		if _, err := c.decrypt(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decrypt KMSで暗号化された環境変数を復号する。DISABLE_ENV_DECRYPTが設定されている場合は平文として扱う
+func (c *Envs) decrypt(key string) (string, error) {
 	if os.Getenv("DISABLE_ENV_DECRYPT") != "" {
-		return c.env(key)
+		return c.env(key), nil
 	}
 
 	v := c.Cache[key]
 	if v != "" {
-		return v
+		return v, nil
 	}
 
 	str := os.Getenv(key)
 	if str == "" {
-		return ""
+		return "", nil
 	}
 
 	v, err := c.KMSClient.Decrypt(str)
 	if err != nil {
-		glog.Warning(err.Error())
-		return ""
+		return "", errors.Wrapf(err, "failed to decrypt %s", key)
 	}
 
 	c.Cache[key] = v
 
-	return c.Cache[key]
+	return c.Cache[key], nil
 }
 
 func (c *Envs) env(key string) string {
 	return os.Getenv(key)
 }
 
+// MetricsNamespace CloudWatchのメトリクスの名前空間。空の場合はメトリクスを記録しない
+func (c *Envs) MetricsNamespace() string {
+	return c.env("METRICS_NAMESPACE")
+}
+
+// TracingExporter トレースの書き出し先。"stdout"・"otlp"のいずれか。空の場合はトレースを記録しない
+func (c *Envs) TracingExporter() string {
+	return c.env("TRACING_EXPORTER")
+}
+
+// OTLPEndpoint TracingExporterが"otlp"の場合の送信先。空の場合は http://localhost:4318 に送信する
+func (c *Envs) OTLPEndpoint() string {
+	return c.env("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// LogLevel 出力するログの重要度の下限。DEBUG・INFO・WARN・ERRORのいずれか
+func (c *Envs) LogLevel() string {
+	return c.env("LOG_LEVEL")
+}
+
 func (c *Envs) DynamoLocalEndpoint() string {
 	return c.env("DYNAMO_LOCAL_ENDPOINT")
 }
 
+// RepositoryBackend リポジトリの保存先。"memory"の場合はDynamoDBを使わずにメモリ上に保存する
+func (c *Envs) RepositoryBackend() string {
+	return c.env("REPOSITORY_BACKEND")
+}
+
+// UseMemoryRepository リポジトリの保存先がメモリかどうか
+func (c *Envs) UseMemoryRepository() bool {
+	return c.RepositoryBackend() == "memory"
+}
+
 func (c *Envs) DynamoTableName() string {
 	return c.env("DYNAMO_TABLE_NAME")
 }
@@ -75,3 +119,23 @@ func (c *Envs) DynamoPKName() string {
 func (c *Envs) DynamoSKName() string {
 	return c.env("DYNAMO_SK_NAME")
 }
+
+func (c *Envs) PagingCursorSecret() (string, error) {
+	return c.decrypt("PAGING_CURSOR_SECRET")
+}
+
+func (c *Envs) JWTHS256Secret() (string, error) {
+	return c.decrypt("JWT_HS256_SECRET")
+}
+
+func (c *Envs) JWTRS256PublicKey() string {
+	return c.env("JWT_RS256_PUBLIC_KEY")
+}
+
+func (c *Envs) JWTIssuer() string {
+	return c.env("JWT_ISSUER")
+}
+
+func (c *Envs) JWTAudience() string {
+	return c.env("JWT_AUDIENCE")
+}
@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+// TestEnvs_LoadSecrets 暗号化された環境変数を復号できない場合は、空文字列にせずエラーを返すこと
+func TestEnvs_LoadSecrets(t *testing.T) {
+	for _, key := range append([]string{"DISABLE_ENV_DECRYPT"}, secretKeys...) {
+		old, ok := os.LookupEnv(key)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+	os.Unsetenv("DISABLE_ENV_DECRYPT")
+	os.Unsetenv("JWT_HS256_SECRET")
+	// KMSで暗号化した値はBase64でエンコードされているため、KMSに問い合わせる前に失敗する
+	os.Setenv("PAGING_CURSOR_SECRET", "not encrypted")
+
+	envs := NewEnvs()
+	err := envs.LoadSecrets()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PAGING_CURSOR_SECRET")
+
+	_, err = envs.PagingCursorSecret()
+	assert.Error(t, err)
+
+	// 復号しない場合は平文のまま使う
+	os.Setenv("DISABLE_ENV_DECRYPT", "1")
+	assert.NoError(t, envs.LoadSecrets())
+	secret, err := envs.PagingCursorSecret()
+	require.NoError(t, err)
+	assert.Equal(t, "not encrypted", secret)
+}
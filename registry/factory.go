@@ -2,11 +2,18 @@ package registry
 
 import (
 	"clean-serverless-book-sample-v2/adapter"
+	"clean-serverless-book-sample-v2/adapter/logging"
+	"clean-serverless-book-sample-v2/adapter/memory"
+	"clean-serverless-book-sample-v2/adapter/tracing"
 	"clean-serverless-book-sample-v2/domain"
 	"clean-serverless-book-sample-v2/interactor"
 	"clean-serverless-book-sample-v2/usecase"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"os"
 )
 
 // FactorySingleton Factoryのインスタンスを使い回すための変数
@@ -44,6 +51,51 @@ func (f *Factory) container(key string, builder func() interface{}) interface{}
 	return f.cache[key]
 }
 
+// BuildLogger JSON形式で標準出力にログを出力するインスタンスを生成。LOG_LEVELが不正な場合はINFOとして扱う
+func (f *Factory) BuildLogger() *logging.Logger {
+	return f.container("Logger", func() interface{} {
+		level, err := logging.ParseLevel(f.Envs.LogLevel())
+		logger := logging.New(os.Stdout, level)
+		if err != nil {
+			logger.Warn("invalid LOG_LEVEL", "error", err)
+		}
+		return logger
+	}).(*logging.Logger)
+}
+
+// BuildMetrics CloudWatchのEmbedded Metric Formatで標準出力にメトリクスを書き出すインスタンスを生成。
+// METRICS_NAMESPACEが設定されていない場合は何も記録しない
+func (f *Factory) BuildMetrics() adapter.Metrics {
+	return f.container("Metrics", func() interface{} {
+		if f.Envs.MetricsNamespace() == "" {
+			return adapter.NopMetrics{}
+		}
+		return adapter.NewEMFMetrics(f.Envs.MetricsNamespace(), os.Stdout)
+	}).(adapter.Metrics)
+}
+
+// BuildTracer スパンを作成するインスタンスを生成。TRACING_EXPORTERで指定した書き出し先をグローバルなTracerProviderに設定する。
+// TRACING_EXPORTERが設定されていない場合は、グローバルなTracerProviderを変更しないため、何も記録しない
+func (f *Factory) BuildTracer() trace.Tracer {
+	return f.container("Tracer", func() interface{} {
+		provider, err := tracing.NewProvider(f.Envs.TracingExporter(), os.Stdout, f.Envs.OTLPEndpoint())
+		if err != nil {
+			f.BuildLogger().Warn("invalid TRACING_EXPORTER", "error", err)
+		}
+		if provider != nil {
+			otel.SetTracerProvider(provider)
+		}
+		return otel.Tracer(tracing.InstrumentationName)
+	}).(trace.Tracer)
+}
+
+// BuildInstrumenter UseCaseの処理時間と結果を記録するためのインスタンスを生成
+func (f *Factory) BuildInstrumenter() *interactor.Instrumenter {
+	return f.container("Instrumenter", func() interface{} {
+		return interactor.NewInstrumenter(f.BuildMetrics(), f.BuildTracer())
+	}).(*interactor.Instrumenter)
+}
+
 // BuildDynamoClient DynamoDBに接続するためのインスタンスを生成
 func (f *Factory) BuildDynamoClient() *adapter.DynamoClient {
 	return f.container("DynamoClient", func() interface{} {
@@ -76,6 +128,8 @@ func (f *Factory) BuildDynamoModelMapper() *adapter.DynamoModelMapper {
 			TableName: f.Envs.DynamoTableName(),
 			PKName:    f.Envs.DynamoPKName(),
 			SKName:    f.Envs.DynamoSKName(),
+			Metrics:   f.BuildMetrics(),
+			Tracer:    f.BuildTracer(),
 		}
 	}).(*adapter.DynamoModelMapper)
 }
@@ -91,13 +145,40 @@ func (f *Factory) BuildUserEmailUniqGenerator() *adapter.UserEmailUniqGenerator
 	}).(*adapter.UserEmailUniqGenerator)
 }
 
+// BuildPagingCursor 一覧取得のカーソルを署名・検証するインスタンスを生成。
+// PAGING_CURSOR_SECRETが設定されていない場合は、インスタンスごとにカーソルを拒否し合うことになるためpanicする
+func (f *Factory) BuildPagingCursor() *adapter.PagingCursor {
+	return f.container("PagingCursor", func() interface{} {
+		secret, err := f.Envs.PagingCursorSecret()
+		if err != nil {
+			panic(err)
+		}
+		cursor, err := adapter.NewPagingCursor(secret)
+		if err != nil {
+			panic(errors.Wrap(err, "PAGING_CURSOR_SECRET is not set"))
+		}
+		return cursor
+	}).(*adapter.PagingCursor)
+}
+
+// BuildMemoryStore リポジトリの保存先をメモリにする場合に、データを保持するインスタンスを生成
+func (f *Factory) BuildMemoryStore() *memory.Store {
+	return f.container("MemoryStore", func() interface{} {
+		return memory.NewStore()
+	}).(*memory.Store)
+}
+
 // BuildUserOperator ユーザー情報関連の操作を行うインスタンスを生成
 func (f *Factory) BuildUserOperator() domain.UserRepository {
 	return f.container("UserOperator", func() interface{} {
+		if f.Envs.UseMemoryRepository() {
+			return memory.NewUserOperator(f.BuildMemoryStore())
+		}
 		return &adapter.UserOperator{
 			Client:                 f.BuildResourceTableOperator(),
 			Mapper:                 f.BuildDynamoModelMapper(),
 			UserEmailUniqGenerator: f.BuildUserEmailUniqGenerator(),
+			Cursor:                 f.BuildPagingCursor(),
 		}
 	}).(domain.UserRepository)
 }
@@ -110,93 +191,257 @@ func (f *Factory) BuildUserEmailUniqChecker() *domain.UserEmailUniqChecker {
 }
 
 // BuildMicropostOperator マイクロポスト情報関連の操作を行うインスタンスを生成
-func (f *Factory) BuildMicropostOperator() *adapter.MicropostOperator {
+func (f *Factory) BuildMicropostOperator() domain.MicropostRepository {
 	return f.container("MicropostOperator", func() interface{} {
+		if f.Envs.UseMemoryRepository() {
+			return memory.NewMicropostOperator(f.BuildMemoryStore())
+		}
 		return &adapter.MicropostOperator{
 			Client: f.BuildResourceTableOperator(),
 			Mapper: f.BuildDynamoModelMapper(),
+			Cursor: f.BuildPagingCursor(),
+		}
+	}).(domain.MicropostRepository)
+}
+
+// BuildRelationshipOperator フォロー関係の操作を行うインスタンスを生成
+func (f *Factory) BuildRelationshipOperator() domain.RelationshipRepository {
+	return f.container("RelationshipOperator", func() interface{} {
+		if f.Envs.UseMemoryRepository() {
+			return memory.NewRelationshipOperator(f.BuildMemoryStore())
+		}
+		return &adapter.RelationshipOperator{
+			Client: f.BuildResourceTableOperator(),
+			Mapper: f.BuildDynamoModelMapper(),
+			Cursor: f.BuildPagingCursor(),
+		}
+	}).(domain.RelationshipRepository)
+}
+
+// BuildLikeOperator いいねの操作を行うインスタンスを生成
+func (f *Factory) BuildLikeOperator() domain.LikeRepository {
+	return f.container("LikeOperator", func() interface{} {
+		if f.Envs.UseMemoryRepository() {
+			return memory.NewLikeOperator(f.BuildMemoryStore())
+		}
+		return &adapter.LikeOperator{
+			Client: f.BuildResourceTableOperator(),
+			Mapper: f.BuildDynamoModelMapper(),
+			Cursor: f.BuildPagingCursor(),
+		}
+	}).(domain.LikeRepository)
+}
+
+// BuildTokenVerifier 認証トークンを検証するインスタンスを生成。
+// JWT_HS256_SECRETもJWT_RS256_PUBLIC_KEYも設定されていない場合は、誰も認証できないためpanicする
+func (f *Factory) BuildTokenVerifier() domain.TokenVerifier {
+	return f.container("TokenVerifier", func() interface{} {
+		secret, err := f.Envs.JWTHS256Secret()
+		if err != nil {
+			panic(err)
+		}
+		verifier, err := adapter.NewJWTVerifier(
+			secret,
+			f.Envs.JWTRS256PublicKey(),
+			f.Envs.JWTIssuer(),
+			f.Envs.JWTAudience())
+		if err != nil {
+			panic(errors.Wrap(err, "JWT_HS256_SECRET or JWT_RS256_PUBLIC_KEY is not set"))
 		}
-	}).(*adapter.MicropostOperator)
+		return verifier
+	}).(domain.TokenVerifier)
+}
+
+// BuildAuthenticate 認証UseCaseインスタンスを生成
+func (f *Factory) BuildAuthenticate() usecase.IAuthenticate {
+	return f.container("Authenticate", func() interface{} {
+		return f.BuildInstrumenter().Authenticate(interactor.NewAuthenticate(f.BuildTokenVerifier()))
+	}).(usecase.IAuthenticate)
+}
+
+// BuildAccessPolicy リソースの操作権限を判定するインスタンスを生成
+func (f *Factory) BuildAccessPolicy() domain.AccessPolicy {
+	return f.container("AccessPolicy", func() interface{} {
+		return domain.NewOwnerPolicy()
+	}).(domain.AccessPolicy)
 }
 
 // BuildCreateUser ユーザー作成UseCaseインスタンスを生成
 func (f *Factory) BuildCreateUser() usecase.ICreateUser {
 	return f.container("CreateUser", func() interface{} {
-		return interactor.NewCreateUser(
+		return f.BuildInstrumenter().CreateUser(interactor.NewCreateUser(
 			f.BuildUserOperator(),
-			f.BuildUserEmailUniqChecker())
+			f.BuildUserEmailUniqChecker()))
 	}).(usecase.ICreateUser)
 }
 
 // BuildUpdateUser ユーザー更新UseCaseインスタンスを生成
 func (f *Factory) BuildUpdateUser() usecase.IUpdateUser {
 	return f.container("UpdateUser", func() interface{} {
-		return interactor.NewUpdateUser(
+		return f.BuildInstrumenter().UpdateUser(interactor.NewUpdateUser(
 			f.BuildUserOperator(),
-			f.BuildUserEmailUniqChecker())
+			f.BuildUserEmailUniqChecker(),
+			f.BuildAccessPolicy()))
 	}).(usecase.IUpdateUser)
 }
 
 // BuildGetUserList ユーザー取得UseCaseインスタンスを生成
 func (f *Factory) BuildGetUserList() usecase.IGetUserList {
 	return f.container("GetUserList", func() interface{} {
-		return interactor.NewGetUserList(f.BuildUserOperator())
+		return f.BuildInstrumenter().GetUserList(interactor.NewGetUserList(f.BuildUserOperator()))
 	}).(usecase.IGetUserList)
 }
 
 // BuildGetUserByID ユーザー取得UseCaseインスタンスを生成
 func (f *Factory) BuildGetUserByID() usecase.IGetUserByID {
 	return f.container("Execute", func() interface{} {
-		return interactor.NewGetUserByID(f.BuildUserOperator())
+		return f.BuildInstrumenter().GetUserByID(interactor.NewGetUserByID(f.BuildUserOperator()))
 	}).(usecase.IGetUserByID)
 }
 
+// BuildRepairUserStats ユーザー集計値修復UseCaseインスタンスを生成
+func (f *Factory) BuildRepairUserStats() usecase.IRepairUserStats {
+	return f.container("RepairUserStats", func() interface{} {
+		return f.BuildInstrumenter().RepairUserStats(interactor.NewRepairUserStats(f.BuildUserOperator()))
+	}).(usecase.IRepairUserStats)
+}
+
 // BuildUserDeleter ユーザー削除Usecaseインスタンスを生成
 func (f *Factory) BuildUserDeleter() usecase.IDeleteUser {
 	return f.container("UserDeleter", func() interface{} {
-		return interactor.NewUserDeleter(
+		return f.BuildInstrumenter().DeleteUser(interactor.NewUserDeleter(
 			f.BuildUserOperator(),
-			f.BuildGetUserByID())
+			f.BuildMicropostOperator(),
+			f.BuildRelationshipOperator(),
+			f.BuildLikeOperator(),
+			f.BuildGetUserByID(),
+			f.BuildAccessPolicy()))
 	}).(usecase.IDeleteUser)
 }
 
 // BuildCreateMicropost マイクロポスト作成UseCaseインスタンスを生成
 func (f *Factory) BuildCreateMicropost() usecase.ICreateMicropost {
 	return f.container("CreateMicropost", func() interface{} {
-		return interactor.NewCreateMicropost(
-			f.BuildMicropostOperator())
+		return f.BuildInstrumenter().CreateMicropost(interactor.NewCreateMicropost(
+			f.BuildMicropostOperator(),
+			f.BuildUserOperator(),
+			f.BuildAccessPolicy()))
 	}).(usecase.ICreateMicropost)
 }
 
 // BuildGetMicropostList マイクロポスト取得UseCaseインスタンスを生成
 func (f *Factory) BuildGetMicropostList() usecase.IGetMicropostList {
 	return f.container("GetMicropostList", func() interface{} {
-		return interactor.NewGetMicropostList(
-			f.BuildMicropostOperator())
+		return f.BuildInstrumenter().GetMicropostList(interactor.NewGetMicropostList(
+			f.BuildMicropostOperator()))
 	}).(usecase.IGetMicropostList)
 }
 
+// BuildGetPublicTimeline 公開タイムライン取得UseCaseインスタンスを生成
+func (f *Factory) BuildGetPublicTimeline() usecase.IGetPublicTimeline {
+	return f.container("GetPublicTimeline", func() interface{} {
+		return f.BuildInstrumenter().GetPublicTimeline(interactor.NewGetPublicTimeline(
+			f.BuildMicropostOperator()))
+	}).(usecase.IGetPublicTimeline)
+}
+
 // BuildGetMicropostList マイクロポスト取得UseCaseインスタンスを生成
 func (f *Factory) BuildGetMicropostByID() usecase.IGetMicropostByID {
 	return f.container("GetMicropostByID", func() interface{} {
-		return interactor.NewGetMicropostByID(
-			f.BuildMicropostOperator())
+		return f.BuildInstrumenter().GetMicropostByID(interactor.NewGetMicropostByID(
+			f.BuildMicropostOperator()))
 	}).(usecase.IGetMicropostByID)
 }
 
 // BuildUpdateMicropost マイクロポスト更新UseCaseインスタンスを生成
 func (f *Factory) BuildUpdateMicropost() usecase.IUpdateMicropost {
 	return f.container("UpdateMicropost", func() interface{} {
-		return interactor.NewUpdateMicropost(
-			f.BuildMicropostOperator())
+		return f.BuildInstrumenter().UpdateMicropost(interactor.NewUpdateMicropost(
+			f.BuildGetMicropostByID(),
+			f.BuildMicropostOperator(),
+			f.BuildAccessPolicy()))
 	}).(usecase.IUpdateMicropost)
 }
 
 // BuildDeleteMicropost マイクロポスト削除UseCaseインスタンスを生成
 func (f *Factory) BuildDeleteMicropost() usecase.IDeleteMicropost {
 	return f.container("DeleteMicropost", func() interface{} {
-		return interactor.NewDeleteMicropost(
+		return f.BuildInstrumenter().DeleteMicropost(interactor.NewDeleteMicropost(
 			f.BuildGetMicropostByID(),
-			f.BuildMicropostOperator())
+			f.BuildMicropostOperator(),
+			f.BuildAccessPolicy()))
 	}).(usecase.IDeleteMicropost)
 }
+
+// BuildFollowUser フォローUseCaseインスタンスを生成
+func (f *Factory) BuildFollowUser() usecase.IFollowUser {
+	return f.container("FollowUser", func() interface{} {
+		return f.BuildInstrumenter().FollowUser(interactor.NewFollowUser(
+			f.BuildRelationshipOperator(),
+			f.BuildUserOperator(),
+			f.BuildAccessPolicy()))
+	}).(usecase.IFollowUser)
+}
+
+// BuildUnfollowUser フォロー解除UseCaseインスタンスを生成
+func (f *Factory) BuildUnfollowUser() usecase.IUnfollowUser {
+	return f.container("UnfollowUser", func() interface{} {
+		return f.BuildInstrumenter().UnfollowUser(interactor.NewUnfollowUser(
+			f.BuildRelationshipOperator(),
+			f.BuildAccessPolicy()))
+	}).(usecase.IUnfollowUser)
+}
+
+// BuildGetFollowers フォロワー一覧取得UseCaseインスタンスを生成
+func (f *Factory) BuildGetFollowers() usecase.IGetFollowers {
+	return f.container("GetFollowers", func() interface{} {
+		return f.BuildInstrumenter().GetFollowers(interactor.NewGetFollowers(
+			f.BuildRelationshipOperator(),
+			f.BuildUserOperator()))
+	}).(usecase.IGetFollowers)
+}
+
+// BuildGetFollowing フォロー中のユーザー一覧取得UseCaseインスタンスを生成
+func (f *Factory) BuildGetFollowing() usecase.IGetFollowing {
+	return f.container("GetFollowing", func() interface{} {
+		return f.BuildInstrumenter().GetFollowing(interactor.NewGetFollowing(
+			f.BuildRelationshipOperator(),
+			f.BuildUserOperator()))
+	}).(usecase.IGetFollowing)
+}
+
+// BuildLikeMicropost いいねUseCaseインスタンスを生成
+func (f *Factory) BuildLikeMicropost() usecase.ILikeMicropost {
+	return f.container("LikeMicropost", func() interface{} {
+		return f.BuildInstrumenter().LikeMicropost(interactor.NewLikeMicropost(
+			f.BuildGetMicropostByID(),
+			f.BuildLikeOperator()))
+	}).(usecase.ILikeMicropost)
+}
+
+// BuildUnlikeMicropost いいね取り消しUseCaseインスタンスを生成
+func (f *Factory) BuildUnlikeMicropost() usecase.IUnlikeMicropost {
+	return f.container("UnlikeMicropost", func() interface{} {
+		return f.BuildInstrumenter().UnlikeMicropost(interactor.NewUnlikeMicropost(
+			f.BuildGetMicropostByID(),
+			f.BuildLikeOperator()))
+	}).(usecase.IUnlikeMicropost)
+}
+
+// BuildGetReplies 返信一覧取得UseCaseインスタンスを生成
+func (f *Factory) BuildGetReplies() usecase.IGetReplies {
+	return f.container("GetReplies", func() interface{} {
+		return f.BuildInstrumenter().GetReplies(interactor.NewGetReplies(
+			f.BuildMicropostOperator()))
+	}).(usecase.IGetReplies)
+}
+
+// BuildGetLikers いいねしたユーザー一覧取得UseCaseインスタンスを生成
+func (f *Factory) BuildGetLikers() usecase.IGetLikers {
+	return f.container("GetLikers", func() interface{} {
+		return f.BuildInstrumenter().GetLikers(interactor.NewGetLikers(
+			f.BuildGetMicropostByID(),
+			f.BuildLikeOperator(),
+			f.BuildUserOperator()))
+	}).(usecase.IGetLikers)
+}
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IAuthenticate 認証UseCase
+type IAuthenticate interface {
+	Execute(ctx context.Context, req *AuthenticateRequest) (*AuthenticateResponse, error)
+}
+
+// AuthenticateRequest 認証Request
+type AuthenticateRequest struct {
+	Token string
+}
+
+// AuthenticateResponse 認証Response
+type AuthenticateResponse struct {
+	Principal *domain.Principal
+}
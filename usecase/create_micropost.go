@@ -1,12 +1,20 @@
 package usecase
 
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
 type ICreateMicropost interface {
-	Execute(req *CreateMicropostRequest) (*CreateMicropostResponse, error)
+	Execute(ctx context.Context, req *CreateMicropostRequest) (*CreateMicropostResponse, error)
 }
 
 type CreateMicropostRequest struct {
 	Content string
 	UserID  uint64
+	// InReplyToID 返信先のマイクロポストのID。返信でない場合は0
+	InReplyToID uint64
+	Principal   *domain.Principal
 }
 
 type CreateMicropostResponse struct {
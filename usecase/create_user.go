@@ -1,10 +1,13 @@
 package usecase
 
-import "clean-serverless-book-sample-v2/domain"
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
 
 // ICreateUser ユーザー新規作成UseCase
 type ICreateUser interface {
-	Execute(req *CreateUserRequest) (*CreateUserResponse, error)
+	Execute(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error)
 }
 
 // CreateUserRequest ユーザー新規作成Request
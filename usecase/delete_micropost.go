@@ -1,12 +1,19 @@
 package usecase
 
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
 type IDeleteMicropost interface {
-	Execute(req *DeleteMicropostRequest) (*DeleteMicropostResponse, error)
+	Execute(ctx context.Context, req *DeleteMicropostRequest) (*DeleteMicropostResponse, error)
 }
 
 type DeleteMicropostRequest struct {
 	MicropostID uint64
 	UserID      uint64
+	Version     int
+	Principal   *domain.Principal
 }
 
 type DeleteMicropostResponse struct {
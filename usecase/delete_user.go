@@ -1,15 +1,23 @@
 package usecase
 
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
 // IDeleteUser ユーザー削除UseCase
 type IDeleteUser interface {
-	Execute(req *DeleteUserRequest) (*DeleteUserResponse, error)
+	Execute(ctx context.Context, req *DeleteUserRequest) (*DeleteUserResponse, error)
 }
 
 // DeleteUserRequest ユーザー削除Request
 type DeleteUserRequest struct {
-	UserID uint64
+	UserID    uint64
+	Version   int
+	Principal *domain.Principal
 }
 
 // DeleteUserResponse ユーザー削除Response
 type DeleteUserResponse struct {
+	DeletedMicropostCount int
 }
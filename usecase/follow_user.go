@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IFollowUser フォローUseCase
+type IFollowUser interface {
+	Execute(ctx context.Context, req *FollowUserRequest) (*FollowUserResponse, error)
+}
+
+// FollowUserRequest フォローRequest。UserIDのユーザーがTargetIDのユーザーをフォローする
+type FollowUserRequest struct {
+	UserID    uint64
+	TargetID  uint64
+	Principal *domain.Principal
+}
+
+// FollowUserResponse フォローResponse
+type FollowUserResponse struct {
+}
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IGetFollowers フォロワー一覧取得UseCase
+type IGetFollowers interface {
+	Execute(ctx context.Context, req *GetFollowersRequest) (*GetFollowersResponse, error)
+}
+
+// GetFollowersRequest フォロワー一覧取得Request
+type GetFollowersRequest struct {
+	UserID uint64
+	Limit  int
+	Cursor string
+}
+
+// GetFollowersResponse フォロワー一覧取得Response
+type GetFollowersResponse struct {
+	Users      []*domain.UserModel
+	NextCursor string
+}
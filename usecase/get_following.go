@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IGetFollowing フォロー中のユーザー一覧取得UseCase
+type IGetFollowing interface {
+	Execute(ctx context.Context, req *GetFollowingRequest) (*GetFollowingResponse, error)
+}
+
+// GetFollowingRequest フォロー中のユーザー一覧取得Request
+type GetFollowingRequest struct {
+	UserID uint64
+	Limit  int
+	Cursor string
+}
+
+// GetFollowingResponse フォロー中のユーザー一覧取得Response
+type GetFollowingResponse struct {
+	Users      []*domain.UserModel
+	NextCursor string
+}
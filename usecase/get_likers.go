@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IGetLikers いいねしたユーザー一覧取得UseCase
+type IGetLikers interface {
+	Execute(ctx context.Context, req *GetLikersRequest) (*GetLikersResponse, error)
+}
+
+// GetLikersRequest いいねしたユーザー一覧取得Request
+type GetLikersRequest struct {
+	UserID      uint64
+	MicropostID uint64
+	Limit       int
+	Cursor      string
+}
+
+// GetLikersResponse いいねしたユーザー一覧取得Response
+type GetLikersResponse struct {
+	Users      []*domain.UserModel
+	NextCursor string
+}
@@ -1,9 +1,12 @@
 package usecase
 
-import "clean-serverless-book-sample-v2/domain"
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
 
 type IGetMicropostByID interface {
-	Execute(req *GetMicropostByIDRequest) (*GetMicropostByIDResponse, error)
+	Execute(ctx context.Context, req *GetMicropostByIDRequest) (*GetMicropostByIDResponse, error)
 }
 
 type GetMicropostByIDRequest struct {
@@ -1,15 +1,21 @@
 package usecase
 
-import "clean-serverless-book-sample-v2/domain"
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
 
 type IGetMicropostList interface {
-	Execute(req *GetMicropostListRequest) (*GetMicropostListResponse, error)
+	Execute(ctx context.Context, req *GetMicropostListRequest) (*GetMicropostListResponse, error)
 }
 
 type GetMicropostListRequest struct {
 	UserID uint64
+	Limit  int
+	Cursor string
 }
 
 type GetMicropostListResponse struct {
 	Microposts []*domain.MicropostModel
+	NextCursor string
 }
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+type IGetPublicTimeline interface {
+	Execute(ctx context.Context, req *GetPublicTimelineRequest) (*GetPublicTimelineResponse, error)
+}
+
+type GetPublicTimelineRequest struct {
+	Limit  int
+	Cursor string
+	// SinceID このIDより新しいマイクロポストを取得する。0の場合は指定しない
+	SinceID uint64
+	// MaxID このID以前のマイクロポストを取得する。0の場合は指定しない
+	MaxID uint64
+}
+
+type GetPublicTimelineResponse struct {
+	Microposts []*domain.MicropostModel
+	NextCursor string
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IGetReplies 返信一覧取得UseCase
+type IGetReplies interface {
+	Execute(ctx context.Context, req *GetRepliesRequest) (*GetRepliesResponse, error)
+}
+
+// GetRepliesRequest 返信一覧取得Request
+type GetRepliesRequest struct {
+	MicropostID uint64
+	Limit       int
+	Cursor      string
+}
+
+// GetRepliesResponse 返信一覧取得Response。返信先が削除されている場合、InReplyToはnil
+type GetRepliesResponse struct {
+	InReplyTo  *domain.MicropostModel
+	Microposts []*domain.MicropostModel
+	NextCursor string
+}
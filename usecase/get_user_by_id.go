@@ -1,10 +1,13 @@
 package usecase
 
-import "clean-serverless-book-sample-v2/domain"
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
 
 // IGetUserByID 指定されたIDのユーザーを取得UseCase
 type IGetUserByID interface {
-	Execute(req *GetUserByIDRequest) (*GetUserByIDResponse, error)
+	Execute(ctx context.Context, req *GetUserByIDRequest) (*GetUserByIDResponse, error)
 }
 
 type GetUserByIDRequest struct {
@@ -1,19 +1,25 @@
 package usecase
 
-import "clean-serverless-book-sample-v2/domain"
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
 
 // IGetUserList ユーザー一覧取得UseCase
 type IGetUserList interface {
-	Execute(req *GetUserListRequest) (*GetUserListResponse, error)
+	Execute(ctx context.Context, req *GetUserListRequest) (*GetUserListResponse, error)
 }
 
 // GetUserListRequest ユーザー一覧取得Request
 type GetUserListRequest struct {
+	Limit  int
+	Cursor string
 }
 
 // GetUserListResponse ユーザー一覧取得Response
 type GetUserListResponse struct {
-	Users []*domain.UserModel
+	Users      []*domain.UserModel
+	NextCursor string
 }
 
 func (g *GetUserListResponse) UserCount() int {
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// ILikeMicropost いいねUseCase
+type ILikeMicropost interface {
+	Execute(ctx context.Context, req *LikeMicropostRequest) (*LikeMicropostResponse, error)
+}
+
+// LikeMicropostRequest いいねRequest。Principalのユーザーが、UserIDのユーザーのMicropostIDのマイクロポストにいいねする
+type LikeMicropostRequest struct {
+	UserID      uint64
+	MicropostID uint64
+	Principal   *domain.Principal
+}
+
+// LikeMicropostResponse いいねResponse
+type LikeMicropostResponse struct {
+}
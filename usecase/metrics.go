@@ -0,0 +1,12 @@
+package usecase
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics UseCaseの処理時間と結果を記録する
+type Metrics interface {
+	// ObserveUseCase nameのUseCaseの処理時間を記録する。errがnilでない場合は失敗として、エラーの種類ごとに記録する
+	ObserveUseCase(ctx context.Context, name string, duration time.Duration, err error)
+}
@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IRepairUserStats ユーザーのマイクロポスト件数と投稿日時を計算し直すUseCase
+type IRepairUserStats interface {
+	Execute(ctx context.Context, req *RepairUserStatsRequest) (*RepairUserStatsResponse, error)
+}
+
+type RepairUserStatsRequest struct {
+	UserID uint64
+}
+
+type RepairUserStatsResponse struct {
+	User *domain.UserModel
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IUnfollowUser フォロー解除UseCase
+type IUnfollowUser interface {
+	Execute(ctx context.Context, req *UnfollowUserRequest) (*UnfollowUserResponse, error)
+}
+
+// UnfollowUserRequest フォロー解除Request。UserIDのユーザーがTargetIDのユーザーのフォローを解除する
+type UnfollowUserRequest struct {
+	UserID    uint64
+	TargetID  uint64
+	Principal *domain.Principal
+}
+
+// UnfollowUserResponse フォロー解除Response
+type UnfollowUserResponse struct {
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
+// IUnlikeMicropost いいね取り消しUseCase
+type IUnlikeMicropost interface {
+	Execute(ctx context.Context, req *UnlikeMicropostRequest) (*UnlikeMicropostResponse, error)
+}
+
+// UnlikeMicropostRequest いいね取り消しRequest。Principalのユーザーが、UserIDのユーザーのMicropostIDのマイクロポストへのいいねを取り消す
+type UnlikeMicropostRequest struct {
+	UserID      uint64
+	MicropostID uint64
+	Principal   *domain.Principal
+}
+
+// UnlikeMicropostResponse いいね取り消しResponse
+type UnlikeMicropostResponse struct {
+}
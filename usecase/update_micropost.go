@@ -1,13 +1,20 @@
 package usecase
 
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
+
 type IUpdateMicropost interface {
-	Execute(req *UpdateMicropostRequest) (*UpdateMicropostResponse, error)
+	Execute(ctx context.Context, req *UpdateMicropostRequest) (*UpdateMicropostResponse, error)
 }
 
 type UpdateMicropostRequest struct {
 	Content     string
 	UserID      uint64
 	MicropostID uint64
+	Version     int
+	Principal   *domain.Principal
 }
 
 type UpdateMicropostResponse struct {
@@ -1,22 +1,28 @@
 package usecase
 
-import "clean-serverless-book-sample-v2/domain"
+import (
+	"clean-serverless-book-sample-v2/domain"
+	"context"
+)
 
 type IUpdateUser interface {
-	Execute(req *UpdateUserRequest) (*UpdateUserResponse, error)
+	Execute(ctx context.Context, req *UpdateUserRequest) (*UpdateUserResponse, error)
 }
 
 type UpdateUserRequest struct {
-	ID    uint64
-	Name  string
-	Email string
+	ID        uint64
+	Name      string
+	Email     string
+	Version   int
+	Principal *domain.Principal
 }
 
 func (u *UpdateUserRequest) ToUserModel() *domain.UserModel {
 	return &domain.UserModel{
-		ID:    u.ID,
-		Name:  u.Name,
-		Email: u.Email,
+		ID:      u.ID,
+		Name:    u.Name,
+		Email:   u.Email,
+		Version: u.Version,
 	}
 }
 